@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
@@ -9,6 +10,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/logsink"
+	"github.com/xabinapal/patrol/internal/notify"
+	"github.com/xabinapal/patrol/internal/tokenstore"
 )
 
 // configPathOutput represents config path output for JSON.
@@ -22,20 +26,24 @@ type configPathOutput struct {
 
 // validationResult represents validation output for JSON.
 type validationResult struct {
-	Valid    bool                `json:"valid"`
-	Profiles []profileValidation `json:"profiles"`
-	Daemon   daemonValidation    `json:"daemon"`
-	Errors   []string            `json:"errors,omitempty"`
+	Valid      bool                 `json:"valid"`
+	Profiles   []profileValidation  `json:"profiles"`
+	Daemon     daemonValidation     `json:"daemon"`
+	Hooks      []hookValidation     `json:"hooks,omitempty"`
+	TokenStore tokenStoreValidation `json:"token_store"`
+	Errors     []string             `json:"errors,omitempty"`
 }
 
 // profileValidation represents profile validation for JSON.
 type profileValidation struct {
-	Name         string `json:"name"`
-	AddressValid bool   `json:"address_valid"`
-	BinaryValid  bool   `json:"binary_valid"`
-	Address      string `json:"address"`
-	Binary       string `json:"binary"`
-	Error        string `json:"error,omitempty"`
+	Name           string `json:"name"`
+	AddressValid   bool   `json:"address_valid"`
+	BinaryValid    bool   `json:"binary_valid"`
+	Address        string `json:"address"`
+	Binary         string `json:"binary"`
+	AutoLogin      string `json:"auto_login,omitempty"`
+	AutoLoginError string `json:"auto_login_error,omitempty"`
+	Error          string `json:"error,omitempty"`
 }
 
 // daemonValidation represents daemon validation for JSON.
@@ -44,6 +52,28 @@ type daemonValidation struct {
 	RenewThresholdValid bool    `json:"renew_threshold_valid"`
 	CheckInterval       string  `json:"check_interval"`
 	RenewThreshold      float64 `json:"renew_threshold"`
+	LogSink             string  `json:"log_sink,omitempty"`
+	LogSinkError        string  `json:"log_sink_error,omitempty"`
+	NotificationRules   int     `json:"notification_rules"`
+	NotificationError   string  `json:"notification_error,omitempty"`
+	Webhook             string  `json:"webhook,omitempty"`
+	WebhookError        string  `json:"webhook_error,omitempty"`
+	HealthEndpoint      string  `json:"health_endpoint,omitempty"`
+	HealthError         string  `json:"health_error,omitempty"`
+}
+
+// hookValidation represents hook validation for JSON.
+type hookValidation struct {
+	Name   string   `json:"name"`
+	Events []string `json:"events"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// tokenStoreValidation represents token store validation for JSON.
+type tokenStoreValidation struct {
+	Type      string `json:"type"`
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
 }
 
 // newConfigCmd creates the config command group.
@@ -206,6 +236,16 @@ func (cli *CLI) newConfigValidateCmd() *cobra.Command {
 					result.Errors = append(result.Errors, fmt.Sprintf("profile %s: %v", conn.Name, err))
 				}
 
+				pv.AutoLogin = conn.AutoLogin.Method
+				if err := conn.AutoLogin.Validate(); err != nil {
+					pv.AutoLoginError = err.Error()
+					if pv.Error == "" {
+						pv.Error = err.Error()
+					}
+					result.Valid = false
+					result.Errors = append(result.Errors, fmt.Sprintf("profile %s: %v", conn.Name, err))
+				}
+
 				result.Profiles = append(result.Profiles, pv)
 			}
 
@@ -218,6 +258,62 @@ func (cli *CLI) newConfigValidateCmd() *cobra.Command {
 				result.Valid = false
 				result.Errors = append(result.Errors, "daemon: renew threshold must be between 0 and 1")
 			}
+			if err := validateLogSink(cfg); err != nil {
+				result.Daemon.LogSinkError = err.Error()
+				result.Valid = false
+				result.Errors = append(result.Errors, fmt.Sprintf("daemon: %v", err))
+			}
+			result.Daemon.LogSink = cfg.Daemon.LogSink
+			result.Daemon.NotificationRules = len(cfg.Daemon.Notifications.Rules)
+			if err := notify.ValidateRules(cfg.Daemon.Notifications); err != nil {
+				result.Daemon.NotificationError = err.Error()
+				result.Valid = false
+				result.Errors = append(result.Errors, fmt.Sprintf("daemon: %v", err))
+			}
+			if u, err := url.Parse(cfg.Daemon.Notifications.Webhook.URL); err == nil && u.Host != "" {
+				// Only the host is shown: webhook paths often embed a secret.
+				result.Daemon.Webhook = u.Host
+			}
+			if err := notify.ValidateWebhook(cfg.Daemon.Notifications.Webhook); err != nil {
+				result.Daemon.WebhookError = err.Error()
+				result.Valid = false
+				result.Errors = append(result.Errors, fmt.Sprintf("daemon: %v", err))
+			}
+
+			result.Daemon.HealthEndpoint = cfg.Daemon.HealthEndpoint
+			if err := cfg.Daemon.Health.Validate(); err != nil {
+				result.Daemon.HealthError = err.Error()
+				result.Valid = false
+				result.Errors = append(result.Errors, fmt.Sprintf("daemon: %v", err))
+			}
+
+			// Check hooks
+			for _, hook := range cfg.Hooks {
+				hv := hookValidation{Name: hook.GetName(), Events: hook.Events}
+				if err := hook.Validate(); err != nil {
+					hv.Error = err.Error()
+					result.Valid = false
+					result.Errors = append(result.Errors, err.Error())
+				}
+				result.Hooks = append(result.Hooks, hv)
+			}
+
+			// Check token store: an invalid selection is an error, while an
+			// unavailable backend is reported so it can be diagnosed.
+			result.TokenStore = tokenStoreValidation{
+				Type:      cfg.TokenStore.GetType(),
+				Available: true,
+			}
+			store, err := tokenstore.NewTokenStoreFromConfig(&cfg.TokenStore)
+			if err != nil {
+				result.TokenStore.Available = false
+				result.TokenStore.Error = err.Error()
+				result.Valid = false
+				result.Errors = append(result.Errors, fmt.Sprintf("token store: %v", err))
+			} else if err := store.IsAvailable(); err != nil {
+				result.TokenStore.Available = false
+				result.TokenStore.Error = err.Error()
+			}
 
 			writer := NewOutputWriter(format)
 			writeErr := writer.Write(result, func() {
@@ -235,6 +331,12 @@ func (cli *CLI) newConfigValidateCmd() *cobra.Command {
 					} else {
 						fmt.Printf("  Binary path: %s (invalid)\n", pv.Binary)
 					}
+					switch {
+					case pv.AutoLoginError != "":
+						fmt.Printf("  Auto login: %s (%s)\n", pv.AutoLogin, pv.AutoLoginError)
+					case pv.AutoLogin != "":
+						fmt.Printf("  Auto login: %s\n", pv.AutoLogin)
+					}
 				}
 
 				fmt.Printf("\nDaemon configuration:\n")
@@ -248,6 +350,49 @@ func (cli *CLI) newConfigValidateCmd() *cobra.Command {
 				} else {
 					fmt.Printf("  Renew threshold: must be between 0 and 1\n")
 				}
+				switch {
+				case result.Daemon.LogSinkError != "":
+					fmt.Printf("  Log sink: %s (%s)\n", result.Daemon.LogSink, result.Daemon.LogSinkError)
+				case result.Daemon.LogSink != "":
+					fmt.Printf("  Log sink: %s\n", result.Daemon.LogSink)
+				}
+				switch {
+				case result.Daemon.NotificationError != "":
+					fmt.Printf("  Notification rules: %s\n", result.Daemon.NotificationError)
+				case result.Daemon.NotificationRules > 0:
+					fmt.Printf("  Notification rules: %d\n", result.Daemon.NotificationRules)
+				}
+				switch {
+				case result.Daemon.WebhookError != "":
+					fmt.Printf("  Webhook: %s (%s)\n", result.Daemon.Webhook, result.Daemon.WebhookError)
+				case result.Daemon.Webhook != "":
+					fmt.Printf("  Webhook: %s\n", result.Daemon.Webhook)
+				}
+				switch {
+				case result.Daemon.HealthError != "":
+					fmt.Printf("  Health endpoint: %s (%s)\n", result.Daemon.HealthEndpoint, result.Daemon.HealthError)
+				case result.Daemon.HealthEndpoint != "":
+					fmt.Printf("  Health endpoint: %s\n", result.Daemon.HealthEndpoint)
+				}
+
+				if len(result.Hooks) > 0 {
+					fmt.Printf("\nHooks:\n")
+					for _, hv := range result.Hooks {
+						if hv.Error != "" {
+							fmt.Printf("  %s: %s\n", hv.Name, hv.Error)
+						} else {
+							fmt.Printf("  %s: %s\n", hv.Name, strings.Join(hv.Events, ", "))
+						}
+					}
+				}
+
+				fmt.Printf("\nToken store:\n")
+				fmt.Printf("  Type: %s\n", result.TokenStore.Type)
+				if result.TokenStore.Available {
+					fmt.Printf("  Status: available\n")
+				} else {
+					fmt.Printf("  Status: unavailable (%s)\n", result.TokenStore.Error)
+				}
 
 				fmt.Println()
 				if result.Valid {
@@ -297,3 +442,17 @@ func suggestProfileName(address string) string {
 
 	return addr
 }
+
+// validateLogSink checks the log sink, and the syslog settings if the log or
+// notifications are sent to syslog.
+func validateLogSink(cfg *config.Config) error {
+	switch cfg.Daemon.LogSink {
+	case "", config.LogSinkFile, config.LogSinkSyslog, config.LogSinkJournald:
+	default:
+		return fmt.Errorf("unknown log_sink %q (use file, syslog or journald)", cfg.Daemon.LogSink)
+	}
+	if cfg.Daemon.LogSink == config.LogSinkSyslog || cfg.Daemon.Notifications.Syslog {
+		return logsink.ValidateSyslog(cfg.Daemon.Syslog)
+	}
+	return nil
+}
@@ -1,7 +1,13 @@
 package cli
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/xabinapal/patrol/internal/tokenstore"
 )
 
 func TestSuggestProfileName(t *testing.T) {
@@ -61,3 +67,30 @@ func TestSuggestProfileName(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigCommandsSkipTokenStore(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(tokenstore.TestStoreEnvVar, "")
+	t.Setenv("PATROL_CONFIG_DIR", configDir)
+	t.Setenv("PATROL_PROFILE", "")
+	cfg := "token_store:\n  type: bogus\n"
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) error {
+		cli := New()
+		cli.rootCmd.SetArgs(args)
+		return cli.rootCmd.ExecuteContext(context.Background())
+	}
+
+	if err := run("config", "path"); err != nil {
+		t.Errorf("config path error = %v, want it to work with a broken token store", err)
+	}
+	if err := run("config", "validate"); err == nil || strings.Contains(err.Error(), "failed to initialize token store") {
+		t.Errorf("config validate error = %v, want the token store reported as a validation error", err)
+	}
+	if err := run("profile", "list"); err == nil || !strings.Contains(err.Error(), "failed to initialize token store") {
+		t.Errorf("profile list error = %v, want the token store error", err)
+	}
+}
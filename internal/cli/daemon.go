@@ -1,22 +1,29 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/xabinapal/patrol/internal/config"
 	"github.com/xabinapal/patrol/internal/daemon"
+	"github.com/xabinapal/patrol/internal/utils"
 )
 
 // DaemonStatusOutput represents daemon status for JSON output.
 type DaemonStatusOutput struct {
-	Running       bool               `json:"running"`
-	PID           int                `json:"pid,omitempty"`
-	Configuration DaemonConfigOutput `json:"configuration"`
-	Service       *ServiceInfoOutput `json:"service,omitempty"`
+	Running       bool                   `json:"running"`
+	PID           int                    `json:"pid,omitempty"`
+	StartedAt     time.Time              `json:"started_at,omitzero"`
+	Configuration DaemonConfigOutput     `json:"configuration"`
+	Profiles      []daemon.ProfileStatus `json:"profiles,omitempty"`
+	Service       *ServiceInfoOutput     `json:"service,omitempty"`
 }
 
 // DaemonConfigOutput represents daemon configuration for JSON output.
@@ -62,6 +69,13 @@ Examples:
   # Check daemon status
   patrol daemon status
 
+  # Renew a token now, or pause renewals of a profile
+  patrol daemon renew prod
+  patrol daemon pause prod
+
+  # Follow the daemon log
+  patrol daemon logs --follow
+
   # Restart the system service
   patrol daemon service restart
 
@@ -72,6 +86,11 @@ Examples:
 	cmd.AddCommand(
 		cli.newDaemonRunCmd(),
 		cli.newDaemonStatusCmd(),
+		cli.newDaemonRenewCmd(),
+		cli.newDaemonReloadCmd(),
+		cli.newDaemonPauseCmd(),
+		cli.newDaemonResumeCmd(),
+		cli.newDaemonLogsCmd(),
 		cli.newDaemonServiceCmd(),
 	)
 
@@ -103,49 +122,59 @@ Examples:
   patrol daemon run --log-json
 
   # Run with health endpoint
-  patrol daemon run --health-addr=localhost:9090`,
+  patrol daemon run --health-addr=localhost:9090
+
+  # Serve the health endpoint on a Unix socket
+  patrol daemon run --health-addr=unix:/run/user/1000/patrol-health.sock
+
+Logging and the health endpoint default to the daemon section of the
+configuration file. The daemon reloads the configuration when the file
+changes or when it receives SIGHUP; flags given here keep precedence.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Parse log level
-			level, err := daemon.ParseLogLevel(logLevel)
-			if err != nil {
-				return err
+			// Flags given on the command line take precedence over the
+			// configuration file, also when it is reloaded.
+			overrides := daemon.Overrides{
+				LogLevel:   logLevel,
+				LogFile:    logFile,
+				HealthAddr: healthAddr,
+			}
+			if cmd.Flags().Changed("log-json") {
+				overrides.LogJSON = &logJSON
 			}
 
-			// Get max log size from config (in MB, convert to bytes)
-			maxSize := int64(cli.Config.Daemon.LogMaxSize) * 1024 * 1024
+			d := daemon.New(cli.Config, cli.Store)
+			d.SetOverrides(overrides)
 
 			// Set up logging with new logger
-			loggerCfg := daemon.LoggerConfig{
-				Level:    level,
-				FilePath: logFile,
-				JSONMode: logJSON,
-				MaxSize:  maxSize,
+			loggerCfg, err := d.EffectiveLoggerConfig()
+			if err != nil {
+				return err
 			}
 
 			logger, err := daemon.NewLogger(loggerCfg)
 			if err != nil {
 				return fmt.Errorf("failed to create logger: %w", err)
 			}
-
-			// Create daemon
-			d := daemon.New(cli.Config, cli.Store)
 			d.SetLogger(logger)
+			// Messages of the log and log/slog packages go to the daemon
+			// log too.
+			slog.SetDefault(slog.New(logger.Handler()))
 
 			// Set up health server if configured
-			if healthAddr != "" {
-				healthServer := daemon.NewHealthServer(healthAddr)
+			if addr := d.EffectiveHealthAddr(); addr != "" {
+				healthServer := daemon.NewHealthServer(addr, daemon.WithHealthConfig(cli.Config.Daemon.Health))
 				d.SetHealthServer(healthServer)
-				fmt.Printf("Health endpoint will be available at http://%s/health\n", healthAddr)
+				fmt.Printf("Health endpoint will be available at %s (/health, /livez, /readyz, /metrics)\n", healthServer.URL())
 			}
 
 			return d.Run(cmd.Context())
 		},
 	}
 
-	cmd.Flags().StringVar(&logFile, "log", "", "Log file path (default: stderr)")
-	cmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
-	cmd.Flags().BoolVar(&logJSON, "log-json", false, "Output logs as JSON")
-	cmd.Flags().StringVar(&healthAddr, "health-addr", "", "Health endpoint address (e.g., localhost:9090)")
+	cmd.Flags().StringVar(&logFile, "log", "", "Log file path (default: log_file from config, or stderr)")
+	cmd.Flags().StringVar(&logLevel, "log-level", "", "Log level: debug, info, warn, error (default: log_level from config, or info)")
+	cmd.Flags().BoolVar(&logJSON, "log-json", false, "Output logs as JSON (default: log_json from config)")
+	cmd.Flags().StringVar(&healthAddr, "health-addr", "", "Health endpoint address, e.g. localhost:9090 or unix:/path/to/socket (default: health_endpoint from config)")
 
 	return cmd
 }
@@ -154,32 +183,21 @@ Examples:
 func (cli *CLI) newDaemonStatusCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "status",
-		Short: "Check if the daemon process is running",
-		Long: `Check if the daemon process is currently running.
-
-This command checks for a running daemon process by looking for the PID file.
-It does not check the system service status - use 'patrol daemon service status'
-for that information.`,
+		Short: "Show the daemon process and per-profile renewal state",
+		Long: `Show whether the daemon process is running and, if it is, the renewal
+state of each profile and the next action scheduled for it.
+
+The state is queried from the running daemon over its control socket. If the
+daemon cannot be reached, only the PID file is checked. This command does not
+check the system service status - use 'patrol daemon service status' for that
+information.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			format, err := ParseOutputFormat(cli.outputFlag)
 			if err != nil {
 				return err
 			}
 
-			running := daemon.IsRunningFromPID(cli.Config)
-			pid := 0
-			if running {
-				var pidErr error
-				pid, pidErr = daemon.GetPID(cli.Config)
-				if pidErr != nil {
-					// PID file might be stale, but daemon is running
-					pid = 0
-				}
-			}
-
 			statusOutput := DaemonStatusOutput{
-				Running: running,
-				PID:     pid,
 				Configuration: DaemonConfigOutput{
 					CheckInterval:  cli.Config.Daemon.CheckInterval.String(),
 					RenewThreshold: cli.Config.Daemon.RenewThreshold,
@@ -187,14 +205,41 @@ for that information.`,
 				},
 			}
 
+			live, liveErr := daemon.NewControlClient(cli.Config).Status(cmd.Context())
+			if liveErr == nil {
+				statusOutput.Running = true
+				statusOutput.PID = live.PID
+				statusOutput.StartedAt = live.StartedAt
+				statusOutput.Profiles = live.Profiles
+			} else if daemon.IsRunningFromPID(cli.Config) {
+				statusOutput.Running = true
+				if pid, pidErr := daemon.GetPID(cli.Config); pidErr == nil {
+					statusOutput.PID = pid
+				}
+			}
+
 			output := NewOutputWriter(format)
 			return output.Write(statusOutput, func() {
-				if running {
-					fmt.Printf("Daemon is running (PID: %d)\n", pid)
-				} else {
+				switch {
+				case live != nil:
+					fmt.Printf("Daemon is running (PID: %d, up %s)\n", statusOutput.PID,
+						utils.FormatDuration(time.Since(statusOutput.StartedAt)))
+				case statusOutput.Running:
+					fmt.Printf("Daemon is running (PID: %d)\n", statusOutput.PID)
+					if !errors.Is(liveErr, daemon.ErrDaemonNotReachable) {
+						fmt.Printf("Could not query profile state: %v\n", liveErr)
+					} else {
+						fmt.Println("Control socket not reachable; profile state unavailable")
+					}
+				default:
 					fmt.Println("Daemon is not running")
 				}
 
+				if len(statusOutput.Profiles) > 0 {
+					fmt.Println()
+					printProfileStatuses(statusOutput.Profiles)
+				}
+
 				fmt.Printf("\nDaemon configuration:\n")
 				fmt.Printf("  Check interval:     %s\n", cli.Config.Daemon.CheckInterval)
 				fmt.Printf("  Renewal threshold:  %.0f%%\n", cli.Config.Daemon.RenewThreshold*100)
@@ -204,6 +249,248 @@ for that information.`,
 	}
 }
 
+// printProfileStatuses prints the per-profile renewal state as a table.
+func printProfileStatuses(profiles []daemon.ProfileStatus) {
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tSTATE\tEXPIRES\tNEXT ACTION")
+	for _, p := range profiles {
+		expires := "-"
+		if !p.ExpiresAt.IsZero() {
+			expires = utils.FormatDuration(p.ExpiresAt.Sub(now))
+		}
+		next := "-"
+		if p.NextAction != "" {
+			next = fmt.Sprintf("%s in %s", p.NextAction, utils.FormatDuration(max(p.NextActionAt.Sub(now), 0)))
+		}
+		state := p.State
+		if p.Failures > 0 {
+			state = fmt.Sprintf("%s (%d failures)", state, p.Failures)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, state, expires, next)
+	}
+	// #nosec G104 - Flush error on stdout; if write fails, user will see incomplete output
+	_ = w.Flush()
+}
+
+// newDaemonRenewCmd creates the daemon renew command.
+func (cli *CLI) newDaemonRenewCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "renew <profile>",
+		Short: "Ask the running daemon to renew a token now",
+		Long: `Ask the running daemon to renew the token of a profile right away,
+regardless of its remaining TTL and of any backoff after earlier failures.
+
+The renewal runs in the daemon; use 'patrol daemon status' to follow it.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cli.daemonProfileArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.sendDaemonCommand(cmd, daemon.ControlRenew, args[0])
+		},
+	}
+}
+
+// newDaemonReloadCmd creates the daemon reload command.
+func (cli *CLI) newDaemonReloadCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Ask the running daemon to reload its configuration",
+		Long: `Ask the running daemon to reload the configuration file and rescan the
+token store right away, instead of waiting for the next check interval.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.sendDaemonCommand(cmd, daemon.ControlReload, "")
+		},
+	}
+}
+
+// newDaemonPauseCmd creates the daemon pause command.
+func (cli *CLI) newDaemonPauseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pause <profile>",
+		Short: "Pause automatic renewal of a profile",
+		Long: `Stop the running daemon from renewing the token of a profile until it is
+resumed with 'patrol daemon resume'. Pauses last until the daemon restarts.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cli.daemonProfileArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.sendDaemonCommand(cmd, daemon.ControlPause, args[0])
+		},
+	}
+}
+
+// newDaemonResumeCmd creates the daemon resume command.
+func (cli *CLI) newDaemonResumeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume <profile>",
+		Short: "Resume automatic renewal of a paused profile",
+		Long: `Resume automatic renewal of a profile paused with 'patrol daemon pause'.
+The token is checked right away.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cli.daemonProfileArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.sendDaemonCommand(cmd, daemon.ControlResume, args[0])
+		},
+	}
+}
+
+// newDaemonLogsCmd creates the daemon logs command.
+func (cli *CLI) newDaemonLogsCmd() *cobra.Command {
+	var (
+		follow  bool
+		level   string
+		since   string
+		profile string
+		file    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show and follow the daemon log",
+		Long: `Show the daemon log, including the files rotated from it, and optionally
+follow it as it is written.
+
+The log is read from --file, for a daemon run with --log, or from log_file
+in the daemon configuration. Without either, the output of the installed
+service is read: the log file of the launchd agent on macOS, or the journal
+of the systemd user service on Linux. Both the
+text and the JSON log formats are understood; -o json prints records as
+JSON lines, whatever the format of the log, and --profile only shows the
+records about a profile.
+
+Examples:
+  # Show the whole log
+  patrol daemon logs
+
+  # Follow warnings and errors about a profile
+  patrol daemon logs --follow --profile prod --level warn
+
+  # Show the last hour as JSON
+  patrol daemon logs --since 1h -o json
+
+  # Read the log of a daemon run with --log
+  patrol daemon logs --file /tmp/patrol.log`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := ParseOutputFormat(cli.outputFlag)
+			if err != nil {
+				return err
+			}
+
+			filter := daemon.LogFilter{Profile: profile}
+			if level != "" {
+				if filter.Level, err = daemon.ParseLogLevel(level); err != nil {
+					return err
+				}
+			}
+			if since != "" {
+				if filter.Since, err = parseSince(since, time.Now()); err != nil {
+					return err
+				}
+			}
+
+			show := func(line string) error {
+				rec := daemon.ParseLogLine(line)
+				if !filter.Match(&rec) {
+					return nil
+				}
+				out := rec.Text()
+				if format == OutputFormatJSON {
+					out = rec.JSON()
+				}
+				_, err := os.Stdout.Write(out)
+				return err
+			}
+
+			logs := daemon.ServiceLogs{Path: file}
+			if file == "" {
+				if logs, err = cli.daemonLogs(); err != nil {
+					return err
+				}
+			}
+			if logs.Unit != "" {
+				return daemon.ReadLogCommand(cmd.Context(), daemon.JournalCommand(logs.Unit, follow, filter.Since), show)
+			}
+
+			reader := &daemon.LogReader{Path: logs.Path, Since: filter.Since, Follow: follow}
+			if err := reader.Read(cmd.Context(), show); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("no daemon log at %s; has the daemon run yet?", logs.Path)
+				}
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep printing lines as they are logged, across rotations")
+	cmd.Flags().StringVar(&level, "level", "", "Lowest level shown: debug, info, warn, error (default: all)")
+	cmd.Flags().StringVar(&since, "since", "", "Only show records since a duration ago (e.g. 1h) or a time (RFC 3339)")
+	// A local --profile only filters, so records of removed profiles can
+	// still be shown; the global flag must name a configured profile.
+	cmd.Flags().StringVarP(&profile, "profile", "p", "", "Only show records about a profile")
+	cmd.Flags().StringVar(&file, "file", "", "Log file to read (default: log_file from config, or the service log)")
+
+	return cmd
+}
+
+// daemonLogs returns where the daemon log is: log_file, or else the output
+// of the installed service.
+func (cli *CLI) daemonLogs() (daemon.ServiceLogs, error) {
+	dc := cli.Config.Daemon
+	if dc.LogSink != "" && dc.LogSink != config.LogSinkFile {
+		return daemon.ServiceLogs{}, fmt.Errorf("the daemon logs to %s (log_sink); read it with the system log tools", dc.LogSink)
+	}
+	if dc.LogFile != "" {
+		return daemon.ServiceLogs{Path: dc.LogFile}, nil
+	}
+
+	mgr, err := cli.getServiceManager()
+	if err != nil {
+		return daemon.ServiceLogs{}, err
+	}
+	if installed, err := mgr.IsInstalled(); err == nil && installed {
+		if logs := mgr.Logs(); logs != (daemon.ServiceLogs{}) {
+			return logs, nil
+		}
+	}
+	return daemon.ServiceLogs{}, errors.New("no daemon log found: set log_file in the daemon configuration, or install the service with 'patrol daemon service install'")
+}
+
+// parseSince parses the --since flag: a duration before now, or a time in
+// RFC 3339 format.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a duration such as 1h or an RFC 3339 time", s)
+}
+
+// daemonProfileArgs completes the profile argument of daemon commands.
+func (cli *CLI) daemonProfileArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return cli.getProfileNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// sendDaemonCommand sends a control command to the running daemon and prints its reply.
+func (cli *CLI) sendDaemonCommand(cmd *cobra.Command, command, profile string) error {
+	msg, err := daemon.NewControlClient(cli.Config).Send(cmd.Context(), command, profile)
+	if errors.Is(err, daemon.ErrDaemonNotReachable) {
+		return fmt.Errorf("%w; is the daemon running? Start it with 'patrol daemon run'", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(msg)
+	return nil
+}
+
 // newDaemonServiceCmd creates the daemon service command group.
 func (cli *CLI) newDaemonServiceCmd() *cobra.Command {
 	cmd := &cobra.Command{
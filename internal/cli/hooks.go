@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/daemon"
+	"github.com/xabinapal/patrol/internal/hooks"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// runHooks runs the hooks configured for an event of type event on prof and
+// waits for them. The TTL is taken from the stored token metadata. Failures
+// are logged to stderr and never fail the command; with --verbose, completed
+// hooks and their output are logged too.
+func (cli *CLI) runHooks(ctx context.Context, event string, prof *types.Profile, tokenStr string) {
+	if cli.Config == nil || len(cli.Config.Hooks) == 0 {
+		return
+	}
+
+	var ttl time.Duration
+	if event != config.HookLogout && cli.Store != nil {
+		if meta, err := cli.Store.GetMetadata(prof); err == nil {
+			ttl = meta.TTL()
+		}
+	}
+
+	level := daemon.LogLevelWarn
+	if cli.verboseFlag {
+		level = daemon.LogLevelDebug
+	}
+	// Without a log file, the logger writes to stderr.
+	logger, err := daemon.NewLogger(daemon.LoggerConfig{Level: level})
+	if err != nil {
+		return
+	}
+
+	ev := &hooks.Event{Type: event, Profile: prof, TTL: ttl, Token: tokenStr}
+	hooks.NewRunner(cli.Config.Hooks, logger).Run(ctx, ev)
+}
@@ -3,16 +3,17 @@ package cli
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/xabinapal/patrol/internal/config"
 	"github.com/xabinapal/patrol/internal/profile"
 	"github.com/xabinapal/patrol/internal/proxy"
 	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/types"
 	"github.com/xabinapal/patrol/internal/vault"
 )
 
@@ -23,22 +24,37 @@ func (cli *CLI) newLoginCmd() *cobra.Command {
 		Short: "Authenticate to Vault/OpenBao and securely store the token",
 		Long: `Authenticate to the Vault or OpenBao server using any authentication method.
 
-This command wraps the underlying vault/bao login command and securely stores
-the resulting token in your system's credential store (Keychain on macOS,
-Credential Manager on Windows, Secret Service on Linux).
+The token, userpass, ldap, approle, cert, jwt, kubernetes and oidc methods are
+handled natively by calling the server's login API, so the vault/bao binary is
+not required for them. Any other method (or a native method with missing
+parameters, which needs interactive prompting) is delegated to the
+underlying vault/bao login command.
+
+The resulting token is stored securely in your system's credential store
+(Keychain on macOS, Credential Manager on Windows, Secret Service on Linux).
 
 Supported authentication methods:
-  - Token (default)
-  - Userpass
-  - LDAP
+  - Token (default, native)
+  - Userpass (native)
+  - LDAP (native)
+  - AppRole (native)
+  - Cert (native, uses the profile's client certificate)
+  - JWT (native)
+  - Kubernetes (native)
+  - OIDC (native browser flow)
   - GitHub
-  - OIDC
-  - AppRole
   - And more...
 
+OIDC login starts a local callback server (localhost:8250 by default), opens
+the provider's login page in your browser and waits for the redirect. The
+following parameters are accepted: role, listenaddress, port, callbackhost,
+callbackport, callbackmethod (get or form_post) and skip_browser. With
+skip_browser=true the URL is printed instead, which is useful on headless
+machines combined with SSH port forwarding.
+
 Examples:
   # Token authentication
-  patrol login
+  patrol login token=<token>
 
   # Userpass authentication
   patrol login -method=userpass username=admin password=secret
@@ -46,11 +62,17 @@ Examples:
   # LDAP authentication with custom path
   patrol login -method=ldap -path=ldap-corp username=user password=pass
 
+  # AppRole authentication
+  patrol login -method=approle role_id=<role-id> secret_id=<secret-id>
+
   # GitHub authentication
   patrol login -method=github token=<github-token>
 
   # OIDC authentication
-  patrol login -method=oidc`,
+  patrol login -method=oidc
+
+  # OIDC authentication with a specific role, without launching a browser
+  patrol login -method=oidc role=dev skip_browser=true`,
 		Args:               cobra.ArbitraryArgs,
 		DisableFlagParsing: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -65,11 +87,7 @@ Examples:
 				return err
 			}
 
-			finalArgs, err := buildLoginArgs(method, path, remainingArgs)
-			if err != nil {
-				return err
-			}
-			return cli.runLogin(cmd.Context(), finalArgs)
+			return cli.runLogin(cmd.Context(), method, path, remainingArgs)
 		},
 	}
 
@@ -127,7 +145,12 @@ func parseLoginFlags(args []string, currentMethod, currentPath string) (method,
 }
 
 // runLogin handles the login command execution.
-func (cli *CLI) runLogin(ctx context.Context, args []string) error {
+func (cli *CLI) runLogin(ctx context.Context, method, path string, args []string) error {
+	finalArgs, err := buildLoginArgs(method, path, args)
+	if err != nil {
+		return err
+	}
+
 	if err := cli.Store.IsAvailable(); err != nil {
 		return fmt.Errorf("cannot store token: %w", err)
 	}
@@ -138,53 +161,102 @@ func (cli *CLI) runLogin(ctx context.Context, args []string) error {
 		return err
 	}
 
+	params := parseLoginParams(args)
+
+	var resp *vault.VaultTokenResponse
+	switch {
+	case strings.EqualFold(method, vault.AuthMethodOIDC):
+		opts, err := vault.OIDCOptionsFromParams(params)
+		if err != nil {
+			return err
+		}
+		resp, err = vault.NewLoginExecutor().LoginOIDC(ctx, prof, path, opts)
+		if err != nil {
+			return err
+		}
+	case vault.IsNativeAuthMethod(method) && len(vault.MissingAuthParams(method, params)) == 0:
+		resp, err = vault.NewLoginExecutor().Login(ctx, prof, method, path, params)
+		if err != nil {
+			return err
+		}
+	default:
+		resp, err = cli.runBinaryLogin(ctx, prof, method, params, finalArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	tm := token.NewTokenManager(ctx, cli.Store, vault.NewTokenExecutor())
+	if err := tm.SetFromLogin(prof, resp); err != nil {
+		return fmt.Errorf("failed to store token securely: %w", err)
+	}
+	if conn, err := cli.Config.GetConnection(prof.Name); err == nil {
+		recordTokenUse(conn, prof)
+	}
+	cli.runHooks(ctx, config.HookLogin, prof, resp.ClientToken)
+
+	fmt.Println()
+	fmt.Println("Success! You are now authenticated.")
+	fmt.Printf("Token stored securely in your system's credential store.\n")
+	if prof.Name != "" && prof.Name != "env" {
+		fmt.Printf("Profile: %s\n", prof.Name)
+	}
+	fmt.Println()
+	fmt.Println("Your token will be automatically used for subsequent vault commands via Patrol.")
+
+	return nil
+}
+
+// runBinaryLogin delegates login to the vault/bao binary and returns the
+// login response it prints as JSON, so limited-use tokens are known before
+// they are looked up.
+func (cli *CLI) runBinaryLogin(ctx context.Context, prof *types.Profile, method string, params map[string]string, args []string) (*vault.VaultTokenResponse, error) {
 	conn := prof.ToConnection()
 	if !proxy.BinaryExists(conn) {
-		return fmt.Errorf("vault/openbao binary %q not found in PATH", prof.GetBinaryPath())
+		if missing := vault.MissingAuthParams(method, params); vault.IsNativeAuthMethod(method) && len(missing) > 0 {
+			return nil, fmt.Errorf("vault/openbao binary %q not found in PATH; provide %s to login without it",
+				prof.GetBinaryPath(), strings.Join(missing, ", "))
+		}
+		return nil, fmt.Errorf("vault/openbao binary %q not found in PATH", prof.GetBinaryPath())
 	}
 
 	loginArgs := buildVaultLoginArgs(args)
 
+	// The JSON response on stdout is read, not shown; prompts go to stderr.
+	var stdout bytes.Buffer
 	exec := proxy.NewExecutor(conn,
-		proxy.WithStdout(os.Stdout),
+		proxy.WithStdout(&stdout),
 		proxy.WithStderr(os.Stderr),
 	)
 
-	var captureBuf bytes.Buffer
-	exitCode, err := exec.Execute(ctx, loginArgs, &captureBuf)
+	exitCode, err := exec.Execute(ctx, loginArgs, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if exitCode != 0 {
 		os.Exit(exitCode)
 	}
 
-	tokenStr := ""
-	for tokenLine := range strings.Lines(captureBuf.String()) {
-		tokenStr = tokenLine
-	}
-	tokenStr = strings.TrimSpace(tokenStr)
-
-	if tokenStr == "" {
-		return errors.New("login succeeded but no token was returned")
-	}
-
-	tm := token.NewTokenManager(ctx, cli.Store, vault.NewTokenExecutor())
-	if err := tm.Set(prof, tokenStr); err != nil {
-		return fmt.Errorf("failed to store token securely: %w", err)
+	resp, err := vault.ParseLoginResponse(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("login succeeded but no token was returned: %w", err)
 	}
+	return resp, nil
+}
 
-	fmt.Println()
-	fmt.Println("Success! You are now authenticated.")
-	fmt.Printf("Token stored securely in your system's credential store.\n")
-	if prof.Name != "" && prof.Name != "env" {
-		fmt.Printf("Profile: %s\n", prof.Name)
+// parseLoginParams converts K=V authentication arguments into a map.
+// Arguments without "=" are ignored; buildLoginArgs rejects them beforehand.
+func parseLoginParams(args []string) map[string]string {
+	params := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			continue
+		}
+		params[key] = value
 	}
-	fmt.Println()
-	fmt.Println("Your token will be automatically used for subsequent vault commands via Patrol.")
-
-	return nil
+	return params
 }
 
 // buildLoginArgs validates and builds login arguments from user input.
@@ -216,7 +288,7 @@ func buildLoginArgs(method, path string, args []string) ([]string, error) {
 
 // buildVaultLoginArgs builds the final arguments for the vault login command.
 func buildVaultLoginArgs(args []string) []string {
-	result := []string{"login", "-token-only", "-no-store"}
+	result := []string{"login", "-format=json", "-no-store"}
 	result = append(result, args...)
 	return result
 }
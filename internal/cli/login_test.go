@@ -1,8 +1,14 @@
 package cli
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"testing"
+
+	"github.com/xabinapal/patrol/internal/types"
 )
 
 func TestBuildLoginArgs(t *testing.T) {
@@ -109,22 +115,22 @@ func TestBuildVaultLoginArgs(t *testing.T) {
 		{
 			name:     "empty args",
 			args:     []string{},
-			expected: []string{"login", "-token-only", "-no-store"},
+			expected: []string{"login", "-format=json", "-no-store"},
 		},
 		{
 			name:     "with method and K=V",
 			args:     []string{"-method=userpass", "username=admin"},
-			expected: []string{"login", "-token-only", "-no-store", "-method=userpass", "username=admin"},
+			expected: []string{"login", "-format=json", "-no-store", "-method=userpass", "username=admin"},
 		},
 		{
 			name:     "with method, path and K=V",
 			args:     []string{"-method=ldap", "-path=ldap-corp", "username=user"},
-			expected: []string{"login", "-token-only", "-no-store", "-method=ldap", "-path=ldap-corp", "username=user"},
+			expected: []string{"login", "-format=json", "-no-store", "-method=ldap", "-path=ldap-corp", "username=user"},
 		},
 		{
 			name:     "only K=V pairs",
 			args:     []string{"username=admin", "password=secret"},
-			expected: []string{"login", "-token-only", "-no-store", "username=admin", "password=secret"},
+			expected: []string{"login", "-format=json", "-no-store", "username=admin", "password=secret"},
 		},
 	}
 
@@ -276,3 +282,63 @@ func TestParseLoginFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLoginParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected map[string]string
+	}{
+		{
+			name:     "empty args",
+			args:     []string{},
+			expected: map[string]string{},
+		},
+		{
+			name:     "K=V pairs",
+			args:     []string{"username=admin", "password=secret"},
+			expected: map[string]string{"username": "admin", "password": "secret"},
+		},
+		{
+			name:     "value containing equals sign",
+			args:     []string{"password=a=b"},
+			expected: map[string]string{"password": "a=b"},
+		},
+		{
+			name:     "ignores args without equals sign",
+			args:     []string{"admin", "role_id=abc"},
+			expected: map[string]string{"role_id": "abc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseLoginParams(tt.args)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseLoginParams(%v) = %v, want %v", tt.args, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunBinaryLogin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the vault binary")
+	}
+
+	// The fake binary prints the JSON response of a limited-use token.
+	vaultPath := filepath.Join(t.TempDir(), "vault")
+	script := "#!/bin/sh\necho '{\"auth\": {\"client_token\": \"hvs.limited\", \"lease_duration\": 3600, \"num_uses\": 3}}'\n"
+	if err := os.WriteFile(vaultPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to create fake vault binary: %v", err)
+	}
+
+	prof := &types.Profile{Name: "test", Address: "https://vault.example.com:8200", BinaryPath: vaultPath}
+	resp, err := (&CLI{}).runBinaryLogin(context.Background(), prof, "userpass", nil, []string{"-method=userpass"})
+	if err != nil {
+		t.Fatalf("runBinaryLogin() error = %v", err)
+	}
+	if resp.ClientToken != "hvs.limited" || resp.NumUses != 3 {
+		t.Errorf("runBinaryLogin() = token %q, num_uses %d; want hvs.limited, 3", resp.ClientToken, resp.NumUses)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/xabinapal/patrol/internal/config"
 	"github.com/xabinapal/patrol/internal/profile"
 	"github.com/xabinapal/patrol/internal/token"
 	"github.com/xabinapal/patrol/internal/types"
@@ -107,6 +108,7 @@ func (cli *CLI) runLogout(ctx context.Context, profileName string, revoke bool)
 	if err := tm.Delete(prof); err != nil {
 		return fmt.Errorf("failed to remove token: %w", err)
 	}
+	cli.runHooks(ctx, config.HookLogout, prof, "")
 
 	fmt.Printf("Successfully logged out from %q\n", profileName)
 	if !revoke || !cli.Config.RevokeOnLogout {
@@ -150,6 +152,7 @@ func (cli *CLI) runLogoutAll(ctx context.Context, revoke bool) error {
 			errs = append(errs, fmt.Errorf("%s: %w", conn.Name, err))
 			continue
 		}
+		cli.runHooks(ctx, config.HookLogout, prof, "")
 
 		loggedOut++
 		if cli.verboseFlag {
@@ -24,7 +24,7 @@ import (
 func (cli *CLI) newProfileCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "profile",
-		Aliases: []string{"profiles", "config"},
+		Aliases: []string{"profiles"},
 		Short:   "Manage Vault/OpenBao connection profiles",
 		Long: `Manage connection profiles for different Vault/OpenBao servers.
 
@@ -41,6 +41,9 @@ Examples:
   # Remove a profile
   patrol profile remove old-profile
 
+  # Rename a profile, keeping its stored token
+  patrol profile rename dev development
+
   # Show profile status
   patrol profile status prod`,
 	}
@@ -50,6 +53,7 @@ Examples:
 		cli.newProfileAddCmd(),
 		cli.newProfileRemoveCmd(),
 		cli.newProfileEditCmd(),
+		cli.newProfileRenameCmd(),
 		cli.newProfileRenewCmd(),
 		cli.newProfileRevokeCmd(),
 		cli.newProfileStatusCmd(),
@@ -67,11 +71,13 @@ type ProfileListOutput struct {
 
 // ProfileListOutputItem represents a single profile in the list output.
 type ProfileListOutputItem struct {
-	Name      string `json:"name"`
-	Address   string `json:"address"`
-	Type      string `json:"type"`
-	Namespace string `json:"namespace,omitempty"`
-	Current   bool   `json:"current"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Type      string    `json:"type"`
+	Namespace string    `json:"namespace,omitempty"`
+	Current   bool      `json:"current"`
+	LoggedIn  bool      `json:"logged_in"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
 }
 
 // newProfileListCmd creates the profile list command.
@@ -99,16 +105,29 @@ func (cli *CLI) runProfileList(format OutputFormat) error {
 	pm := profile.NewProfileManager(ctx, cli.Config)
 	profiles := pm.List()
 
+	// Check the token store to see which profiles are logged in (CLI layer
+	// responsibility). Expiry comes from stored metadata, so no server is contacted.
+	tm := token.NewTokenManager(ctx, cli.Store, vault.NewTokenExecutor())
+	metadata := make([]*types.TokenMetadata, len(profiles))
+
 	// Convert to output format
 	profileItems := make([]ProfileListOutputItem, 0, len(profiles))
-	for _, prof := range profiles {
-		profileItems = append(profileItems, ProfileListOutputItem{
+	for i, prof := range profiles {
+		item := ProfileListOutputItem{
 			Name:      prof.Name,
 			Address:   prof.Address,
 			Type:      prof.Type,
 			Namespace: prof.Namespace,
 			Current:   prof.Name == cli.Config.Current,
-		})
+			LoggedIn:  tm.HasToken(prof),
+		}
+		if item.LoggedIn {
+			if meta, err := tm.GetMetadata(prof); err == nil {
+				metadata[i] = meta
+				item.ExpiresAt = meta.ExpiresAt
+			}
+		}
+		profileItems = append(profileItems, item)
 	}
 
 	profileList := ProfileListOutput{
@@ -126,18 +145,16 @@ func (cli *CLI) runProfileList(format OutputFormat) error {
 
 	return output.Write(profileList, func() {
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "NAME\tADDRESS\tTYPE\tLOGGED IN")
+		fmt.Fprintln(w, "NAME\tADDRESS\tTYPE\tLOGGED IN\tEXPIRES")
 
-		for _, prof := range profiles {
+		for i, prof := range profiles {
 			current := ""
 			if prof.Name == cli.Config.Current {
 				current = "* "
 			}
 
-			// Check keyring to see if logged in (CLI layer responsibility)
 			loggedInStr := "no"
-			tm := token.NewTokenManager(ctx, cli.Store, vault.NewTokenExecutor())
-			if tm.HasToken(prof) {
+			if profileItems[i].LoggedIn {
 				loggedInStr = "yes"
 			}
 
@@ -146,7 +163,8 @@ func (cli *CLI) runProfileList(format OutputFormat) error {
 				connType = "vault"
 			}
 
-			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\n", current, prof.Name, prof.Address, connType, loggedInStr)
+			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\n", current, prof.Name, prof.Address, connType, loggedInStr,
+				formatTokenExpiry(profileItems[i].LoggedIn, metadata[i]))
 		}
 
 		// #nosec G104 - Flush error on stdout; if write fails, user will see incomplete output
@@ -158,6 +176,20 @@ func (cli *CLI) runProfileList(format OutputFormat) error {
 	})
 }
 
+// formatTokenExpiry describes when a stored token expires based on its metadata.
+func formatTokenExpiry(loggedIn bool, meta *types.TokenMetadata) string {
+	switch {
+	case !loggedIn:
+		return "-"
+	case meta == nil:
+		return "unknown"
+	case meta.ExpiresAt.IsZero():
+		return "never"
+	default:
+		return utils.FormatDuration(meta.TTL())
+	}
+}
+
 // newProfileAddCmd creates the profile add command.
 func (cli *CLI) newProfileAddCmd() *cobra.Command {
 	var (
@@ -399,6 +431,124 @@ func (cli *CLI) newProfileEditCmd() *cobra.Command {
 	return cmd
 }
 
+// newProfileRenameCmd creates the profile rename command.
+func (cli *CLI) newProfileRenameCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rename <old> <new>",
+		Aliases: []string{"mv"},
+		Short:   "Rename a profile, keeping its stored token",
+		Long: `Rename a connection profile.
+
+Stored tokens are keyed by profile name, so renaming a profile by editing
+the configuration file leaves its token behind. This command moves the
+stored token and its metadata to the new name, along with the last use
+of the token, and updates the active profile if needed. If either the token
+store or the configuration cannot be updated, both are left as they were.
+The daemon keeps no renewal history across the rename: its failure count
+and backoff start afresh under the new name.
+
+Examples:
+  # Rename the dev profile to development
+  patrol profile rename dev development`,
+		Args: cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return cli.getProfileNames(), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			oldName, newName := args[0], args[1]
+
+			moved, err := renameProfile(cli.Config, cli.Store, oldName, newName)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Renamed profile %q to %q\n", oldName, newName)
+			if moved {
+				fmt.Println("The stored token was moved to the new name.")
+			}
+			return nil
+		},
+	}
+}
+
+// renameProfile renames a profile in cfg and moves its stored token and
+// metadata in store. The token is copied before the configuration is saved
+// and the copy is removed again if saving fails, so a failure on either side
+// leaves both unchanged. The last-use record of the profile is moved
+// afterwards. It reports whether a token was moved.
+func renameProfile(cfg *config.Config, store tokenstore.TokenStore, oldName, newName string) (bool, error) {
+	if !utils.IsValidProfileName(newName) {
+		return false, fmt.Errorf("invalid profile name %q: use letters, digits, '-', '_' and '.'", newName)
+	}
+	if oldName == newName {
+		return false, fmt.Errorf("profile is already named %q", newName)
+	}
+
+	conn, err := cfg.GetConnection(oldName)
+	if err != nil {
+		return false, err
+	}
+	if _, err := cfg.GetConnection(newName); err == nil {
+		return false, fmt.Errorf("profile %q already exists", newName)
+	}
+
+	oldProf := types.FromConnection(conn)
+	newProf := *oldProf
+	newProf.Name = newName
+
+	hasToken := true
+	if _, err := store.Get(oldProf); err != nil {
+		if !errors.Is(err, tokenstore.ErrTokenNotFound) {
+			return false, fmt.Errorf("failed to read stored token: %w", err)
+		}
+		hasToken = false
+	}
+
+	// Never overwrite or adopt a token left behind under the new name.
+	if _, err := store.Get(&newProf); err == nil {
+		return false, fmt.Errorf("a token is already stored for %q; remove it with 'patrol token gc' first", newName)
+	}
+
+	if hasToken {
+		if _, err := tokenstore.CopyEntry(store, oldProf, store, &newProf); err != nil {
+			return false, fmt.Errorf("failed to move stored token: %w", err)
+		}
+	}
+
+	if err := cfg.RenameConnection(oldName, newName); err != nil {
+		if hasToken {
+			_ = store.Delete(&newProf) //nolint:errcheck // best-effort rollback
+		}
+		return false, err
+	}
+
+	if err := cfg.Save(); err != nil {
+		_ = cfg.RenameConnection(newName, oldName) //nolint:errcheck // restores the previous state
+		if hasToken {
+			_ = store.Delete(&newProf) //nolint:errcheck // best-effort rollback
+		}
+		return false, fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	if hasToken {
+		if err := store.Delete(oldProf); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove the token stored under %q: %v\n", oldName, err)
+			fmt.Fprintln(os.Stderr, "Run 'patrol token gc' to clean it up.")
+		}
+	}
+
+	// The last use of the token follows the profile; losing it only resets
+	// the idle period.
+	if err := token.NewUsageLog(token.DefaultUsageDir()).Move(oldProf, &newProf); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to move the last use of %q: %v\n", oldName, err)
+	}
+
+	return hasToken, nil
+}
+
 // newProfileUseCmd creates the profile use command for switching profiles.
 func (cli *CLI) newProfileUseCmd() *cobra.Command {
 	return &cobra.Command{
@@ -520,6 +670,7 @@ If no profile name is given, renews the token for the current profile.`,
 			if err != nil {
 				return err
 			}
+			cli.runHooks(ctx, config.HookRenewal, prof, tok.ClientToken)
 
 			if tok.ClientToken != storedToken {
 				fmt.Println("Token renewed and updated in keyring")
@@ -605,6 +756,7 @@ If no profile name is given, revokes the token for the current profile.`,
 			if err := tm.Delete(prof); err != nil {
 				return fmt.Errorf("failed to remove token from keyring: %w", err)
 			}
+			cli.runHooks(ctx, config.HookLogout, prof, "")
 
 			fmt.Println("Token removed from keyring")
 			return nil
@@ -621,6 +773,7 @@ type ProfileStatusOutput struct {
 	Profile *ProfileStatusOutputProfileItem `json:"profile,omitempty"`
 	Server  *ProfileStatusOutputServerItem  `json:"server,omitempty"`
 	Token   *ProfileStatusOutputTokenItem   `json:"token,omitempty"`
+	Offline bool                            `json:"offline,omitempty"`
 }
 
 // ProfileStatusOutputProfileItem represents a profile in status output.
@@ -647,16 +800,37 @@ type ProfileStatusOutputServerItem struct {
 
 // ProfileStatusOutputTokenItem represents token status output for JSON.
 type ProfileStatusOutputTokenItem struct {
-	Token     string    `json:"token"`
-	TTL       int       `json:"ttl"`
-	Renewable bool      `json:"renewable"`
-	Valid     bool      `json:"valid"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token          string    `json:"token"`
+	TTL            int       `json:"ttl"`
+	Renewable      bool      `json:"renewable"`
+	Valid          bool      `json:"valid"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Accessor       string    `json:"accessor,omitempty"`
+	Policies       []string  `json:"policies,omitempty"`
+	TokenType      string    `json:"token_type,omitempty"`
+	IssueTime      time.Time `json:"issue_time,omitzero"`
+	ExplicitMaxTTL int       `json:"explicit_max_ttl,omitempty"`
+	LastRenewal    time.Time `json:"last_renewal,omitzero"`
+	HardExpiresAt  time.Time `json:"hard_expires_at,omitzero"`
+}
+
+// applyMetadata copies the cached token metadata into the output item.
+func (t *ProfileStatusOutputTokenItem) applyMetadata(meta *types.TokenMetadata) {
+	t.Accessor = meta.Accessor
+	t.Policies = meta.Policies
+	t.TokenType = meta.TokenType
+	t.IssueTime = meta.IssueTime
+	t.ExplicitMaxTTL = meta.ExplicitMaxTTL
+	t.LastRenewal = meta.LastRenewal
+	if hardExpiry, ok := meta.HardExpiry(); ok {
+		t.HardExpiresAt = hardExpiry
+	}
 }
 
 // newProfileStatusCmd creates the profile status command.
 func (cli *CLI) newProfileStatusCmd() *cobra.Command {
 	var showToken bool
+	var offline bool
 
 	cmd := &cobra.Command{
 		Use:   "status [name]",
@@ -668,6 +842,9 @@ func (cli *CLI) newProfileStatusCmd() *cobra.Command {
 
 By default, the full token is masked. Use --show-token to display it.
 
+Use --offline to skip contacting the server and show the token metadata
+cached when the token was last obtained, renewed or looked up.
+
 If no profile name is given, shows status for the current profile.`,
 		Args: cobra.MaximumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -699,21 +876,22 @@ If no profile name is given, shows status for the current profile.`,
 				}
 			}
 
-			return cli.runProfileStatus(ctx, prof, format, showToken)
+			return cli.runProfileStatus(ctx, prof, format, showToken, offline)
 		},
 	}
 
 	cmd.Flags().BoolVar(&showToken, "show-token", false, "Show the full token (not masked)")
+	cmd.Flags().BoolVar(&offline, "offline", false, "Use cached token metadata without contacting the server")
 
 	return cmd
 }
 
 // runProfileStatus displays comprehensive status for a profile.
-func (cli *CLI) runProfileStatus(ctx context.Context, prof *types.Profile, format OutputFormat, showToken bool) error {
+func (cli *CLI) runProfileStatus(ctx context.Context, prof *types.Profile, format OutputFormat, showToken, offline bool) error {
 	output := NewOutputWriter(format)
 
 	// Initialize status output for JSON
-	status := &ProfileStatusOutput{}
+	status := &ProfileStatusOutput{Offline: offline}
 
 	// Convert to output format
 	status.Profile = &ProfileStatusOutputProfileItem{
@@ -731,6 +909,11 @@ func (cli *CLI) runProfileStatus(ctx context.Context, prof *types.Profile, forma
 		Active:        prof.Name == cli.Config.Current,
 	}
 
+	tm := token.NewTokenManager(ctx, cli.Store, vault.NewTokenExecutor())
+	if offline {
+		return cli.runProfileStatusOffline(tm, prof, status, output, showToken)
+	}
+
 	// Test server connectivity
 	healthExecutor := vault.NewHealthExecutor()
 	serverStatus := healthExecutor.CheckHealth(ctx, prof)
@@ -741,8 +924,7 @@ func (cli *CLI) runProfileStatus(ctx context.Context, prof *types.Profile, forma
 		}
 	}
 
-	// Get token status
-	tm := token.NewTokenManager(ctx, cli.Store, vault.NewTokenExecutor())
+	// Get token status (a successful lookup also refreshes the cached metadata)
 	tok, err := tm.Lookup(prof)
 
 	// Get stored token string if we have a token (even if invalid)
@@ -770,6 +952,9 @@ func (cli *CLI) runProfileStatus(ctx context.Context, prof *types.Profile, forma
 			Valid:     true,
 			ExpiresAt: tok.ExpiresAt,
 		}
+		if meta, metaErr := tm.GetMetadata(prof); metaErr == nil {
+			tokenOutput.applyMetadata(meta)
+		}
 	} else if storedToken != "" {
 		// We have a stored token but lookup failed (invalid/expired token)
 		tokenOutput = &ProfileStatusOutputTokenItem{
@@ -789,6 +974,43 @@ func (cli *CLI) runProfileStatus(ctx context.Context, prof *types.Profile, forma
 	})
 }
 
+// runProfileStatusOffline displays profile status from cached token metadata only.
+func (cli *CLI) runProfileStatusOffline(tm *token.TokenManager, prof *types.Profile, status *ProfileStatusOutput, output *OutputWriter, showToken bool) error {
+	storedToken, err := tm.Get(prof)
+
+	var meta *types.TokenMetadata
+	if err == nil {
+		status.Token = &ProfileStatusOutputTokenItem{Token: storedToken}
+		if cached, metaErr := tm.GetMetadata(prof); metaErr == nil {
+			meta = cached
+			ttl := meta.TTL()
+			status.Token.TTL = int(ttl.Seconds())
+			status.Token.Renewable = meta.Renewable
+			status.Token.Valid = ttl > 0 || meta.ExpiresAt.IsZero()
+			status.Token.ExpiresAt = meta.ExpiresAt
+			status.Token.applyMetadata(meta)
+		}
+	}
+
+	return output.Write(status, func() {
+		cli.printProfileStatusHeader(status.Profile)
+		if err == nil && meta == nil {
+			fmt.Println("Token Information:")
+			if showToken {
+				fmt.Printf("  Token:           %s\n", storedToken)
+			} else {
+				fmt.Printf("  Token:           %s\n", utils.MaskToken(storedToken))
+			}
+			fmt.Printf("  Status:          stored (no cached metadata)\n")
+			return
+		}
+		cli.printTokenInformation(status.Token, err, nil, storedToken, showToken)
+		if err == nil {
+			fmt.Println("Token details are cached; run without --offline to verify them with the server.")
+		}
+	})
+}
+
 // printProfileStatusHeader prints the profile configuration header.
 func (cli *CLI) printProfileStatusHeader(prof *ProfileStatusOutputProfileItem) {
 	if prof == nil {
@@ -877,13 +1099,43 @@ func (cli *CLI) printTokenInformation(tok *ProfileStatusOutputTokenItem, err err
 			} else {
 				fmt.Printf("  TTL:             %s\n", utils.FormatDuration(ttl))
 			}
+		} else if !tok.Valid && !tok.ExpiresAt.IsZero() {
+			fmt.Printf("  TTL:             expired (at %s)\n", tok.ExpiresAt.Format(time.RFC3339))
 		} else {
 			fmt.Printf("  TTL:             ∞ (never expires)\n")
 		}
 		fmt.Printf("  Renewable:       %t\n", tok.Renewable)
 		fmt.Printf("  Valid:           %t\n", tok.Valid)
+		if tok.TokenType != "" {
+			fmt.Printf("  Type:            %s\n", tok.TokenType)
+		}
+		if tok.Accessor != "" {
+			fmt.Printf("  Accessor:        %s\n", tok.Accessor)
+		}
+		if len(tok.Policies) > 0 {
+			fmt.Printf("  Policies:        %s\n", strings.Join(tok.Policies, ", "))
+		}
+		if !tok.IssueTime.IsZero() {
+			fmt.Printf("  Issued:          %s\n", tok.IssueTime.Format(time.RFC3339))
+		}
+		if !tok.LastRenewal.IsZero() {
+			fmt.Printf("  Last Renewal:    %s\n", tok.LastRenewal.Format(time.RFC3339))
+		}
+		if tok.ExplicitMaxTTL > 0 {
+			fmt.Printf("  Max TTL:         %s\n", utils.FormatDurationSeconds(tok.ExplicitMaxTTL))
+		}
+		sessionLeft := time.Until(tok.HardExpiresAt)
+		if !tok.HardExpiresAt.IsZero() && sessionLeft > 0 {
+			fmt.Printf("  Session Ends:    %s (in %s)\n", tok.HardExpiresAt.Format(time.RFC3339), utils.FormatDuration(sessionLeft))
+		}
 		fmt.Println()
 
+		// The session cannot be extended past its hard expiry
+		const SessionEndWarning = time.Hour
+		if !tok.HardExpiresAt.IsZero() && sessionLeft > 0 && sessionLeft < SessionEndWarning {
+			fmt.Println("Warning: Token cannot be renewed past its session end. Run 'patrol login' before then to keep access.")
+		}
+
 		// Renewal recommendation
 		const TokenExpiryWarningSeconds = 300 // 5 minutes
 		if tok.TTL > 0 && tok.TTL < TokenExpiryWarningSeconds && tok.Renewable {
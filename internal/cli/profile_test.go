@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/tokenstore"
+	"github.com/xabinapal/patrol/internal/types"
 )
 
 func TestProfileListOutput(t *testing.T) {
@@ -59,3 +64,146 @@ func TestGetProfileNamesNilConfig(t *testing.T) {
 		t.Errorf("getProfileNames() = %v, want nil", names)
 	}
 }
+
+func TestRenameProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmpDir, ".local", "share"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, ".cache"))
+
+	newConfig := func(t *testing.T, path string) *config.Config {
+		t.Helper()
+		cfg, err := config.LoadFrom(path)
+		if err != nil {
+			t.Fatalf("LoadFrom() failed: %v", err)
+		}
+		cfg.Connections = []config.Connection{
+			{Name: "dev", Address: "https://dev.example.com:8200"},
+			{Name: "prod", Address: "https://prod.example.com:8200"},
+		}
+		cfg.Current = "dev"
+		return cfg
+	}
+	newStore := func(t *testing.T) *tokenstore.FileStore {
+		t.Helper()
+		store, err := tokenstore.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewFileStore() failed: %v", err)
+		}
+		return store
+	}
+	dev := &types.Profile{Name: "dev"}
+	development := &types.Profile{Name: "development"}
+
+	t.Run("moves token and metadata", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		cfg := newConfig(t, path)
+		store := newStore(t)
+		if err := store.Set(dev, "hvs.dev"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetMetadata(dev, &types.TokenMetadata{Accessor: "acc"}); err != nil {
+			t.Fatal(err)
+		}
+
+		usage := token.NewUsageLog(token.DefaultUsageDir())
+		used := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := usage.Touch(dev, used); err != nil {
+			t.Fatal(err)
+		}
+
+		moved, err := renameProfile(cfg, store, "dev", "development")
+		if err != nil {
+			t.Fatalf("renameProfile() failed: %v", err)
+		}
+		if !moved {
+			t.Error("renameProfile() reported no token moved")
+		}
+		if lastUsed, err := usage.LastUsed(development); err != nil || !lastUsed.Equal(used) {
+			t.Errorf("last use under new name = %v, %v; want %v", lastUsed, err, used)
+		}
+		if cfg.Current != "development" {
+			t.Errorf("Current = %q, want %q", cfg.Current, "development")
+		}
+		if got, _ := store.Get(development); got != "hvs.dev" {
+			t.Errorf("token under new name = %q, want %q", got, "hvs.dev")
+		}
+		if meta, err := store.GetMetadata(development); err != nil || meta.Accessor != "acc" {
+			t.Errorf("metadata under new name = %+v, %v", meta, err)
+		}
+		if _, err := store.Get(dev); err == nil {
+			t.Error("token under old name was not removed")
+		}
+
+		saved, err := config.LoadFrom(path)
+		if err != nil {
+			t.Fatalf("LoadFrom() failed: %v", err)
+		}
+		if _, err := saved.GetConnection("development"); err != nil || saved.Current != "development" {
+			t.Errorf("saved config not renamed: current=%q, err=%v", saved.Current, err)
+		}
+	})
+
+	t.Run("without token", func(t *testing.T) {
+		cfg := newConfig(t, filepath.Join(t.TempDir(), "config.yaml"))
+		moved, err := renameProfile(cfg, newStore(t), "prod", "production")
+		if err != nil {
+			t.Fatalf("renameProfile() failed: %v", err)
+		}
+		if moved {
+			t.Error("renameProfile() reported a token moved")
+		}
+		if cfg.Current != "dev" {
+			t.Errorf("Current = %q, want %q", cfg.Current, "dev")
+		}
+	})
+
+	t.Run("rejects invalid names", func(t *testing.T) {
+		cfg := newConfig(t, filepath.Join(t.TempDir(), "config.yaml"))
+		store := newStore(t)
+		for _, name := range []string{"prod", "dev", "bad name", ""} {
+			if _, err := renameProfile(cfg, store, "dev", name); err == nil {
+				t.Errorf("renameProfile(%q) should fail", name)
+			}
+		}
+		if _, err := renameProfile(cfg, store, "missing", "other"); err == nil {
+			t.Error("renameProfile() should fail for a missing profile")
+		}
+	})
+
+	t.Run("refuses leftover token under new name", func(t *testing.T) {
+		cfg := newConfig(t, filepath.Join(t.TempDir(), "config.yaml"))
+		store := newStore(t)
+		if err := store.Set(development, "hvs.stale"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := renameProfile(cfg, store, "dev", "development"); err == nil {
+			t.Fatal("renameProfile() should fail when a token exists under the new name")
+		}
+		if got, _ := store.Get(development); got != "hvs.stale" {
+			t.Errorf("leftover token was modified: %q", got)
+		}
+	})
+
+	t.Run("rolls back when saving fails", func(t *testing.T) {
+		cfg := newConfig(t, filepath.Join(t.TempDir(), "missing", "config.yaml"))
+		store := newStore(t)
+		if err := store.Set(dev, "hvs.dev"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := renameProfile(cfg, store, "dev", "development"); err == nil {
+			t.Fatal("renameProfile() should fail when the config cannot be saved")
+		}
+		if _, err := cfg.GetConnection("dev"); err != nil || cfg.Current != "dev" {
+			t.Errorf("config not rolled back: current=%q, err=%v", cfg.Current, err)
+		}
+		if got, _ := store.Get(dev); got != "hvs.dev" {
+			t.Errorf("token under old name = %q, want %q", got, "hvs.dev")
+		}
+		if _, err := store.Get(development); err == nil {
+			t.Error("token copy under new name was not rolled back")
+		}
+	})
+}
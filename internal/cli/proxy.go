@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/xabinapal/patrol/internal/config"
 	"github.com/xabinapal/patrol/internal/proxy"
 	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/types"
 	"github.com/xabinapal/patrol/internal/utils"
 	"github.com/xabinapal/patrol/internal/vault"
 )
@@ -17,7 +19,7 @@ import (
 // This prevents bugs where new commands are added to addCommands() but not here.
 var patrolCommands = map[string]bool{
 	// Core commands
-	"profile": true, "daemon": true,
+	"profile": true, "daemon": true, "token": true,
 	"login": true, "logout": true,
 	// Token helper commands (used by Vault)
 	"get": true, "store": true, "erase": true,
@@ -26,6 +28,13 @@ var patrolCommands = map[string]bool{
 	"help": true, "completion": true,
 }
 
+// sharedCommands lists the Patrol command groups whose name is also a Vault
+// CLI command. Patrol only handles the subcommands listed here; any other
+// subcommand (e.g. "token lookup") is proxied to vault/bao.
+var sharedCommands = map[string]map[string]bool{
+	"token": {"migrate": true, "gc": true, "set-secret": true, "help": true},
+}
+
 // ShouldProxy checks if the command should be proxied to vault/bao.
 // Returns true and the args if we should proxy, false otherwise.
 func (cli *CLI) ShouldProxy() (bool, []string) {
@@ -39,9 +48,9 @@ func (cli *CLI) ShouldProxy() (bool, []string) {
 		return false, nil
 	}
 
-	// Check if the first non-flag arg is a patrol command
-	firstArg := args[0]
-	if !isPatrolCommand(firstArg) && !strings.HasPrefix(firstArg, "-") {
+	// extractVaultArgs already dropped patrol commands; a leading flag
+	// is left to the root command so that e.g. --help works.
+	if !strings.HasPrefix(args[0], "-") {
 		return true, args
 	}
 
@@ -72,6 +81,10 @@ func (cli *CLI) InitializeForProxy() error {
 	}
 	cli.Config = cfg
 
+	if err := cli.initStore(); err != nil {
+		return err
+	}
+
 	// Override current profile if flag is set
 	if cli.profileFlag != "" {
 		if err := cli.Config.SetCurrent(cli.profileFlag); err != nil {
@@ -107,6 +120,11 @@ func (cli *CLI) proxyCommand(ctx context.Context, args []string) error {
 	// Get the stored token (optional - token is not required for proxy)
 	tm := token.NewTokenManager(ctx, cli.Store, vault.NewTokenExecutor())
 	tokenStr, _ := tm.Get(prof) //nolint:errcheck // token is optional
+	if tokenStr != "" {
+		if conn, err := cli.Config.GetConnection(prof.Name); err == nil {
+			recordTokenUse(conn, prof)
+		}
+	}
 
 	// Create the executor
 	conn := prof.ToConnection()
@@ -131,6 +149,15 @@ func (cli *CLI) proxyCommand(ctx context.Context, args []string) error {
 	return nil
 }
 
+// recordTokenUse records that the token of prof was handed out, so the
+// daemon does not revoke it under the connection's idle_timeout.
+func recordTokenUse(conn *config.Connection, prof *types.Profile) {
+	if conn.IdleTimeout <= 0 {
+		return
+	}
+	_ = token.NewUsageLog(token.DefaultUsageDir()).Touch(prof, time.Now()) //nolint:errcheck // a missed use only shortens the idle period
+}
+
 // extractVaultArgs extracts arguments meant for the Vault CLI.
 func extractVaultArgs() []string {
 	args := os.Args[1:]
@@ -138,7 +165,6 @@ func extractVaultArgs() []string {
 	// Skip patrol-specific flags
 	vaultArgs := make([]string, 0, len(args))
 	skipNext := false
-	foundCommand := false
 	for _, arg := range args {
 		if skipNext {
 			skipNext = false
@@ -157,21 +183,40 @@ func extractVaultArgs() []string {
 			continue
 		}
 
-		// Only check the FIRST non-flag argument to see if it's a patrol command
-		// Subsequent args like "get" in "kv get" should not be checked
-		if !foundCommand && !strings.HasPrefix(arg, "-") {
-			if isPatrolCommand(arg) {
-				return nil
-			}
-			foundCommand = true
-		}
-
 		vaultArgs = append(vaultArgs, arg)
 	}
 
+	// Only check the FIRST non-flag argument to see if it's a patrol command
+	// Subsequent args like "get" in "kv get" should not be checked
+	for i, arg := range vaultArgs {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if isPatrolCommand(arg) && !isVaultSubcommand(arg, vaultArgs[i+1:]) {
+			return nil
+		}
+		break
+	}
+
 	return vaultArgs
 }
 
+// isVaultSubcommand reports whether args name a subcommand of the shared
+// command group that Patrol does not define, so it belongs to vault/bao.
+// A bare group or one followed only by flags stays with Patrol.
+func isVaultSubcommand(group string, args []string) bool {
+	subcommands, ok := sharedCommands[group]
+	if !ok {
+		return false
+	}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return !subcommands[arg]
+		}
+	}
+	return false
+}
+
 // isPatrolCommand checks if the given command is a built-in Patrol command.
 func isPatrolCommand(name string) bool {
 	return patrolCommands[name]
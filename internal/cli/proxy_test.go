@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestShouldProxy(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantProxy bool
+		wantArgs  []string
+	}{
+		{
+			name:      "vault command",
+			args:      []string{"kv", "get", "secret/foo"},
+			wantProxy: true,
+			wantArgs:  []string{"kv", "get", "secret/foo"},
+		},
+		{
+			name:      "patrol flags are stripped",
+			args:      []string{"-p", "prod", "kv", "get", "secret/foo"},
+			wantProxy: true,
+			wantArgs:  []string{"kv", "get", "secret/foo"},
+		},
+		{
+			name:      "patrol command",
+			args:      []string{"profile", "list"},
+			wantProxy: false,
+		},
+		{
+			name:      "vault token subcommand",
+			args:      []string{"token", "lookup"},
+			wantProxy: true,
+			wantArgs:  []string{"token", "lookup"},
+		},
+		{
+			name:      "vault token subcommand with flags",
+			args:      []string{"--profile", "prod", "token", "-format=json", "lookup", "-self"},
+			wantProxy: true,
+			wantArgs:  []string{"token", "-format=json", "lookup", "-self"},
+		},
+		{
+			name:      "patrol token subcommand",
+			args:      []string{"token", "gc", "--dry-run"},
+			wantProxy: false,
+		},
+		{
+			name:      "bare token group",
+			args:      []string{"token"},
+			wantProxy: false,
+		},
+		{
+			name:      "token group help",
+			args:      []string{"token", "--help"},
+			wantProxy: false,
+		},
+		{
+			name:      "leading flag",
+			args:      []string{"--help"},
+			wantProxy: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldArgs := os.Args
+			t.Cleanup(func() { os.Args = oldArgs })
+			os.Args = append([]string{"patrol"}, tt.args...)
+
+			gotProxy, gotArgs := (&CLI{}).ShouldProxy()
+			if gotProxy != tt.wantProxy {
+				t.Fatalf("ShouldProxy() = %v, want %v", gotProxy, tt.wantProxy)
+			}
+			if tt.wantProxy && !slices.Equal(gotArgs, tt.wantArgs) {
+				t.Errorf("ShouldProxy() args = %v, want %v", gotArgs, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -83,6 +83,7 @@ func (cli *CLI) addCommands() {
 		cli.newProfileCmd(),
 		cli.newConfigCmd(),
 		cli.newDaemonCmd(),
+		cli.newTokenCmd(),
 		cli.newTokenHelperCmd(),
 		cli.newCompletionCmd(),
 	)
@@ -102,6 +103,14 @@ func (cli *CLI) initialize(cmd *cobra.Command) error {
 	}
 	cli.Config = cfg
 
+	// The config commands never touch tokens, and must keep working with a
+	// token_store section that cannot be used so it can be diagnosed and fixed.
+	if !isConfigCommand(cmd) {
+		if err := cli.initStore(); err != nil {
+			return err
+		}
+	}
+
 	// Override current profile if flag is set
 	if cli.profileFlag != "" {
 		if err := cli.Config.SetCurrent(cli.profileFlag); err != nil {
@@ -127,6 +136,30 @@ func (cli *CLI) initialize(cmd *cobra.Command) error {
 	return nil
 }
 
+// isConfigCommand reports whether cmd is the config command or one of its
+// subcommands.
+func isConfigCommand(cmd *cobra.Command) bool {
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		if c.Parent() == c.Root() {
+			return c.Name() == "config"
+		}
+	}
+	return false
+}
+
+// initStore replaces the default token store with the backend selected in
+// the token_store configuration section. Keys written through the store are
+// recorded in the key index used by "patrol token gc".
+func (cli *CLI) initStore() error {
+	store, err := tokenstore.NewTokenStoreFromConfig(&cli.Config.TokenStore)
+	if err != nil {
+		return fmt.Errorf("failed to initialize token store: %w", err)
+	}
+	cli.Store = tokenstore.NewIndexedStore(store, cli.Config.TokenStore.GetType(),
+		tokenstore.NewKeyIndex(tokenstore.DefaultIndexPath()))
+	return nil
+}
+
 // Execute runs the CLI.
 // It detects the command type and routes to the appropriate handler.
 func (cli *CLI) Execute(ctx context.Context) error {
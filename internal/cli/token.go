@@ -0,0 +1,620 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/tokenstore"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// Token migration statuses.
+const (
+	migrateStatusMigrated     = "migrated"
+	migrateStatusWouldMigrate = "would_migrate"
+	migrateStatusNoToken      = "no_token"
+	migrateStatusUpToDate     = "up_to_date"
+	migrateStatusConflict     = "conflict"
+	migrateStatusFailed       = "failed"
+)
+
+// TokenMigrateOutput represents token migrate output for JSON.
+type TokenMigrateOutput struct {
+	From         string                   `json:"from"`
+	To           string                   `json:"to"`
+	DryRun       bool                     `json:"dry_run"`
+	DeleteSource bool                     `json:"delete_source"`
+	Profiles     []TokenMigrateOutputItem `json:"profiles"`
+	Migrated     int                      `json:"migrated"`
+	Skipped      int                      `json:"skipped"`
+	Failed       int                      `json:"failed"`
+}
+
+// TokenMigrateOutputItem represents the migration result for a single profile.
+type TokenMigrateOutputItem struct {
+	Name          string `json:"name"`
+	Status        string `json:"status"`
+	Metadata      bool   `json:"metadata"`
+	SourceDeleted bool   `json:"source_deleted,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// TokenGCOutput represents token gc output for JSON.
+type TokenGCOutput struct {
+	Store   string              `json:"store"`
+	DryRun  bool                `json:"dry_run"`
+	Orphans []TokenGCOutputItem `json:"orphans"`
+	Removed int                 `json:"removed"`
+	Failed  int                 `json:"failed"`
+}
+
+// TokenGCOutputItem represents a single orphaned store entry.
+type TokenGCOutputItem struct {
+	Key       string    `json:"key"`
+	Profile   string    `json:"profile,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	Removed   bool      `json:"removed"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// newTokenCmd creates the token command group.
+func (cli *CLI) newTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage stored tokens",
+		Long: `Manage the tokens Patrol keeps in its token store.
+
+Other token subcommands, such as "patrol token lookup", are passed to the
+Vault/OpenBao CLI.
+
+Examples:
+  # Move all tokens from the OS keyring to the encrypted file store
+  patrol token migrate --from keyring --to encrypted_file
+
+  # Remove tokens left behind by renamed or removed profiles
+  patrol token gc
+
+  # Store the AppRole secret ID used by auto_login
+  patrol token set-secret ci < secret-id.txt`,
+	}
+
+	cmd.AddCommand(
+		cli.newTokenMigrateCmd(),
+		cli.newTokenGCCmd(),
+		cli.newTokenSetSecretCmd(),
+	)
+
+	return cmd
+}
+
+// newTokenMigrateCmd creates the token migrate command.
+func (cli *CLI) newTokenMigrateCmd() *cobra.Command {
+	var (
+		from         string
+		to           string
+		dryRun       bool
+		deleteSource bool
+		force        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "migrate --from <store> --to <store>",
+		Short: "Copy stored tokens between token store backends",
+		Long: `Copy the token and metadata of every configured profile from one token
+store backend to another. Each copy is verified by reading it back. AppRole
+secret IDs kept in the store for auto_login are copied as well.
+
+Supported stores: keyring, encrypted_file (alias: file) and helper. Settings
+for the encrypted file and helper stores are taken from the token_store
+configuration section.
+
+Profiles that already have a different token in the destination are left
+untouched unless --force is given. Use --delete-source to remove tokens from
+the source store once they have been copied and verified.
+
+After migrating, set token_store.type in the configuration to the
+destination store so Patrol uses it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := ParseOutputFormat(cli.outputFlag)
+			if err != nil {
+				return err
+			}
+			return cli.runTokenMigrate(format, from, to, dryRun, deleteSource, force)
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "Source token store (required)")
+	cmd.Flags().StringVar(&to, "to", "", "Destination token store (required)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be migrated without changing anything")
+	cmd.Flags().BoolVar(&deleteSource, "delete-source", false, "Delete tokens from the source store after a verified copy")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite different tokens already in the destination store")
+
+	if err := cmd.MarkFlagRequired("from"); err != nil {
+		return nil
+	}
+	if err := cmd.MarkFlagRequired("to"); err != nil {
+		return nil
+	}
+
+	return cmd
+}
+
+// parseStoreType normalizes a token store name given on the command line.
+func parseStoreType(name string) string {
+	if name == "file" {
+		return config.TokenStoreEncryptedFile
+	}
+	return name
+}
+
+// runTokenMigrate copies tokens between two token stores.
+func (cli *CLI) runTokenMigrate(format OutputFormat, from, to string, dryRun, deleteSource, force bool) error {
+	from, to = parseStoreType(from), parseStoreType(to)
+	if from == to {
+		return fmt.Errorf("source and destination stores are the same (%s)", from)
+	}
+
+	index := tokenstore.NewKeyIndex(tokenstore.DefaultIndexPath())
+
+	srcStore, err := tokenstore.NewTokenStoreOfType(from, &cli.Config.TokenStore)
+	if err != nil {
+		return fmt.Errorf("invalid source store: %w", err)
+	}
+	if err := srcStore.IsAvailable(); err != nil {
+		return fmt.Errorf("source store %s: %w", from, err)
+	}
+	src := tokenstore.NewIndexedStore(srcStore, from, index)
+
+	dstStore, err := tokenstore.NewTokenStoreOfType(to, &cli.Config.TokenStore)
+	if err != nil {
+		return fmt.Errorf("invalid destination store: %w", err)
+	}
+	if err := dstStore.IsAvailable(); err != nil {
+		return fmt.Errorf("destination store %s: %w", to, err)
+	}
+	dst := tokenstore.NewIndexedStore(dstStore, to, index)
+
+	result := TokenMigrateOutput{
+		From:         from,
+		To:           to,
+		DryRun:       dryRun,
+		DeleteSource: deleteSource,
+		Profiles:     make([]TokenMigrateOutputItem, 0, len(cli.Config.Connections)),
+	}
+
+	for i := range cli.Config.Connections {
+		conn := &cli.Config.Connections[i]
+		profs := []*types.Profile{types.FromConnection(conn)}
+		if conn.AutoLogin.SecretIDFromStore {
+			profs = append(profs, &types.Profile{Name: conn.AutoLoginSecretName()})
+		}
+
+		for _, prof := range profs {
+			item := migrateProfileToken(src, dst, prof, dryRun, deleteSource, force)
+
+			switch item.Status {
+			case migrateStatusMigrated, migrateStatusWouldMigrate:
+				result.Migrated++
+			case migrateStatusConflict, migrateStatusFailed:
+				result.Failed++
+			default:
+				result.Skipped++
+			}
+			result.Profiles = append(result.Profiles, item)
+		}
+	}
+
+	output := NewOutputWriter(format)
+	writeErr := output.Write(result, func() {
+		if len(result.Profiles) == 0 {
+			fmt.Println("No profiles configured.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROFILE\tSTATUS\tMETADATA\tDETAILS")
+		for _, item := range result.Profiles {
+			details := item.Error
+			if item.SourceDeleted {
+				details = "removed from " + from
+			}
+			metadata := "no"
+			if item.Metadata {
+				metadata = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Name, item.Status, metadata, details)
+		}
+		// #nosec G104 - Flush error on stdout; if write fails, user will see incomplete output
+		_ = w.Flush()
+
+		fmt.Println()
+		if dryRun {
+			fmt.Printf("Dry run: %d to migrate, %d skipped, %d failed\n", result.Migrated, result.Skipped, result.Failed)
+			return
+		}
+		fmt.Printf("%d migrated, %d skipped, %d failed\n", result.Migrated, result.Skipped, result.Failed)
+		if result.Migrated > 0 && cli.Config.TokenStore.GetType() != to {
+			fmt.Printf("\nSet 'token_store.type: %s' in your configuration to use the new store.\n", to)
+		}
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%d profile(s) could not be migrated", result.Failed)
+	}
+	return nil
+}
+
+// migrateProfileToken migrates the token of a single profile.
+func migrateProfileToken(src, dst tokenstore.TokenStore, prof *types.Profile, dryRun, deleteSource, force bool) TokenMigrateOutputItem {
+	item := TokenMigrateOutputItem{Name: prof.Name}
+
+	tokenStr, err := src.Get(prof)
+	if err != nil {
+		if errors.Is(err, tokenstore.ErrTokenNotFound) {
+			item.Status = migrateStatusNoToken
+			return item
+		}
+		item.Status = migrateStatusFailed
+		item.Error = err.Error()
+		return item
+	}
+
+	_, metaErr := src.GetMetadata(prof)
+	item.Metadata = metaErr == nil
+
+	// Never overwrite a different token in the destination without --force,
+	// since CopyEntry removes the destination entry if the copy fails.
+	existing, err := dst.Get(prof)
+	switch {
+	case err == nil && existing == tokenStr:
+		item.Status = migrateStatusUpToDate
+	case err == nil && !force:
+		item.Status = migrateStatusConflict
+		item.Error = "destination has a different token (use --force to overwrite)"
+		return item
+	case err != nil && !errors.Is(err, tokenstore.ErrTokenNotFound):
+		item.Status = migrateStatusFailed
+		item.Error = err.Error()
+		return item
+	}
+
+	if dryRun {
+		if item.Status == "" {
+			item.Status = migrateStatusWouldMigrate
+		}
+		return item
+	}
+
+	// Up-to-date entries are copied again to refresh their metadata.
+	item.Metadata, err = tokenstore.CopyEntry(src, prof, dst, prof)
+	if err != nil {
+		item.Status = migrateStatusFailed
+		item.Error = err.Error()
+		return item
+	}
+	if item.Status == "" {
+		item.Status = migrateStatusMigrated
+	}
+
+	if deleteSource {
+		if err := src.Delete(prof); err != nil {
+			item.Error = fmt.Sprintf("copied, but failed to delete from source: %v", err)
+			return item
+		}
+		item.SourceDeleted = true
+	}
+
+	return item
+}
+
+// newTokenGCCmd creates the token gc command.
+func (cli *CLI) newTokenGCCmd() *cobra.Command {
+	var (
+		dryRun bool
+		force  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove stored tokens that no longer belong to a profile",
+		Long: `Find and remove token store entries that do not belong to any configured
+profile, such as tokens left behind by renamed profiles or by profiles removed
+with --force.
+
+Store keys are hashed profile names, so Patrol keeps an index of the keys it
+has written to find their profiles again. File-based stores are also scanned
+directly; entries found only that way are listed without a profile name.
+
+Token helper entries (stored when Vault runs Patrol as its token helper) are
+kept as long as a profile points at the same address.
+
+You are asked for confirmation before anything is removed unless --force is
+given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := ParseOutputFormat(cli.outputFlag)
+			if err != nil {
+				return err
+			}
+			return cli.runTokenGC(format, dryRun, force)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List orphaned entries without removing them")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Remove orphaned entries without asking for confirmation")
+
+	return cmd
+}
+
+// runTokenGC lists and removes orphaned token store entries.
+func (cli *CLI) runTokenGC(format OutputFormat, dryRun, force bool) error {
+	store, ok := cli.Store.(*tokenstore.IndexedStore)
+	if !ok {
+		return fmt.Errorf("token store does not keep a key index")
+	}
+	if err := store.IsAvailable(); err != nil {
+		return fmt.Errorf("token store %s: %w", store.StoreType(), err)
+	}
+
+	orphans, err := findOrphanedEntries(store, cli.Config.Connections)
+	if err != nil {
+		return err
+	}
+
+	result := TokenGCOutput{
+		Store:   store.StoreType(),
+		DryRun:  dryRun,
+		Orphans: orphans,
+	}
+
+	if len(orphans) > 0 && !dryRun {
+		if force || confirm(os.Stdin, fmt.Sprintf("Remove %d orphaned token(s) from the %s store?",
+			len(orphans), store.StoreType())) {
+			result.Removed, result.Failed = removeOrphanedEntries(store, result.Orphans)
+		} else {
+			result.DryRun = true
+		}
+	}
+
+	output := NewOutputWriter(format)
+	writeErr := output.Write(result, func() {
+		if len(result.Orphans) == 0 {
+			fmt.Println("No orphaned tokens found.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tPROFILE\tLAST UPDATED\tSTATUS")
+		for _, item := range result.Orphans {
+			name := item.Profile
+			if name == "" {
+				name = "(unknown)"
+			}
+			updated := "-"
+			if !item.UpdatedAt.IsZero() {
+				updated = item.UpdatedAt.Local().Format(time.RFC3339)
+			}
+			status := "orphaned"
+			switch {
+			case item.Removed:
+				status = "removed"
+			case item.Error != "":
+				status = "failed: " + item.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", shortStoreKey(item.Key), name, updated, status)
+		}
+		// #nosec G104 - Flush error on stdout; if write fails, user will see incomplete output
+		_ = w.Flush()
+
+		fmt.Println()
+		if result.DryRun {
+			fmt.Printf("%d orphaned token(s) found, nothing removed\n", len(result.Orphans))
+			return
+		}
+		fmt.Printf("%d removed, %d failed\n", result.Removed, result.Failed)
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%d orphaned token(s) could not be removed", result.Failed)
+	}
+	return nil
+}
+
+// liveStoreKeys returns the store keys still referenced by the configured
+// connections: the profile entries, the token helper entries for the
+// connection addresses, with and without the namespace, and the auto_login
+// secret IDs.
+func liveStoreKeys(conns []config.Connection) map[string]bool {
+	live := make(map[string]bool, len(conns)*3)
+	for i := range conns {
+		names := []string{
+			conns[i].Name,
+			tokenHelperProfileName(conns[i].Address, ""),
+		}
+		if conns[i].Namespace != "" {
+			names = append(names, tokenHelperProfileName(conns[i].Address, conns[i].Namespace))
+		}
+		if conns[i].AutoLogin.SecretIDFromStore {
+			names = append(names, conns[i].AutoLoginSecretName())
+		}
+		for _, name := range names {
+			live[tokenstore.KeyFromProfile(&types.Profile{Name: name})] = true
+		}
+	}
+	return live
+}
+
+// findOrphanedEntries returns the entries of store that no configured
+// connection refers to. Index entries whose token is already gone are
+// dropped from the index.
+func findOrphanedEntries(store *tokenstore.IndexedStore, conns []config.Connection) ([]TokenGCOutputItem, error) {
+	live := liveStoreKeys(conns)
+	base := store.Unwrap()
+
+	entries, err := store.Index().Entries(store.StoreType())
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := make([]TokenGCOutputItem, 0)
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		seen[entry.Key] = true
+		if live[entry.Key] {
+			continue
+		}
+
+		prof := &types.Profile{Name: entry.Profile}
+		_, tokenErr := base.Get(prof)
+		_, metaErr := base.GetMetadata(prof)
+		if errors.Is(tokenErr, tokenstore.ErrTokenNotFound) && errors.Is(metaErr, tokenstore.ErrMetadataNotFound) {
+			_ = store.Index().Remove(store.StoreType(), entry.Key) //nolint:errcheck // index is best-effort
+			continue
+		}
+
+		orphans = append(orphans, TokenGCOutputItem{
+			Key:       entry.Key,
+			Profile:   entry.Profile,
+			UpdatedAt: entry.UpdatedAt,
+		})
+	}
+
+	if lister, ok := base.(tokenstore.KeyLister); ok {
+		keys, err := lister.ListKeys()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if !live[key] && !seen[key] {
+				orphans = append(orphans, TokenGCOutputItem{Key: key})
+			}
+		}
+	}
+
+	return orphans, nil
+}
+
+// removeOrphanedEntries deletes the given entries from store and records the
+// outcome in each item.
+func removeOrphanedEntries(store *tokenstore.IndexedStore, items []TokenGCOutputItem) (removed, failed int) {
+	for i := range items {
+		var err error
+		if items[i].Profile != "" {
+			err = store.Delete(&types.Profile{Name: items[i].Profile})
+		} else if lister, ok := store.Unwrap().(tokenstore.KeyLister); ok {
+			err = lister.DeleteKey(items[i].Key)
+		} else {
+			err = fmt.Errorf("store cannot delete entries by key")
+		}
+
+		if err != nil {
+			items[i].Error = err.Error()
+			failed++
+			continue
+		}
+		items[i].Removed = true
+		removed++
+	}
+	return removed, failed
+}
+
+// newTokenSetSecretCmd creates the token set-secret command.
+func (cli *CLI) newTokenSetSecretCmd() *cobra.Command {
+	var remove bool
+
+	cmd := &cobra.Command{
+		Use:   "set-secret <profile>",
+		Short: "Store the AppRole secret ID used to log in automatically",
+		Long: `Store the AppRole secret ID the daemon uses to log a profile in again when
+its token can no longer be renewed. The profile's auto_login section must set
+secret_id_from_store: true for it to be used.
+
+The secret ID is read from standard input so it does not end up in the shell
+history or the process list. Use --delete to remove it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.runTokenSetSecret(os.Stdin, args[0], remove)
+		},
+	}
+
+	cmd.Flags().BoolVar(&remove, "delete", false, "Remove the stored secret ID")
+
+	return cmd
+}
+
+// runTokenSetSecret stores or removes the auto_login secret ID of a profile.
+func (cli *CLI) runTokenSetSecret(in io.Reader, name string, remove bool) error {
+	conn, err := cli.Config.GetConnection(name)
+	if err != nil {
+		return err
+	}
+	if err := cli.Store.IsAvailable(); err != nil {
+		return fmt.Errorf("token store: %w", err)
+	}
+	prof := &types.Profile{Name: conn.AutoLoginSecretName()}
+
+	if remove {
+		if err := cli.Store.Delete(prof); err != nil && !errors.Is(err, tokenstore.ErrTokenNotFound) {
+			return fmt.Errorf("failed to delete secret ID: %w", err)
+		}
+		fmt.Printf("Secret ID of profile %s removed.\n", name)
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Secret ID for profile %s: ", name)
+	secret, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read secret ID: %w", err)
+	}
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return errors.New("no secret ID given")
+	}
+
+	if err := cli.Store.Set(prof, secret); err != nil {
+		return fmt.Errorf("failed to store secret ID: %w", err)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Printf("Secret ID of profile %s stored.\n", name)
+	if !conn.AutoLogin.SecretIDFromStore {
+		fmt.Printf("Set 'auto_login.secret_id_from_store: true' on the profile for the daemon to use it.\n")
+	}
+	return nil
+}
+
+// shortStoreKey abbreviates a store key for display.
+func shortStoreKey(key string) string {
+	const keyDisplayLen = len(tokenstore.ServicePrefix) + 1 + 12
+	if len(key) <= keyDisplayLen {
+		return key
+	}
+	return key[:keyDisplayLen]
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from in.
+// Anything other than "y" or "yes" is a no.
+func confirm(in io.Reader, question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
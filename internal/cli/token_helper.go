@@ -113,6 +113,11 @@ func (cli *CLI) handleTokenHelperGet() error {
 		return nil
 	}
 
+	if err := cli.initTokenHelperStore(); err != nil {
+		fmt.Fprintf(os.Stderr, "patrol: %v\n", err)
+		return nil
+	}
+
 	// Check keyring availability
 	keyringErr := cli.Store.IsAvailable()
 	if keyringErr != nil {
@@ -138,6 +143,8 @@ func (cli *CLI) handleTokenHelperGet() error {
 		os.Exit(1)
 	}
 
+	cli.recordTokenHelperUse(conn)
+
 	// Output the token (no newline, per spec)
 	fmt.Print(tokenStr)
 	return nil
@@ -157,6 +164,11 @@ func (cli *CLI) handleTokenHelperStore() error {
 		os.Exit(1)
 	}
 
+	if err := cli.initTokenHelperStore(); err != nil {
+		fmt.Fprintf(os.Stderr, "patrol: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Check keyring availability
 	keyringErr := cli.Store.IsAvailable()
 	if keyringErr != nil {
@@ -192,6 +204,8 @@ func (cli *CLI) handleTokenHelperStore() error {
 		fmt.Fprintf(os.Stderr, "patrol: failed to store token: %v\n", err)
 		os.Exit(1)
 	}
+	cli.recordTokenHelperUse(conn)
+	cli.runHooks(ctx, config.HookLogin, prof, tokenStr)
 
 	return nil
 }
@@ -209,20 +223,41 @@ func (cli *CLI) handleTokenHelperErase() error {
 		return nil
 	}
 
+	if err := cli.initTokenHelperStore(); err != nil {
+		fmt.Fprintf(os.Stderr, "patrol: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Delete the token (ignore "not found" errors)
 	prof := types.FromConnection(conn)
 	ctx := context.Background()
 	tm := token.NewTokenManager(ctx, cli.Store, vault.NewTokenExecutor())
+	hadToken := tm.HasToken(prof)
 	if err := tm.Delete(prof); err != nil {
 		if !errors.Is(err, tokenstore.ErrTokenNotFound) {
 			fmt.Fprintf(os.Stderr, "patrol: failed to erase token: %v\n", err)
 			os.Exit(1)
 		}
 	}
+	if hadToken {
+		cli.runHooks(ctx, config.HookLogout, prof, "")
+	}
 
 	return nil
 }
 
+// initTokenHelperStore selects the token store from the configuration.
+// Token helper invocations skip normal initialization, so a missing or
+// unreadable config file keeps the default store.
+func (cli *CLI) initTokenHelperStore() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil
+	}
+	cli.Config = cfg
+	return cli.initStore()
+}
+
 // getTokenHelperConnection creates a connection from environment variables.
 // Used when Patrol is invoked as a token helper.
 func (cli *CLI) getTokenHelperConnection() (*config.Connection, error) {
@@ -233,15 +268,8 @@ func (cli *CLI) getTokenHelperConnection() (*config.Connection, error) {
 
 	namespace := os.Getenv("VAULT_NAMESPACE")
 
-	// Create a unique profile name from the address for token helper mode
-	// This ensures different servers have different keyring entries
-	profileName := utils.SanitizeAddressForProfile(addr)
-	if namespace != "" {
-		profileName += "-" + utils.SanitizeNamespaceForProfile(namespace)
-	}
-
 	conn := &config.Connection{
-		Name:      profileName,
+		Name:      tokenHelperProfileName(addr, namespace),
 		Address:   addr,
 		Namespace: namespace,
 	}
@@ -253,3 +281,36 @@ func (cli *CLI) getTokenHelperConnection() (*config.Connection, error) {
 
 	return conn, nil
 }
+
+// recordTokenHelperUse records a use of the token of every configured
+// profile of the server conn points at. The connection of the token helper
+// is built from the environment and has no idle_timeout of its own, while
+// the daemon enforces the idle_timeout of the configured profiles.
+func (cli *CLI) recordTokenHelperUse(conn *config.Connection) {
+	if cli.Config == nil {
+		return
+	}
+	for i := range cli.Config.Connections {
+		c := &cli.Config.Connections[i]
+		if sameServer(c.Address, conn.Address) && c.Namespace == conn.Namespace {
+			recordTokenUse(c, types.FromConnection(c))
+		}
+	}
+}
+
+// sameServer reports whether two server addresses are the same, ignoring a
+// trailing slash and the case of the scheme and host.
+func sameServer(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "/"), strings.TrimSuffix(b, "/"))
+}
+
+// tokenHelperProfileName returns the profile name token helper mode stores
+// tokens under. It is derived from the address so different servers have
+// different keyring entries.
+func tokenHelperProfileName(addr, namespace string) string {
+	name := utils.SanitizeAddressForProfile(addr)
+	if namespace != "" {
+		name += "-" + utils.SanitizeNamespaceForProfile(namespace)
+	}
+	return name
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/tokenstore"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestHandleTokenHelperGet_RecordsUse(t *testing.T) {
+	const addr = "https://vault.example.com:8200"
+
+	dir := t.TempDir()
+	keyringDir := filepath.Join(dir, "keyring")
+	configDir := filepath.Join(dir, "config")
+	dataDir := filepath.Join(dir, "data")
+	t.Setenv(tokenstore.TestStoreEnvVar, keyringDir)
+	t.Setenv("PATROL_CONFIG_DIR", configDir)
+	t.Setenv("XDG_DATA_HOME", dataDir)
+	t.Setenv("LOCALAPPDATA", dataDir)
+	t.Setenv("HOME", dir)
+	t.Setenv("VAULT_ADDR", addr)
+	t.Setenv("VAULT_NAMESPACE", "")
+
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	cfg := "connections:\n" +
+		"  - name: prod\n    address: " + addr + "/\n    idle_timeout: 1h\n" +
+		"  - name: dev\n    address: https://dev.example.com:8200\n    idle_timeout: 1h\n"
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := tokenstore.NewFileStore(keyringDir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	if err := store.Set(&types.Profile{Name: tokenHelperProfileName(addr, "")}, "hvs.test"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if err := (&CLI{}).handleTokenHelperGet(); err != nil {
+		t.Fatalf("handleTokenHelperGet() error = %v", err)
+	}
+
+	usage := token.NewUsageLog(token.DefaultUsageDir())
+	if last, err := usage.LastUsed(&types.Profile{Name: "prod"}); err != nil || last.IsZero() {
+		t.Errorf("LastUsed(prod) = %v, %v; want a recorded use", last, err)
+	}
+	if last, _ := usage.LastUsed(&types.Profile{Name: "dev"}); !last.IsZero() {
+		t.Errorf("LastUsed(dev) = %v, want no use recorded for another server", last)
+	}
+}
+
+func TestHandleTokenHelperErase_RunsLogoutHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use sh")
+	}
+	const addr = "https://vault.example.com:8200"
+
+	dir := t.TempDir()
+	keyringDir := filepath.Join(dir, "keyring")
+	configDir := filepath.Join(dir, "config")
+	out := filepath.Join(dir, "hook.out")
+	t.Setenv(tokenstore.TestStoreEnvVar, keyringDir)
+	t.Setenv("PATROL_CONFIG_DIR", configDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("HOME", dir)
+	t.Setenv("VAULT_ADDR", addr)
+	t.Setenv("VAULT_NAMESPACE", "")
+
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	cfg := "hooks:\n" +
+		"  - events: [logout]\n    command: sh\n    args: [\"-c\", \"echo $PATROL_EVENT >> " + out + "\"]\n"
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := tokenstore.NewFileStore(keyringDir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	if err := store.Set(&types.Profile{Name: tokenHelperProfileName(addr, "")}, "hvs.test"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// The second erase finds no token and runs no hook.
+	for range 2 {
+		if err := (&CLI{}).handleTokenHelperErase(); err != nil {
+			t.Fatalf("handleTokenHelperErase() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("logout hook did not run: %v", err)
+	}
+	if got := string(data); got != "logout\n" {
+		t.Errorf("hook output = %q, want a single logout event", got)
+	}
+}
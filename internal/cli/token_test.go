@@ -1,3 +1,237 @@
 package cli
 
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/tokenstore"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
 // Tests for utils functions have been moved to internal/utils/format_test.go
+
+func TestParseStoreType(t *testing.T) {
+	tests := map[string]string{
+		"file":           config.TokenStoreEncryptedFile,
+		"encrypted_file": config.TokenStoreEncryptedFile,
+		"keyring":        config.TokenStoreKeyring,
+		"helper":         config.TokenStoreHelper,
+	}
+	for in, want := range tests {
+		if got := parseStoreType(in); got != want {
+			t.Errorf("parseStoreType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMigrateProfileToken(t *testing.T) {
+	newStores := func(t *testing.T) (*tokenstore.FileStore, *tokenstore.FileStore) {
+		t.Helper()
+		src, err := tokenstore.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewFileStore() failed: %v", err)
+		}
+		dst, err := tokenstore.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewFileStore() failed: %v", err)
+		}
+		return src, dst
+	}
+	prof := &types.Profile{Name: "dev"}
+
+	t.Run("no token", func(t *testing.T) {
+		src, dst := newStores(t)
+		item := migrateProfileToken(src, dst, prof, false, false, false)
+		if item.Status != migrateStatusNoToken {
+			t.Errorf("Status = %q, want %q", item.Status, migrateStatusNoToken)
+		}
+	})
+
+	t.Run("migrate and delete source", func(t *testing.T) {
+		src, dst := newStores(t)
+		if err := src.Set(prof, "hvs.source"); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.SetMetadata(prof, &types.TokenMetadata{Accessor: "acc"}); err != nil {
+			t.Fatal(err)
+		}
+
+		item := migrateProfileToken(src, dst, prof, false, true, false)
+		if item.Status != migrateStatusMigrated || !item.Metadata || !item.SourceDeleted {
+			t.Fatalf("unexpected result: %+v", item)
+		}
+		if got, _ := dst.Get(prof); got != "hvs.source" {
+			t.Errorf("destination token = %q, want %q", got, "hvs.source")
+		}
+		if _, err := src.Get(prof); err == nil {
+			t.Error("expected source token to be deleted")
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		src, dst := newStores(t)
+		if err := src.Set(prof, "hvs.source"); err != nil {
+			t.Fatal(err)
+		}
+
+		item := migrateProfileToken(src, dst, prof, true, true, false)
+		if item.Status != migrateStatusWouldMigrate {
+			t.Errorf("Status = %q, want %q", item.Status, migrateStatusWouldMigrate)
+		}
+		if _, err := dst.Get(prof); err == nil {
+			t.Error("dry run should not write to the destination")
+		}
+		if _, err := src.Get(prof); err != nil {
+			t.Error("dry run should not delete the source token")
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		src, dst := newStores(t)
+		if err := src.Set(prof, "hvs.source"); err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.Set(prof, "hvs.other"); err != nil {
+			t.Fatal(err)
+		}
+
+		item := migrateProfileToken(src, dst, prof, false, false, false)
+		if item.Status != migrateStatusConflict {
+			t.Errorf("Status = %q, want %q", item.Status, migrateStatusConflict)
+		}
+		if got, _ := dst.Get(prof); got != "hvs.other" {
+			t.Errorf("destination token overwritten without --force: %q", got)
+		}
+
+		item = migrateProfileToken(src, dst, prof, false, false, true)
+		if item.Status != migrateStatusMigrated {
+			t.Errorf("Status with force = %q, want %q", item.Status, migrateStatusMigrated)
+		}
+		if got, _ := dst.Get(prof); got != "hvs.source" {
+			t.Errorf("destination token = %q, want %q", got, "hvs.source")
+		}
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		src, dst := newStores(t)
+		for _, s := range []tokenstore.TokenStore{src, dst} {
+			if err := s.Set(prof, "hvs.same"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		item := migrateProfileToken(src, dst, prof, false, false, false)
+		if item.Status != migrateStatusUpToDate {
+			t.Errorf("Status = %q, want %q", item.Status, migrateStatusUpToDate)
+		}
+	})
+}
+
+func TestFindOrphanedEntries(t *testing.T) {
+	base, err := tokenstore.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+	store := tokenstore.NewIndexedStore(base, "file",
+		tokenstore.NewKeyIndex(filepath.Join(t.TempDir(), tokenstore.IndexFileName)))
+
+	conns := []config.Connection{
+		{
+			Name:      "dev",
+			Address:   "https://vault.example.com:8200",
+			Namespace: "team",
+			AutoLogin: config.AutoLoginConfig{Method: config.AutoLoginAppRole, RoleID: "r", SecretIDFromStore: true},
+		},
+	}
+
+	for _, name := range []string{
+		"dev",
+		"dev/auto-login-secret-id",    // auto_login secret ID
+		"vault-example-com-8200",      // token helper entry without namespace
+		"vault-example-com-8200-team", // token helper entry with namespace
+		"renamed",                     // orphaned profile
+		"old-example-com",             // orphaned token helper entry
+	} {
+		if err := store.Set(&types.Profile{Name: name}, "hvs."+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Written directly to the store, so it is not in the index.
+	unindexed := &types.Profile{Name: "before-index"}
+	if err := base.Set(unindexed, "hvs.unindexed"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Indexed, but the token is already gone.
+	stale := &types.Profile{Name: "stale"}
+	if err := store.Set(stale, "hvs.stale"); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.Delete(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	orphans, err := findOrphanedEntries(store, conns)
+	if err != nil {
+		t.Fatalf("findOrphanedEntries() failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, o := range orphans {
+		got[o.Key] = o.Profile
+	}
+	want := map[string]string{
+		tokenstore.KeyFromProfile(&types.Profile{Name: "renamed"}):         "renamed",
+		tokenstore.KeyFromProfile(&types.Profile{Name: "old-example-com"}): "old-example-com",
+		tokenstore.KeyFromProfile(unindexed):                               "",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findOrphanedEntries() = %+v, want %v", orphans, want)
+	}
+	for key, name := range want {
+		if gotName, ok := got[key]; !ok || gotName != name {
+			t.Errorf("orphan %s: got profile %q (found=%v), want %q", key, gotName, ok, name)
+		}
+	}
+
+	entries, _ := store.Index().Entries("file")
+	for _, e := range entries {
+		if e.Profile == "stale" {
+			t.Error("stale index entry was not dropped")
+		}
+	}
+
+	removed, failed := removeOrphanedEntries(store, orphans)
+	if removed != 3 || failed != 0 {
+		t.Fatalf("removeOrphanedEntries() = %d removed, %d failed, want 3, 0", removed, failed)
+	}
+	orphans, err = findOrphanedEntries(store, conns)
+	if err != nil {
+		t.Fatalf("findOrphanedEntries() failed: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("orphans left after removal: %+v", orphans)
+	}
+	if _, err := store.Get(&types.Profile{Name: "dev"}); err != nil {
+		t.Errorf("live token was removed: %v", err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+		"yes":   true,
+	}
+	for input, want := range tests {
+		if got := confirm(strings.NewReader(input), "Continue?"); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
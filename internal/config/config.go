@@ -3,14 +3,19 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/xabinapal/patrol/internal/utils"
 )
 
 // Security-related errors.
@@ -53,13 +58,104 @@ type Connection struct {
 	ClientCert string `yaml:"client_cert,omitempty"`
 	// ClientKey is the path to a client key file.
 	ClientKey string `yaml:"client_key,omitempty"`
+	// AutoLogin lets the daemon log in again without user interaction when
+	// the token can no longer be renewed.
+	AutoLogin AutoLoginConfig `yaml:"auto_login,omitempty"`
+	// IdleTimeout makes the daemon revoke and delete the token once it has
+	// not been used for this long (0 disables it).
+	IdleTimeout time.Duration `yaml:"idle_timeout,omitempty"`
+}
+
+// Auto login methods. Only methods that need no user interaction are supported.
+const (
+	// AutoLoginAppRole logs in with a role ID and an optional secret ID.
+	AutoLoginAppRole = "approle"
+	// AutoLoginCert logs in with the connection's TLS client certificate.
+	AutoLoginCert = "cert"
+	// AutoLoginKubernetes logs in with a Kubernetes service account token.
+	AutoLoginKubernetes = "kubernetes"
+	// AutoLoginJWT logs in with a JWT read from a file.
+	AutoLoginJWT = "jwt"
+
+	// DefaultKubernetesJWTFile is the service account token mounted into pods.
+	DefaultKubernetesJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// AutoLoginConfig holds settings for unattended re-authentication.
+type AutoLoginConfig struct {
+	// Method is the auth method (approle, cert, kubernetes, jwt).
+	Method string `yaml:"method,omitempty"`
+	// Path is the auth mount path (defaults to the method name).
+	Path string `yaml:"path,omitempty"`
+	// Role is the role to log in with (kubernetes, jwt, and the cert role name).
+	Role string `yaml:"role,omitempty"`
+	// RoleID is the AppRole role ID.
+	RoleID string `yaml:"role_id,omitempty"`
+	// SecretIDFile is the path to a file holding the AppRole secret ID.
+	SecretIDFile string `yaml:"secret_id_file,omitempty"`
+	// SecretIDFromStore reads the AppRole secret ID from the token store,
+	// where it is saved with "patrol token set-secret".
+	SecretIDFromStore bool `yaml:"secret_id_from_store,omitempty"`
+	// JWTFile is the path to the JWT (defaults to the service account token
+	// for kubernetes).
+	JWTFile string `yaml:"jwt_file,omitempty"`
+	// Before is how long before expiry the token is replaced (defaults to
+	// the daemon's min_renew_ttl).
+	Before time.Duration `yaml:"before,omitempty"`
+}
+
+// Enabled reports whether auto login is configured.
+func (a *AutoLoginConfig) Enabled() bool {
+	return a.Method != ""
+}
+
+// GetJWTFile returns the path of the JWT used by the kubernetes and jwt methods.
+func (a *AutoLoginConfig) GetJWTFile() string {
+	if a.JWTFile == "" && a.Method == AutoLoginKubernetes {
+		return DefaultKubernetesJWTFile
+	}
+	return a.JWTFile
+}
+
+// Validate checks that the settings needed by the configured method are present.
+func (a *AutoLoginConfig) Validate() error {
+	if !a.Enabled() {
+		return nil
+	}
+
+	switch a.Method {
+	case AutoLoginAppRole:
+		if a.RoleID == "" {
+			return errors.New("auto_login: approle requires role_id")
+		}
+		if a.SecretIDFile != "" && a.SecretIDFromStore {
+			return errors.New("auto_login: secret_id_file and secret_id_from_store are mutually exclusive")
+		}
+	case AutoLoginCert:
+	case AutoLoginKubernetes:
+		if a.Role == "" {
+			return errors.New("auto_login: kubernetes requires role")
+		}
+	case AutoLoginJWT:
+		if a.JWTFile == "" {
+			return errors.New("auto_login: jwt requires jwt_file")
+		}
+	default:
+		return fmt.Errorf("auto_login: unsupported method %q (use approle, cert, kubernetes or jwt)", a.Method)
+	}
+
+	if a.Before < 0 {
+		return errors.New("auto_login: before must not be negative")
+	}
+	return nil
 }
 
 // DaemonConfig holds settings for the background renewal daemon.
 type DaemonConfig struct {
 	// AutoStart indicates whether to auto-start the daemon on login.
 	AutoStart bool `yaml:"auto_start,omitempty"`
-	// CheckInterval is how often to check tokens for renewal.
+	// CheckInterval is how often the daemon rescans the config and token store
+	// for new profiles and changed tokens. Renewals are scheduled per token.
 	CheckInterval time.Duration `yaml:"check_interval,omitempty"`
 	// RenewThreshold is the fraction of TTL elapsed before renewal (0.0-1.0).
 	RenewThreshold float64 `yaml:"renew_threshold,omitempty"`
@@ -69,30 +165,503 @@ type DaemonConfig struct {
 	InitialRetryBackoff time.Duration `yaml:"initial_retry_backoff,omitempty"`
 	// MaxRetryBackoff is the maximum backoff duration for renewal retries.
 	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff,omitempty"`
+	// MaxConcurrency is the maximum number of profiles renewed in parallel.
+	MaxConcurrency int `yaml:"max_concurrency,omitempty"`
+	// RenewTimeout bounds the lookup and renewal of a single profile.
+	RenewTimeout time.Duration `yaml:"renew_timeout,omitempty"`
 	// LogFile is the path to the daemon log file.
 	LogFile string `yaml:"log_file,omitempty"`
 	// PIDFile is the path to the daemon PID file.
 	PIDFile string `yaml:"pid_file,omitempty"`
+	// ControlSocket is the path to the Unix socket used to control a running daemon.
+	ControlSocket string `yaml:"control_socket,omitempty"`
 	// LogLevel is the logging level (debug, info, warn, error).
 	LogLevel string `yaml:"log_level,omitempty"`
 	// LogJSON enables JSON-formatted logging.
 	LogJSON bool `yaml:"log_json,omitempty"`
 	// LogMaxSize is the maximum log file size in MB before rotation.
 	LogMaxSize int `yaml:"log_max_size,omitempty"`
-	// HealthEndpoint is the address for the health HTTP endpoint (e.g., localhost:9090).
+	// LogMaxFiles is the number of rotated log files kept (-1 keeps all).
+	LogMaxFiles int `yaml:"log_max_files,omitempty"`
+	// LogCompress gzips rotated log files.
+	LogCompress bool `yaml:"log_compress,omitempty"`
+	// LogRotateInterval rotates the log file when an interval aligned to
+	// UTC ends, e.g. 24h at midnight UTC.
+	LogRotateInterval time.Duration `yaml:"log_rotate_interval,omitempty"`
+	// LogSink sends the log to syslog or the systemd journal instead of
+	// log_file (file, syslog or journald).
+	LogSink string `yaml:"log_sink,omitempty"`
+	// Syslog holds the syslog settings used by the syslog log sink and
+	// notification backend.
+	Syslog SyslogConfig `yaml:"syslog,omitempty"`
+	// HealthEndpoint is the address for the health HTTP endpoint (e.g.,
+	// localhost:9090), or unix:<path> to serve it on a Unix socket.
 	HealthEndpoint string `yaml:"health_endpoint,omitempty"`
+	// Health secures the health endpoint with TLS and authentication.
+	Health HealthConfig `yaml:"health,omitempty"`
 	// Notifications holds notification settings.
 	Notifications NotificationConfig `yaml:"notifications,omitempty"`
 }
 
-// NotificationConfig holds settings for desktop notifications.
+// GetLogMaxFiles returns the number of rotated log files kept, or zero to
+// keep all of them.
+func (d *DaemonConfig) GetLogMaxFiles() int {
+	switch {
+	case d.LogMaxFiles < 0:
+		return 0
+	case d.LogMaxFiles == 0:
+		return DefaultLogMaxFiles
+	}
+	return d.LogMaxFiles
+}
+
+// NotificationConfig holds notification settings: the backends events are
+// sent to, and the rules choosing which events go where.
 type NotificationConfig struct {
 	// Enabled enables desktop notifications.
 	Enabled bool `yaml:"enabled,omitempty"`
-	// OnRenewal sends notification on successful token renewal.
+	// OnRenewal sends notification on successful token renewal. The on_*
+	// settings are only used when no rules are configured.
 	OnRenewal bool `yaml:"on_renewal,omitempty"`
-	// OnFailure sends notification on renewal failure.
+	// OnFailure sends notification on renewal failure, on tokens that need a
+	// new login and on sealed servers.
 	OnFailure bool `yaml:"on_failure,omitempty"`
+	// OnExpiring sends notification when a token approaches the point after
+	// which it can no longer be renewed.
+	OnExpiring bool `yaml:"on_expiring,omitempty"`
+	// ExpiryWarnings are how long before that point to warn, each once per token.
+	ExpiryWarnings []time.Duration `yaml:"expiry_warnings,omitempty"`
+	// OnIdle sends notification before and after an idle token is revoked.
+	OnIdle bool `yaml:"on_idle,omitempty"`
+	// Rules route events to backends. The first rule matching an event
+	// applies, and events matching no rule are not sent.
+	Rules []NotificationRule `yaml:"rules,omitempty"`
+	// DedupWindow is how long a repeated failure of a profile is not sent
+	// again (-1 disables deduplication).
+	DedupWindow time.Duration `yaml:"dedup_window,omitempty"`
+	// RateLimit is how many notifications are sent per profile and hour
+	// (-1 disables the limit). Critical events are not limited.
+	RateLimit int `yaml:"rate_limit,omitempty"`
+	// Syslog sends notification events to syslog, using the daemon's syslog
+	// settings.
+	Syslog bool `yaml:"syslog,omitempty"`
+	// Journald sends notification events to the systemd journal.
+	Journald bool `yaml:"journald,omitempty"`
+	// QuietHours holds back non-critical notifications during a daily window.
+	QuietHours QuietHoursConfig `yaml:"quiet_hours,omitempty"`
+	// Webhook posts notification events to an HTTP endpoint, independently
+	// of desktop notifications.
+	Webhook WebhookConfig `yaml:"webhook,omitempty"`
+}
+
+// GetDedupWindow returns how long repeated failures are deduplicated, or
+// zero if they are not.
+func (n *NotificationConfig) GetDedupWindow() time.Duration {
+	switch {
+	case n.DedupWindow < 0:
+		return 0
+	case n.DedupWindow == 0:
+		return DefaultNotificationDedupWindow
+	}
+	return n.DedupWindow
+}
+
+// GetRateLimit returns the notifications allowed per profile and hour, or
+// zero if they are not limited.
+func (n *NotificationConfig) GetRateLimit() int {
+	switch {
+	case n.RateLimit < 0:
+		return 0
+	case n.RateLimit == 0:
+		return DefaultNotificationRateLimit
+	}
+	return n.RateLimit
+}
+
+// Notification backends.
+const (
+	// NotifyBackendDesktop shows desktop notifications.
+	NotifyBackendDesktop = "desktop"
+	// NotifyBackendWebhook posts events to the configured webhook.
+	NotifyBackendWebhook = "webhook"
+	// NotifyBackendSyslog sends events to syslog.
+	NotifyBackendSyslog = "syslog"
+	// NotifyBackendJournald sends events to the systemd journal.
+	NotifyBackendJournald = "journald"
+)
+
+// Notification severities, from least to most urgent.
+const (
+	// SeverityInfo is for routine events, shown as plain notifications.
+	SeverityInfo = "info"
+	// SeverityWarning is for events needing attention, shown as alerts.
+	SeverityWarning = "warning"
+	// SeverityCritical is for events that block access. Critical events are
+	// sent during quiet hours and are not rate limited.
+	SeverityCritical = "critical"
+)
+
+// NotificationRule sends matching events to backends at a severity.
+type NotificationRule struct {
+	// Events are the event types the rule applies to, or all if empty.
+	Events []string `yaml:"events,omitempty"`
+	// Profiles are path.Match patterns of the profiles the rule applies to,
+	// or all if empty.
+	Profiles []string `yaml:"profiles,omitempty"`
+	// Backends are the backends matching events are sent to, or all
+	// configured backends if empty.
+	Backends []string `yaml:"backends,omitempty"`
+	// Severity overrides the default severity of matching events.
+	Severity string `yaml:"severity,omitempty"`
+	// Mute drops matching events.
+	Mute bool `yaml:"mute,omitempty"`
+}
+
+// Validate checks the severity and profile patterns of the rule. Event
+// types and backends are checked by the notify package, which defines them.
+func (r *NotificationRule) Validate() error {
+	switch r.Severity {
+	case "", SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("unknown severity %q (use info, warning or critical)", r.Severity)
+	}
+	for _, pattern := range r.Profiles {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid profile pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to event on profile name.
+func (r *NotificationRule) Matches(event, name string) bool {
+	if len(r.Events) > 0 && !slices.Contains(r.Events, event) {
+		return false
+	}
+	if len(r.Profiles) == 0 {
+		return true
+	}
+	for _, pattern := range r.Profiles {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// QuietHoursConfig is a daily window of local time, such as 22:00 to 07:00,
+// during which only critical notifications are sent.
+type QuietHoursConfig struct {
+	// Start is when quiet hours begin, as HH:MM.
+	Start string `yaml:"start,omitempty"`
+	// End is when quiet hours end, as HH:MM. It may be before Start for a
+	// window spanning midnight.
+	End string `yaml:"end,omitempty"`
+}
+
+// Enabled reports whether quiet hours are configured.
+func (q *QuietHoursConfig) Enabled() bool {
+	return q.Start != "" || q.End != ""
+}
+
+// Validate checks that both ends of the window are valid times of day.
+func (q *QuietHoursConfig) Validate() error {
+	if !q.Enabled() {
+		return nil
+	}
+	if _, err := parseTimeOfDay(q.Start); err != nil {
+		return fmt.Errorf("quiet_hours: invalid start %q (use HH:MM)", q.Start)
+	}
+	if _, err := parseTimeOfDay(q.End); err != nil {
+		return fmt.Errorf("quiet_hours: invalid end %q (use HH:MM)", q.End)
+	}
+	return nil
+}
+
+// Contains reports whether t falls within quiet hours, in t's location.
+func (q *QuietHoursConfig) Contains(t time.Time) bool {
+	start, err := parseTimeOfDay(q.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseTimeOfDay(q.End)
+	if err != nil {
+		return false
+	}
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// parseTimeOfDay parses an HH:MM time of day into the time since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// WebhookConfig holds settings for the HTTP webhook notification backend.
+type WebhookConfig struct {
+	// URL is the endpoint events are posted to. The webhook is disabled if empty.
+	URL string `yaml:"url,omitempty"`
+	// Headers are extra request headers. Values may reference environment
+	// variables as ${VAR}.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// BodyTemplate is a Go text/template rendering the request body from the
+	// event. The event is posted as JSON if empty.
+	BodyTemplate string `yaml:"body_template,omitempty"`
+	// HMACSecretFile is the path to a file holding the key used to sign the body.
+	HMACSecretFile string `yaml:"hmac_secret_file,omitempty"`
+	// HMACSecretEnv is the environment variable holding the signing key.
+	HMACSecretEnv string `yaml:"hmac_secret_env,omitempty"`
+	// Timeout bounds each delivery attempt.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Retries is how many times a failed delivery is retried (-1 disables
+	// retries).
+	Retries int `yaml:"retries,omitempty"`
+}
+
+// Enabled reports whether the webhook is configured.
+func (w *WebhookConfig) Enabled() bool {
+	return w.URL != ""
+}
+
+// GetTimeout returns the timeout of a delivery attempt.
+func (w *WebhookConfig) GetTimeout() time.Duration {
+	if w.Timeout <= 0 {
+		return DefaultWebhookTimeout
+	}
+	return w.Timeout
+}
+
+// GetRetries returns how many times a failed delivery is retried.
+func (w *WebhookConfig) GetRetries() int {
+	switch {
+	case w.Retries < 0:
+		return 0
+	case w.Retries == 0:
+		return DefaultWebhookRetries
+	}
+	return w.Retries
+}
+
+// Validate checks the webhook settings. The body template is checked by the
+// notify package, which defines the functions available to it.
+func (w *WebhookConfig) Validate() error {
+	if !w.Enabled() {
+		return nil
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook: url must be an http or https URL")
+	}
+	if w.HMACSecretFile != "" && w.HMACSecretEnv != "" {
+		return errors.New("webhook: hmac_secret_file and hmac_secret_env are mutually exclusive")
+	}
+	if w.Retries < -1 {
+		return errors.New("webhook: retries must be -1 (no retries) or more")
+	}
+	if w.Timeout < 0 {
+		return errors.New("webhook: timeout must not be negative")
+	}
+	return nil
+}
+
+// DefaultExpiryWarnings returns the default expiry warning times.
+func DefaultExpiryWarnings() []time.Duration {
+	return []time.Duration{time.Hour, 10 * time.Minute}
+}
+
+// Daemon defaults.
+const (
+	// DefaultMaxConcurrency is the default number of profiles renewed in parallel.
+	DefaultMaxConcurrency = 4
+	// DefaultRenewTimeout is the default time allowed to renew a single profile.
+	DefaultRenewTimeout = time.Minute
+	// DefaultIdleWarning is how long before an idle token is revoked to warn.
+	DefaultIdleWarning = 10 * time.Minute
+	// DefaultWebhookTimeout is the default time allowed for a webhook delivery attempt.
+	DefaultWebhookTimeout = 10 * time.Second
+	// DefaultWebhookRetries is the default number of webhook delivery retries.
+	DefaultWebhookRetries = 3
+	// DefaultNotificationDedupWindow is how long repeated failures are not
+	// notified again by default.
+	DefaultNotificationDedupWindow = time.Hour
+	// DefaultNotificationRateLimit is the default number of notifications
+	// per profile and hour.
+	DefaultNotificationRateLimit = 10
+	// DefaultLogMaxFiles is the default number of rotated log files kept.
+	DefaultLogMaxFiles = 5
+)
+
+// Log sinks.
+const (
+	// LogSinkFile writes the log to log_file, or to stderr if it is not set.
+	LogSinkFile = "file"
+	// LogSinkSyslog sends the log to syslog.
+	LogSinkSyslog = "syslog"
+	// LogSinkJournald sends the log to the systemd journal.
+	LogSinkJournald = "journald"
+)
+
+// Syslog networks.
+const (
+	// SyslogUnixgram sends datagrams to a local syslog socket.
+	SyslogUnixgram = "unixgram"
+	// SyslogUnix sends to a local syslog stream socket.
+	SyslogUnix = "unix"
+	// SyslogUDP sends datagrams to a remote syslog server.
+	SyslogUDP = "udp"
+)
+
+// SyslogConfig holds the settings for sending to syslog.
+type SyslogConfig struct {
+	// Network is unixgram, unix or udp. If empty, the local syslog socket
+	// is found automatically.
+	Network string `yaml:"network,omitempty"`
+	// Address is the socket path, or host:port for udp.
+	Address string `yaml:"address,omitempty"`
+	// Facility is the syslog facility, such as daemon (the default), user
+	// or local0 to local7.
+	Facility string `yaml:"facility,omitempty"`
+	// Tag is the application name messages are sent with.
+	Tag string `yaml:"tag,omitempty"`
+}
+
+// GetTag returns the application name messages are sent with.
+func (s *SyslogConfig) GetTag() string {
+	if s.Tag == "" {
+		return "patrol"
+	}
+	return s.Tag
+}
+
+// GetFacility returns the syslog facility name.
+func (s *SyslogConfig) GetFacility() string {
+	if s.Facility == "" {
+		return "daemon"
+	}
+	return s.Facility
+}
+
+// Validate checks the network and address. The facility is checked by the
+// logsink package, which maps it to its code.
+func (s *SyslogConfig) Validate() error {
+	switch s.Network {
+	case "":
+		if s.Address != "" {
+			return errors.New("syslog: network is required with an address")
+		}
+	case SyslogUnixgram, SyslogUnix:
+		if s.Address == "" {
+			return fmt.Errorf("syslog: %s requires the socket path as address", s.Network)
+		}
+	case SyslogUDP:
+		if _, _, err := net.SplitHostPort(s.Address); err != nil {
+			return fmt.Errorf("syslog: udp requires a host:port address: %w", err)
+		}
+	default:
+		return fmt.Errorf("syslog: unknown network %q (use unixgram, unix or udp)", s.Network)
+	}
+	return nil
+}
+
+// HealthConfig holds the TLS and authentication settings of the health
+// endpoint. Without them it serves plain HTTP to anyone who can connect.
+type HealthConfig struct {
+	// TLSCert and TLSKey are the paths to the server certificate and key.
+	TLSCert string `yaml:"tls_cert,omitempty"`
+	TLSKey  string `yaml:"tls_key,omitempty"`
+	// ClientCA is the path to the CA certificates client certificates must
+	// be signed by. Setting it requires clients to present one (mTLS).
+	ClientCA string `yaml:"client_ca,omitempty"`
+	// TokenFile is the path to a file holding the bearer token clients must
+	// send in the Authorization header.
+	TokenFile string `yaml:"token_file,omitempty"`
+	// TokenEnv is the environment variable holding the bearer token.
+	TokenEnv string `yaml:"token_env,omitempty"`
+}
+
+// TLSEnabled reports whether the endpoint is served over TLS.
+func (h *HealthConfig) TLSEnabled() bool {
+	return h.TLSCert != ""
+}
+
+// Validate checks that the settings are consistent. Files are read when the
+// endpoint starts.
+func (h *HealthConfig) Validate() error {
+	if (h.TLSCert == "") != (h.TLSKey == "") {
+		return errors.New("health: tls_cert and tls_key must be set together")
+	}
+	if h.ClientCA != "" && !h.TLSEnabled() {
+		return errors.New("health: client_ca requires tls_cert and tls_key")
+	}
+	if h.TokenFile != "" && h.TokenEnv != "" {
+		return errors.New("health: set only one of token_file and token_env")
+	}
+	return nil
+}
+
+// Token store backend types.
+const (
+	// TokenStoreKeyring stores tokens in the OS keyring.
+	TokenStoreKeyring = "keyring"
+	// TokenStoreEncryptedFile stores tokens in encrypted files on disk.
+	TokenStoreEncryptedFile = "encrypted_file"
+	// TokenStoreHelper delegates token storage to an external helper executable.
+	TokenStoreHelper = "helper"
+
+	// DefaultTokenStorePassphraseEnv is the environment variable read for the
+	// encrypted file store passphrase when no other variable is configured.
+	DefaultTokenStorePassphraseEnv = "PATROL_TOKEN_STORE_PASSPHRASE"
+)
+
+// TokenStoreConfig holds settings for the token storage backend.
+type TokenStoreConfig struct {
+	// Type is the storage backend (keyring, encrypted_file, helper). Defaults to keyring.
+	Type string `yaml:"type,omitempty"`
+	// Path is the encrypted file store directory (defaults to <data dir>/tokens).
+	Path string `yaml:"path,omitempty"`
+	// KeyFile is the path to a file whose contents are used to derive the encryption key.
+	KeyFile string `yaml:"key_file,omitempty"`
+	// PassphraseEnv is the environment variable holding the passphrase, used when
+	// KeyFile is not set (defaults to PATROL_TOKEN_STORE_PASSPHRASE).
+	PassphraseEnv string `yaml:"passphrase_env,omitempty"`
+	// Helper is the helper executable for the helper store; a bare name such as
+	// "pass" runs "patrol-store-pass" from PATH, and a path must be absolute.
+	Helper string `yaml:"helper,omitempty"`
+	// HelperArgs are extra arguments passed to the helper before the operation.
+	HelperArgs []string `yaml:"helper_args,omitempty"`
+	// HelperTimeout bounds each helper invocation (defaults to 30s).
+	HelperTimeout time.Duration `yaml:"helper_timeout,omitempty"`
+}
+
+// GetType returns the token store type, defaulting to keyring.
+func (t *TokenStoreConfig) GetType() string {
+	if t.Type == "" {
+		return TokenStoreKeyring
+	}
+	return t.Type
+}
+
+// GetPath returns the encrypted file store directory.
+func (t *TokenStoreConfig) GetPath() string {
+	if t.Path != "" {
+		return t.Path
+	}
+	return filepath.Join(GetPaths().DataDir, "tokens")
+}
+
+// GetPassphraseEnv returns the environment variable holding the passphrase.
+func (t *TokenStoreConfig) GetPassphraseEnv() string {
+	if t.PassphraseEnv != "" {
+		return t.PassphraseEnv
+	}
+	return DefaultTokenStorePassphraseEnv
 }
 
 // Config represents the Patrol configuration.
@@ -105,11 +674,110 @@ type Config struct {
 	Daemon DaemonConfig `yaml:"daemon,omitempty"`
 	// RevokeOnLogout indicates whether to revoke tokens on logout.
 	RevokeOnLogout bool `yaml:"revoke_on_logout,omitempty"`
+	// TokenStore holds token storage backend settings.
+	TokenStore TokenStoreConfig `yaml:"token_store,omitempty"`
+	// Hooks are commands run on token lifecycle events, by both the CLI and
+	// the daemon.
+	Hooks []HookConfig `yaml:"hooks,omitempty"`
 
 	// filePath is the path where this config was loaded from.
 	filePath string `yaml:"-"`
 }
 
+// Hook events.
+const (
+	// HookLogin runs after a token was stored by a login.
+	HookLogin = "login"
+	// HookRenewal runs after the daemon or "patrol profile renew" renewed a token.
+	HookRenewal = "renewal"
+	// HookFailure runs after a renewal or automatic login failed.
+	HookFailure = "failure"
+	// HookLogout runs after a token was removed by a logout, a revoke or for
+	// being idle.
+	HookLogout = "logout"
+
+	// DefaultHookTimeout is the default time a hook command may run.
+	DefaultHookTimeout = 30 * time.Second
+)
+
+// HookConfig is a command run on token lifecycle events.
+type HookConfig struct {
+	// Name identifies the hook in logs (defaults to the command).
+	Name string `yaml:"name,omitempty"`
+	// Events are the events that run the command (login, renewal, failure, logout).
+	Events []string `yaml:"events"`
+	// Profiles limits the hook to profiles matching these glob patterns
+	// (all profiles if empty).
+	Profiles []string `yaml:"profiles,omitempty"`
+	// Command is the program to run. It is not run through a shell.
+	Command string `yaml:"command"`
+	// Args are the arguments passed to the command.
+	Args []string `yaml:"args,omitempty"`
+	// Timeout bounds each run (defaults to 30s).
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// PassToken adds the token to the command's environment.
+	PassToken bool `yaml:"pass_token,omitempty"`
+}
+
+// GetName returns the name of the hook used in logs.
+func (h *HookConfig) GetName() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return filepath.Base(h.Command)
+}
+
+// GetTimeout returns how long a run of the hook may take.
+func (h *HookConfig) GetTimeout() time.Duration {
+	if h.Timeout <= 0 {
+		return DefaultHookTimeout
+	}
+	return h.Timeout
+}
+
+// Validate checks that the hook has a command, known events and valid
+// profile patterns.
+func (h *HookConfig) Validate() error {
+	if h.Command == "" {
+		return errors.New("hook: command is required")
+	}
+	if len(h.Events) == 0 {
+		return fmt.Errorf("hook %s: at least one event is required", h.GetName())
+	}
+	for _, event := range h.Events {
+		switch event {
+		case HookLogin, HookRenewal, HookFailure, HookLogout:
+		default:
+			return fmt.Errorf("hook %s: unknown event %q (use login, renewal, failure or logout)", h.GetName(), event)
+		}
+	}
+	for _, pattern := range h.Profiles {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("hook %s: invalid profile pattern %q", h.GetName(), pattern)
+		}
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("hook %s: timeout must not be negative", h.GetName())
+	}
+	return nil
+}
+
+// Matches reports whether the hook runs for event on profile name.
+func (h *HookConfig) Matches(event, name string) bool {
+	if !slices.Contains(h.Events, event) {
+		return false
+	}
+	if len(h.Profiles) == 0 {
+		return true
+	}
+	for _, pattern := range h.Profiles {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Default returns a new Config with default values.
 func Default() *Config {
 	paths := GetPaths()
@@ -122,12 +790,17 @@ func Default() *Config {
 			MinRenewTTL:         5 * time.Minute,
 			InitialRetryBackoff: 30 * time.Second,
 			MaxRetryBackoff:     15 * time.Minute,
+			MaxConcurrency:      DefaultMaxConcurrency,
+			RenewTimeout:        DefaultRenewTimeout,
 			LogFile:             "",
 			PIDFile:             "",
 			Notifications: NotificationConfig{
-				Enabled:   false,
-				OnRenewal: true,
-				OnFailure: true,
+				Enabled:        false,
+				OnRenewal:      true,
+				OnFailure:      true,
+				OnExpiring:     true,
+				ExpiryWarnings: DefaultExpiryWarnings(),
+				OnIdle:         true,
 			},
 		},
 		RevokeOnLogout: true,
@@ -170,6 +843,12 @@ func LoadFrom(path string) (*Config, error) {
 	if cfg.Daemon.MinRenewTTL == 0 {
 		cfg.Daemon.MinRenewTTL = 5 * time.Minute
 	}
+	if cfg.Daemon.MaxConcurrency <= 0 {
+		cfg.Daemon.MaxConcurrency = DefaultMaxConcurrency
+	}
+	if cfg.Daemon.RenewTimeout <= 0 {
+		cfg.Daemon.RenewTimeout = DefaultRenewTimeout
+	}
 
 	return cfg, nil
 }
@@ -191,7 +870,7 @@ func (c *Config) Save() error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(c.filePath, data, 0600); err != nil {
+	if err := utils.WriteFileAtomic(c.filePath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -267,6 +946,30 @@ func (c *Config) RemoveConnection(name string) error {
 	return fmt.Errorf("connection %q not found", name)
 }
 
+// RenameConnection renames a connection, keeping it active if it was current.
+func (c *Config) RenameConnection(oldName, newName string) error {
+	if newName == "" {
+		return errors.New("connection name is required")
+	}
+
+	conn, err := c.GetConnection(oldName)
+	if err != nil {
+		return err
+	}
+	if oldName == newName {
+		return nil
+	}
+	if _, err := c.GetConnection(newName); err == nil {
+		return fmt.Errorf("connection %q already exists", newName)
+	}
+
+	conn.Name = newName
+	if c.Current == oldName {
+		c.Current = newName
+	}
+	return nil
+}
+
 // SetCurrent sets the active connection.
 func (c *Config) SetCurrent(name string) error {
 	// Verify the connection exists
@@ -296,6 +999,18 @@ func (conn *Connection) KeyringKey() string {
 	return conn.Name
 }
 
+// AutoLoginSecretName returns the token store entry holding the AppRole
+// secret ID of the connection when secret_id_from_store is set.
+func (conn *Connection) AutoLoginSecretName() string {
+	return conn.Name + "/auto-login-secret-id"
+}
+
+// IdleWarning returns how long before its idle token is revoked to warn:
+// DefaultIdleWarning, or half the idle timeout if that is shorter.
+func (conn *Connection) IdleWarning() time.Duration {
+	return min(DefaultIdleWarning, conn.IdleTimeout/2)
+}
+
 // ValidateBinaryPath validates that the binary path is safe to execute.
 // This prevents command injection attacks via malicious config files.
 // Returns nil if the binary path is safe, or an error describing the issue.
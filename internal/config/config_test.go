@@ -186,6 +186,42 @@ func TestRemoveConnection(t *testing.T) {
 	}
 }
 
+func TestRenameConnection(t *testing.T) {
+	cfg := Default()
+	cfg.Connections = []Connection{
+		{Name: "first", Address: "https://first.example.com"},
+		{Name: "second", Address: "https://second.example.com"},
+	}
+	cfg.Current = "first"
+
+	if err := cfg.RenameConnection("first", "primary"); err != nil {
+		t.Fatalf("RenameConnection() failed: %v", err)
+	}
+	if cfg.Connections[0].Name != "primary" {
+		t.Errorf("expected connection name 'primary', got '%s'", cfg.Connections[0].Name)
+	}
+	if cfg.Current != "primary" {
+		t.Errorf("expected Current 'primary', got '%s'", cfg.Current)
+	}
+
+	if err := cfg.RenameConnection("second", "secondary"); err != nil {
+		t.Fatalf("RenameConnection() failed: %v", err)
+	}
+	if cfg.Current != "primary" {
+		t.Errorf("renaming a non-current connection changed Current to '%s'", cfg.Current)
+	}
+
+	if err := cfg.RenameConnection("primary", "secondary"); err == nil {
+		t.Error("RenameConnection() should fail for a duplicate name")
+	}
+	if err := cfg.RenameConnection("nonexistent", "other"); err == nil {
+		t.Error("RenameConnection() should fail for non-existent connection")
+	}
+	if err := cfg.RenameConnection("primary", ""); err == nil {
+		t.Error("RenameConnection() should fail for an empty name")
+	}
+}
+
 func TestSetCurrent(t *testing.T) {
 	cfg := Default()
 	cfg.Connections = []Connection{
@@ -521,6 +557,12 @@ daemon:
 	if cfg.Daemon.MinRenewTTL != 5*time.Minute {
 		t.Errorf("expected default MinRenewTTL %v, got %v", 5*time.Minute, cfg.Daemon.MinRenewTTL)
 	}
+	if cfg.Daemon.MaxConcurrency != DefaultMaxConcurrency {
+		t.Errorf("expected default MaxConcurrency %d, got %d", DefaultMaxConcurrency, cfg.Daemon.MaxConcurrency)
+	}
+	if cfg.Daemon.RenewTimeout != DefaultRenewTimeout {
+		t.Errorf("expected default RenewTimeout %v, got %v", DefaultRenewTimeout, cfg.Daemon.RenewTimeout)
+	}
 	// Check that the auto_start value was preserved
 	if !cfg.Daemon.AutoStart {
 		t.Error("expected AutoStart to be true as specified in config")
@@ -574,6 +616,13 @@ func TestConfigRoundTrip(t *testing.T) {
 			CAPath:        "/etc/ssl/certs",
 			ClientCert:    "/etc/ssl/client.pem",
 			ClientKey:     "/etc/ssl/client-key.pem",
+			AutoLogin: AutoLoginConfig{
+				Method:       AutoLoginAppRole,
+				RoleID:       "role-id",
+				SecretIDFile: "/run/secrets/secret-id",
+				Before:       10 * time.Minute,
+			},
+			IdleTimeout: 8 * time.Hour,
 		},
 		{
 			Name:    "prod",
@@ -625,6 +674,15 @@ func TestConfigRoundTrip(t *testing.T) {
 	if conn.CACert != "/etc/ssl/ca.pem" {
 		t.Errorf("CACert mismatch: got %q", conn.CACert)
 	}
+	if conn.AutoLogin != cfg.Connections[0].AutoLogin {
+		t.Errorf("AutoLogin mismatch: got %+v", conn.AutoLogin)
+	}
+	if conn.IdleTimeout != 8*time.Hour {
+		t.Errorf("IdleTimeout mismatch: got %v, want %v", conn.IdleTimeout, 8*time.Hour)
+	}
+	if loaded.Connections[1].AutoLogin.Enabled() {
+		t.Error("AutoLogin should be disabled when not configured")
+	}
 
 	// Check daemon config
 	if loaded.Daemon.AutoStart != true {
@@ -921,3 +979,209 @@ func TestValidateConnection(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenStoreConfigDefaults(t *testing.T) {
+	var ts TokenStoreConfig
+
+	if got := ts.GetType(); got != TokenStoreKeyring {
+		t.Errorf("GetType() = %q, want %q", got, TokenStoreKeyring)
+	}
+	if got := ts.GetPassphraseEnv(); got != DefaultTokenStorePassphraseEnv {
+		t.Errorf("GetPassphraseEnv() = %q, want %q", got, DefaultTokenStorePassphraseEnv)
+	}
+	if want := filepath.Join(GetPaths().DataDir, "tokens"); ts.GetPath() != want {
+		t.Errorf("GetPath() = %q, want %q", ts.GetPath(), want)
+	}
+
+	ts = TokenStoreConfig{
+		Type:          TokenStoreEncryptedFile,
+		Path:          "/srv/patrol/tokens",
+		PassphraseEnv: "MY_PASSPHRASE",
+	}
+	if got := ts.GetType(); got != TokenStoreEncryptedFile {
+		t.Errorf("GetType() = %q, want %q", got, TokenStoreEncryptedFile)
+	}
+	if got := ts.GetPath(); got != "/srv/patrol/tokens" {
+		t.Errorf("GetPath() = %q, want %q", got, "/srv/patrol/tokens")
+	}
+	if got := ts.GetPassphraseEnv(); got != "MY_PASSPHRASE" {
+		t.Errorf("GetPassphraseEnv() = %q, want %q", got, "MY_PASSPHRASE")
+	}
+}
+
+func TestAutoLoginConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		auto    AutoLoginConfig
+		wantErr bool
+	}{
+		{name: "disabled", auto: AutoLoginConfig{}, wantErr: false},
+		{name: "approle with secret file", auto: AutoLoginConfig{Method: "approle", RoleID: "r", SecretIDFile: "/run/secret"}, wantErr: false},
+		{name: "approle without role_id", auto: AutoLoginConfig{Method: "approle"}, wantErr: true},
+		{name: "approle with two secret sources", auto: AutoLoginConfig{Method: "approle", RoleID: "r", SecretIDFile: "/run/secret", SecretIDFromStore: true}, wantErr: true},
+		{name: "cert", auto: AutoLoginConfig{Method: "cert"}, wantErr: false},
+		{name: "kubernetes without role", auto: AutoLoginConfig{Method: "kubernetes"}, wantErr: true},
+		{name: "jwt without file", auto: AutoLoginConfig{Method: "jwt", Role: "ci"}, wantErr: true},
+		{name: "negative before", auto: AutoLoginConfig{Method: "cert", Before: -time.Minute}, wantErr: true},
+		{name: "interactive method", auto: AutoLoginConfig{Method: "userpass"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auto.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	k8s := AutoLoginConfig{Method: AutoLoginKubernetes, Role: "app"}
+	if got := k8s.GetJWTFile(); got != DefaultKubernetesJWTFile {
+		t.Errorf("GetJWTFile() = %q, want %q", got, DefaultKubernetesJWTFile)
+	}
+}
+
+func TestHookConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    HookConfig
+		wantErr bool
+	}{
+		{name: "valid", hook: HookConfig{Command: "/usr/local/bin/sync", Events: []string{HookLogin, HookRenewal}, Profiles: []string{"prod-*"}}, wantErr: false},
+		{name: "no command", hook: HookConfig{Events: []string{HookLogin}}, wantErr: true},
+		{name: "no events", hook: HookConfig{Command: "true"}, wantErr: true},
+		{name: "unknown event", hook: HookConfig{Command: "true", Events: []string{"expired"}}, wantErr: true},
+		{name: "bad pattern", hook: HookConfig{Command: "true", Events: []string{HookLogout}, Profiles: []string{"prod-["}}, wantErr: true},
+		{name: "negative timeout", hook: HookConfig{Command: "true", Events: []string{HookFailure}, Timeout: -time.Second}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hook.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	hook := HookConfig{Command: "/usr/local/bin/sync-creds"}
+	if got := hook.GetName(); got != "sync-creds" {
+		t.Errorf("GetName() = %q, want the command's base name", got)
+	}
+	if got := hook.GetTimeout(); got != DefaultHookTimeout {
+		t.Errorf("GetTimeout() = %s, want %s", got, DefaultHookTimeout)
+	}
+}
+
+func TestHookConfigMatches(t *testing.T) {
+	hook := HookConfig{Command: "true", Events: []string{HookRenewal}, Profiles: []string{"prod-*", "staging"}}
+
+	tests := []struct {
+		event   string
+		profile string
+		want    bool
+	}{
+		{HookRenewal, "prod-eu", true},
+		{HookRenewal, "staging", true},
+		{HookRenewal, "dev", false},
+		{HookLogin, "prod-eu", false},
+	}
+	for _, tt := range tests {
+		if got := hook.Matches(tt.event, tt.profile); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.event, tt.profile, got, tt.want)
+		}
+	}
+
+	all := HookConfig{Command: "true", Events: []string{HookLogout}}
+	if !all.Matches(HookLogout, "anything") {
+		t.Error("a hook without profiles should match every profile")
+	}
+}
+
+func TestQuietHoursContains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	overnight := QuietHoursConfig{Start: "22:00", End: "07:00"}
+	daytime := QuietHoursConfig{Start: "12:00", End: "13:30"}
+	tests := []struct {
+		name  string
+		quiet QuietHoursConfig
+		t     time.Time
+		want  bool
+	}{
+		{"overnight, late evening", overnight, at(23, 15), true},
+		{"overnight, early morning", overnight, at(6, 59), true},
+		{"overnight, at the end", overnight, at(7, 0), false},
+		{"overnight, afternoon", overnight, at(15, 0), false},
+		{"daytime, inside", daytime, at(13, 0), true},
+		{"daytime, outside", daytime, at(13, 30), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quiet.Contains(tt.t); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.t.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+
+	if err := (&QuietHoursConfig{Start: "22:00"}).Validate(); err == nil {
+		t.Error("Validate() should require both ends of quiet hours")
+	}
+}
+
+func TestNotificationConfigThrottleDefaults(t *testing.T) {
+	var n NotificationConfig
+	if got := n.GetDedupWindow(); got != DefaultNotificationDedupWindow {
+		t.Errorf("GetDedupWindow() = %s, want %s", got, DefaultNotificationDedupWindow)
+	}
+	if got := n.GetRateLimit(); got != DefaultNotificationRateLimit {
+		t.Errorf("GetRateLimit() = %d, want %d", got, DefaultNotificationRateLimit)
+	}
+
+	n = NotificationConfig{DedupWindow: -1, RateLimit: -1}
+	if n.GetDedupWindow() != 0 || n.GetRateLimit() != 0 {
+		t.Errorf("-1 should disable deduplication and the rate limit")
+	}
+}
+
+func TestDaemonConfigLogMaxFiles(t *testing.T) {
+	tests := []struct {
+		maxFiles int
+		want     int
+	}{
+		{0, DefaultLogMaxFiles},
+		{3, 3},
+		{-1, 0},
+	}
+
+	for _, tt := range tests {
+		d := DaemonConfig{LogMaxFiles: tt.maxFiles}
+		if got := d.GetLogMaxFiles(); got != tt.want {
+			t.Errorf("GetLogMaxFiles() with log_max_files %d = %d, want %d", tt.maxFiles, got, tt.want)
+		}
+	}
+}
+
+func TestHealthConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     HealthConfig
+		wantErr bool
+	}{
+		{"empty", HealthConfig{}, false},
+		{"tls", HealthConfig{TLSCert: "/cert.pem", TLSKey: "/key.pem"}, false},
+		{"mtls and token", HealthConfig{TLSCert: "/cert.pem", TLSKey: "/key.pem", ClientCA: "/ca.pem", TokenEnv: "TOKEN"}, false},
+		{"cert without key", HealthConfig{TLSCert: "/cert.pem"}, true},
+		{"client ca without tls", HealthConfig{ClientCA: "/ca.pem"}, true},
+		{"two tokens", HealthConfig{TokenFile: "/token", TokenEnv: "TOKEN"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/notify"
+	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/tokenstore"
+	"github.com/xabinapal/patrol/internal/types"
+	"github.com/xabinapal/patrol/internal/vault"
+)
+
+// autoLoginConn returns the connection of profile name if it has auto_login
+// configured, or nil.
+func autoLoginConn(cfg *config.Config, name string) *config.Connection {
+	conn, err := cfg.GetConnection(name)
+	if err != nil || !conn.AutoLogin.Enabled() {
+		return nil
+	}
+	return conn
+}
+
+// autoLoginBefore returns how long before expiry the token of conn is
+// replaced by a new login.
+func autoLoginBefore(cfg *config.Config, conn *config.Connection) time.Duration {
+	if conn.AutoLogin.Before > 0 {
+		return conn.AutoLogin.Before
+	}
+	return cfg.Daemon.MinRenewTTL
+}
+
+// reloginTime returns when the token of profile name should be replaced by
+// a new login rather than renewed: right away once it expired, and like a
+// renewal (by threshold or the auto_login margin) when renewing can no longer
+// extend it. It returns false if the profile has no auto_login or its token
+// can still be renewed.
+func (d *Daemon) reloginTime(cfg *config.Config, name string, tok *types.Token, now time.Time) (time.Time, bool) {
+	conn := autoLoginConn(cfg, name)
+	if conn == nil || tok.ExpiresAt.IsZero() {
+		return time.Time{}, false
+	}
+	if !tok.ExpiresAt.After(now) {
+		return now, true
+	}
+
+	end := tok.ExpiresAt
+	if tok.Renewable {
+		meta, err := d.store.GetMetadata(types.FromConnection(conn))
+		if err != nil {
+			return time.Time{}, false
+		}
+		hardExpiry, capped := meta.HardExpiry()
+		if !capped {
+			return time.Time{}, false
+		}
+		end = hardExpiry
+	}
+
+	// The end of the token is treated as a lease that cannot be renewed.
+	lease := &types.Token{Renewable: true, ExpiresAt: end, LeaseDuration: tok.LeaseDuration}
+	return nextRenewalTime(lease, cfg.Daemon.RenewThreshold, autoLoginBefore(cfg, conn), now)
+}
+
+// autoLogin logs profile prof in again with its auto_login settings and
+// replaces the stored token. It reports whether the login succeeded.
+func (d *Daemon) autoLogin(ctx context.Context, cfg *config.Config, tm *token.TokenManager, prof *types.Profile) bool {
+	name := prof.Name
+	conn := autoLoginConn(cfg, name)
+	if conn == nil {
+		return false
+	}
+
+	d.logger.Info("Logging in again", "profile", name, "method", conn.AutoLogin.Method)
+
+	resp, err := d.doAutoLogin(ctx, tm, prof, conn)
+	if err != nil {
+		if shuttingDown(ctx) {
+			// Log in again right after the next start.
+			d.queue.Reschedule(name, time.Now())
+			return false
+		}
+		err = timeoutErr(ctx, err)
+		d.logger.Error("Automatic login failed", "profile", name, "event", notify.EventFailure, "error", err)
+		d.queue.Reschedule(name, d.recordRenewalFailure(cfg, name, err))
+		d.notifyFailure(ctx, prof, err)
+		d.runHooks(ctx, cfg, config.HookFailure, prof, 0, err)
+		return false
+	}
+
+	d.recordRenewalSuccess(name)
+
+	newTTL := time.Duration(resp.LeaseDuration) * time.Second
+	d.logger.Info("Logged in again, token replaced", "profile", name, "event", config.HookLogin, "ttl", newTTL)
+	if notifyErr := d.currentNotifier().NotifyRenewal(name, newTTL); notifyErr != nil {
+		d.logger.Debug("Failed to send notification", "profile", name, "event", notify.EventRenewal, "error", notifyErr)
+	}
+	d.runHooks(ctx, cfg, config.HookLogin, prof, newTTL, nil)
+
+	d.scheduleNext(cfg, name, &types.Token{
+		ClientToken:   resp.ClientToken,
+		LeaseDuration: resp.LeaseDuration,
+		Renewable:     resp.Renewable,
+		ExpiresAt:     resp.ExpiresAt,
+	})
+	return true
+}
+
+// doAutoLogin performs the login for conn and stores the new token.
+func (d *Daemon) doAutoLogin(ctx context.Context, tm *token.TokenManager, prof *types.Profile, conn *config.Connection) (*vault.VaultTokenResponse, error) {
+	params, err := autoLoginParams(d.store, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := vault.NewLoginExecutor().Login(ctx, prof, conn.AutoLogin.Method, conn.AutoLogin.Path, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := tm.SetFromLogin(prof, resp); err != nil {
+		return nil, fmt.Errorf("failed to store token: %w", err)
+	}
+	return resp, nil
+}
+
+// autoLoginParams returns the login parameters for the auto_login settings
+// of conn. Secrets are read on every login so rotated files are picked up.
+func autoLoginParams(store tokenstore.TokenStore, conn *config.Connection) (map[string]string, error) {
+	al := &conn.AutoLogin
+	if err := al.Validate(); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]string)
+	switch al.Method {
+	case config.AutoLoginAppRole:
+		params["role_id"] = al.RoleID
+		switch {
+		case al.SecretIDFile != "":
+			secretID, err := readSecretFile(al.SecretIDFile)
+			if err != nil {
+				return nil, err
+			}
+			params["secret_id"] = secretID
+		case al.SecretIDFromStore:
+			secretID, err := store.Get(&types.Profile{Name: conn.AutoLoginSecretName()})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read secret ID from the token store: %w", err)
+			}
+			params["secret_id"] = secretID
+		}
+	case config.AutoLoginCert:
+		if al.Role != "" {
+			params["name"] = al.Role
+		}
+	case config.AutoLoginKubernetes, config.AutoLoginJWT:
+		jwt, err := readSecretFile(al.GetJWTFile())
+		if err != nil {
+			return nil, err
+		}
+		params["jwt"] = jwt
+		if al.Role != "" {
+			params["role"] = al.Role
+		}
+	}
+	return params, nil
+}
+
+// readSecretFile returns the trimmed contents of the secret file at path.
+func readSecretFile(path string) (string, error) {
+	// #nosec G304 - path comes from the user's auto_login configuration
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
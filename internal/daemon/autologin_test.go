@@ -0,0 +1,179 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// approleHandler serves AppRole logins, recording the secret IDs it received.
+// Any other request is denied, as for an expired token.
+func approleHandler(t *testing.T, secrets *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/approle/login" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode login body: %v", err)
+		}
+		*secrets = append(*secrets, body["secret_id"])
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"auth":{"client_token":"hvs.relogged","lease_duration":7200,"renewable":false}}`)
+	}
+}
+
+func TestAutoLoginParams(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	conn := &cfg.Connections[0]
+
+	secretFile := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(secretFile, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.store.Set(&types.Profile{Name: conn.AutoLoginSecretName()}, "store-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		auto    config.AutoLoginConfig
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "approle with secret file",
+			auto: config.AutoLoginConfig{Method: "approle", RoleID: "role", SecretIDFile: secretFile},
+			want: map[string]string{"role_id": "role", "secret_id": "file-secret"},
+		},
+		{
+			name: "approle with secret from store",
+			auto: config.AutoLoginConfig{Method: "approle", RoleID: "role", SecretIDFromStore: true},
+			want: map[string]string{"role_id": "role", "secret_id": "store-secret"},
+		},
+		{
+			name: "cert with role name",
+			auto: config.AutoLoginConfig{Method: "cert", Role: "web"},
+			want: map[string]string{"name": "web"},
+		},
+		{
+			name: "jwt from file",
+			auto: config.AutoLoginConfig{Method: "jwt", Role: "ci", JWTFile: secretFile},
+			want: map[string]string{"role": "ci", "jwt": "file-secret"},
+		},
+		{
+			name:    "missing secret file",
+			auto:    config.AutoLoginConfig{Method: "approle", RoleID: "role", SecretIDFile: secretFile + ".missing"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn.AutoLogin = tt.auto
+			params, err := autoLoginParams(d.store, conn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("autoLoginParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(params) != len(tt.want) {
+				t.Errorf("autoLoginParams() = %v, want %v", params, tt.want)
+			}
+			for k, v := range tt.want {
+				if params[k] != v {
+					t.Errorf("params[%q] = %q, want %q", k, params[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestAutoLoginReplacesToken(t *testing.T) {
+	var secrets []string
+	server := httptest.NewServer(approleHandler(t, &secrets))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL})
+	secretFile := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(secretFile, []byte("s3cret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Connections[0].AutoLogin = config.AutoLoginConfig{Method: "approle", RoleID: "role", SecretIDFile: secretFile}
+	prof := types.FromConnection(&cfg.Connections[0])
+
+	// A token that cannot be renewed and expires within min_renew_ttl.
+	meta := &types.TokenMetadata{LeaseDuration: 3600, Renewable: false, ExpiresAt: time.Now().Add(time.Minute)}
+	if err := d.store.SetMetadata(prof, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	d.scheduleNext(cfg, prof.Name, meta.Token("hvs.token"))
+	if st := d.profileStatus(prof.Name); st.NextAction != "login" {
+		t.Errorf("next action = %q, want login", st.NextAction)
+	}
+
+	renewProfileNow(t, d, cfg, prof)
+
+	if len(secrets) != 1 || secrets[0] != "s3cret" {
+		t.Fatalf("login requests sent secret IDs %v, want one with the file contents", secrets)
+	}
+	if tok, err := d.store.Get(prof); err != nil || tok != "hvs.relogged" {
+		t.Errorf("stored token = %q, %v; want the new login token", tok, err)
+	}
+	expiresAt, at, ok := d.queue.Entry(prof.Name)
+	if !ok || time.Until(expiresAt) < time.Hour {
+		t.Errorf("profile tracked with expiry %v (tracked=%v), want the new token's", expiresAt, ok)
+	}
+	if !at.After(time.Now().Add(time.Hour)) {
+		t.Errorf("next login at %v, want near the end of the new token", at)
+	}
+}
+
+func TestAutoLoginAfterExpiry(t *testing.T) {
+	var secrets []string
+	server := httptest.NewServer(approleHandler(t, &secrets))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL})
+	cfg.Connections[0].AutoLogin = config.AutoLoginConfig{Method: "approle", RoleID: "role"}
+	prof := types.FromConnection(&cfg.Connections[0])
+
+	meta := &types.TokenMetadata{LeaseDuration: 3600, Renewable: true, ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := d.store.SetMetadata(prof, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	d.scheduleNext(cfg, prof.Name, meta.Token("hvs.token"))
+	if st := d.profileStatus(prof.Name); st.State == ProfileStateLoginRequired {
+		t.Error("a profile with auto_login should not wait for the user to log in")
+	}
+
+	renewProfileNow(t, d, cfg, prof)
+
+	if len(secrets) != 1 || secrets[0] != "" {
+		t.Errorf("login requests sent secret IDs %v, want one without a secret ID", secrets)
+	}
+	if tok, _ := d.store.Get(prof); tok != "hvs.relogged" {
+		t.Errorf("stored token = %q, want the new login token", tok)
+	}
+}
+
+// renewProfileNow runs the due check of prof, which must be scheduled now.
+func renewProfileNow(t *testing.T, d *Daemon, cfg *config.Config, prof *types.Profile) {
+	t.Helper()
+	if due := d.queue.PopDue(time.Now().Add(time.Second)); len(due) != 1 || due[0] != prof.Name {
+		t.Fatalf("due profiles = %v, want %s", due, prof.Name)
+	}
+	d.renewProfile(context.Background(), cfg, prof)
+}
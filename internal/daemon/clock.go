@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/notify"
+	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/types"
+	"github.com/xabinapal/patrol/internal/vault"
+)
+
+const (
+	// clockCheckInterval is how often the run loop compares the wall clock
+	// with the monotonic clock.
+	clockCheckInterval = 10 * time.Second
+	// clockJumpThreshold is the drift between both clocks that is treated as
+	// a suspend or a clock change rather than scheduling noise.
+	clockJumpThreshold = 30 * time.Second
+)
+
+// clockMonitor detects system suspend and wall clock changes. The monotonic
+// clock stops while the system is suspended and ignores clock changes, so
+// the difference between how far each clock advanced since the previous
+// check is the time the daemon did not see pass.
+type clockMonitor struct {
+	start    time.Time // monotonic reference
+	lastWall time.Time
+	lastMono time.Duration
+}
+
+// newClockMonitor creates a clock monitor starting now.
+func newClockMonitor() *clockMonitor {
+	now := time.Now()
+	return &clockMonitor{start: now, lastWall: now.Round(0)}
+}
+
+// observe compares both clocks with the previous check. It returns the
+// unseen time (positive after a suspend or a forward clock change, negative
+// if the clock was set back) and the wall time of the previous check.
+func (m *clockMonitor) observe() (time.Duration, time.Time) {
+	now := time.Now()
+	return m.check(now.Round(0), now.Sub(m.start))
+}
+
+// check is observe with explicit wall and monotonic readings.
+func (m *clockMonitor) check(wall time.Time, mono time.Duration) (time.Duration, time.Time) {
+	jump := wall.Sub(m.lastWall) - (mono - m.lastMono)
+	prevWall := m.lastWall
+	m.lastWall = wall
+	m.lastMono = mono
+	return jump, prevWall
+}
+
+// handleClockJump reacts to a suspend or clock change detected at now, with
+// the previous check at since: every profile is rescheduled by wall time, so
+// renewals that became due while the system slept run right away, and
+// tokens that expired in the meantime are reported as needing a new login.
+func (d *Daemon) handleClockJump(ctx context.Context, jump time.Duration, since, now time.Time) {
+	if jump > 0 {
+		d.logger.Info("System resumed or the clock was set forward, checking all tokens", "jump", jump.Round(time.Second))
+	} else {
+		d.logger.Info("Clock was set back, rescheduling all tokens", "jump", jump.Round(time.Second))
+	}
+
+	// Expired tokens are reported first, so rescheduling them does not
+	// announce the same expiry again.
+	if jump > 0 {
+		d.reportExpiredSince(ctx, since, now)
+	}
+
+	d.requeueAll()
+	d.rescanProfiles(ctx)
+}
+
+// reportExpiredSince notifies about tokens that expired between since and
+// now, which the daemon could not renew because the system was suspended.
+func (d *Daemon) reportExpiredSince(ctx context.Context, since, now time.Time) {
+	cfg := d.config
+	tm := token.NewTokenManager(ctx, d.store, vault.NewTokenExecutor())
+
+	for _, conn := range cfg.Connections {
+		prof := types.FromConnection(&conn)
+		meta, err := tm.GetMetadata(prof)
+		if err != nil || meta.ExpiresAt.IsZero() {
+			continue
+		}
+		if !meta.ExpiresAt.After(since) || meta.ExpiresAt.After(now) {
+			continue
+		}
+		if conn.AutoLogin.Enabled() {
+			// The daemon logs in again instead of asking the user to.
+			continue
+		}
+
+		d.setNeedsLogin(conn.Name, true)
+		d.logger.Warn("Token expired while the system was suspended, login required",
+			"profile", conn.Name, "event", notify.EventLoginRequired, "expired_at", meta.ExpiresAt)
+		if err := d.currentNotifier().NotifyLoginRequired(conn.Name); err != nil {
+			d.logger.Debug("Failed to send notification", "profile", conn.Name, "event", notify.EventLoginRequired, "error", err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// recordingNotifier records the profiles it was asked to notify about.
+type recordingNotifier struct {
+	mu       sync.Mutex
+	failures []string
+	expiring []time.Duration
+	idle     []time.Duration
+	logins   []string
+	sealed   []string
+}
+
+func (n *recordingNotifier) NotifyRenewal(profile string, newTTL time.Duration) error {
+	return nil
+}
+
+func (n *recordingNotifier) NotifyFailure(profile string, err error) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.failures = append(n.failures, profile)
+	return nil
+}
+
+func (n *recordingNotifier) NotifyExpiring(profile string, remaining time.Duration) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.expiring = append(n.expiring, remaining)
+	return nil
+}
+
+func (n *recordingNotifier) NotifyIdle(profile string, revokeIn time.Duration) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.idle = append(n.idle, revokeIn)
+	return nil
+}
+
+func (n *recordingNotifier) NotifyLoginRequired(profile string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.logins = append(n.logins, profile)
+	return nil
+}
+
+func (n *recordingNotifier) NotifySealed(profile, address string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sealed = append(n.sealed, profile)
+	return nil
+}
+
+func TestClockMonitorCheck(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := &clockMonitor{lastWall: base}
+
+	tests := []struct {
+		name     string
+		wall     time.Time
+		mono     time.Duration
+		wantJump time.Duration
+	}{
+		{"steady", base.Add(10 * time.Second), 10 * time.Second, 0},
+		{"suspended for an hour", base.Add(time.Hour + 20*time.Second), 20 * time.Second, time.Hour},
+		{"clock set back", base.Add(time.Hour + 25*time.Second), 35 * time.Second, -10 * time.Second},
+	}
+	prevWall := base
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jump, since := m.check(tt.wall, tt.mono)
+			if jump != tt.wantJump {
+				t.Errorf("jump = %s, want %s", jump, tt.wantJump)
+			}
+			if !since.Equal(prevWall) {
+				t.Errorf("since = %v, want the previous check %v", since, prevWall)
+			}
+			prevWall = tt.wall
+		})
+	}
+}
+
+func TestHandleClockJump(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1", "http://127.0.0.1:2"})
+	notifier := &recordingNotifier{}
+	d.notifier = notifier
+	var logs bytes.Buffer
+	d.SetLogger(&Logger{writer: &logs, level: LogLevelInfo})
+
+	now := time.Now()
+	since := now.Add(-time.Hour)
+
+	// profile-0 expired during the suspend; profile-1 became due for renewal.
+	expired := types.FromConnection(&cfg.Connections[0])
+	meta := &types.TokenMetadata{LeaseDuration: 3600, Renewable: true, ExpiresAt: now.Add(-10 * time.Minute)}
+	if err := d.store.SetMetadata(expired, meta); err != nil {
+		t.Fatal(err)
+	}
+	// Both were scheduled before the suspend, far in the monotonic future.
+	d.queue.Schedule("profile-0", meta.ExpiresAt, now.Add(time.Hour))
+	d.queue.Schedule("profile-1", now.Add(time.Minute), now.Add(time.Hour))
+
+	d.handleClockJump(context.Background(), time.Hour, since, now)
+
+	if len(notifier.logins) != 1 || notifier.logins[0] != "profile-0" {
+		t.Errorf("login required notifications = %v, want only profile-0", notifier.logins)
+	}
+	if st := d.profileStatus("profile-0"); st.State != ProfileStateLoginRequired {
+		t.Errorf("profile-0 state = %q, want %q", st.State, ProfileStateLoginRequired)
+	}
+	if len(notifier.failures) != 0 {
+		t.Errorf("failure notifications = %v, want none on top of the login required one", notifier.failures)
+	}
+	if !strings.Contains(logs.String(), "event=login_required") {
+		t.Errorf("log = %q, want the expiry logged as a login_required event", logs.String())
+	}
+	if due := d.queue.PopDue(time.Now()); len(due) != 1 || due[0] != "profile-1" {
+		t.Errorf("due profiles = %v, want profile-1 renewed right away", due)
+	}
+}
@@ -0,0 +1,464 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/types"
+	"github.com/xabinapal/patrol/internal/vault"
+)
+
+// ControlSocketName is the name of the control socket in the data directory.
+const ControlSocketName = "patrol.sock"
+
+// controlTimeout bounds a single control request, on both ends of the socket.
+const controlTimeout = 10 * time.Second
+
+// Control commands understood by the daemon.
+const (
+	ControlStatus = "status"
+	ControlRenew  = "renew"
+	ControlReload = "reload"
+	ControlPause  = "pause"
+	ControlResume = "resume"
+)
+
+// Profile states reported by the status command.
+const (
+	// ProfileStateUntracked means no token is stored, or the daemon has not
+	// seen it yet.
+	ProfileStateUntracked = "untracked"
+	// ProfileStateScheduled means the next renewal check is scheduled.
+	ProfileStateScheduled = "scheduled"
+	// ProfileStateIdle means the token is tracked but needs no renewal, for
+	// example because it does not expire or cannot be renewed.
+	ProfileStateIdle = "idle"
+	// ProfileStateRenewing means a worker is renewing the token right now.
+	ProfileStateRenewing = "renewing"
+	// ProfileStateBackoff means the last attempts failed and the next one is
+	// delayed.
+	ProfileStateBackoff = "backoff"
+	// ProfileStatePaused means renewals were paused with the pause command.
+	ProfileStatePaused = "paused"
+	// ProfileStateLoginRequired means the token expired and a new login is needed.
+	ProfileStateLoginRequired = "login_required"
+)
+
+// ErrDaemonNotReachable is returned when no daemon answers on the control socket.
+var ErrDaemonNotReachable = errors.New("daemon is not reachable on its control socket")
+
+// ControlRequest is a command sent to the daemon over the control socket.
+// Each connection carries a single JSON request followed by a single JSON response.
+type ControlRequest struct {
+	Command string `json:"command"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// ControlResponse is the daemon's answer to a ControlRequest.
+type ControlResponse struct {
+	OK      bool    `json:"ok"`
+	Error   string  `json:"error,omitempty"`
+	Message string  `json:"message,omitempty"`
+	Status  *Status `json:"status,omitempty"`
+}
+
+// Status is the live state of a running daemon.
+type Status struct {
+	PID       int             `json:"pid"`
+	StartedAt time.Time       `json:"started_at"`
+	Profiles  []ProfileStatus `json:"profiles"`
+}
+
+// ProfileStatus is the renewal state of a single profile.
+type ProfileStatus struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// NextAction is what the daemon will do next (check, renew, login or retry),
+	// empty if nothing is scheduled.
+	NextAction   string    `json:"next_action,omitempty"`
+	NextActionAt time.Time `json:"next_action_at,omitzero"`
+	// Failures is the number of consecutive failed attempts.
+	Failures    int       `json:"failures,omitempty"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// ControlSocketPath returns the path of the daemon control socket.
+func ControlSocketPath(cfg *config.Config) string {
+	if cfg.Daemon.ControlSocket != "" {
+		return cfg.Daemon.ControlSocket
+	}
+	return filepath.Join(config.GetPaths().DataDir, ControlSocketName)
+}
+
+// controlServer serves control requests on a Unix domain socket.
+type controlServer struct {
+	d        *Daemon
+	path     string
+	listener net.Listener
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// startControlServer listens on the control socket and serves requests until
+// Close is called. It must only be called while holding the PID file lock, as
+// it removes any socket left behind by a previous instance.
+func (d *Daemon) startControlServer(ctx context.Context) (*controlServer, error) {
+	path := ControlSocketPath(d.config)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	// Tighten the mode right away rather than through the umask, which is
+	// shared by the whole process.
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set control socket permissions: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &controlServer{d: d, path: path, listener: listener, cancel: cancel}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.serve(ctx)
+	}()
+
+	return s, nil
+}
+
+// serve accepts connections until the listener is closed.
+func (s *controlServer) serve(ctx context.Context) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.d.logger.Warn("Control socket accept failed", "error", err)
+			}
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(ctx, conn)
+		}()
+	}
+}
+
+// handleConn reads a single request from conn and writes the response.
+func (s *controlServer) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(controlTimeout)) //nolint:errcheck // best-effort deadline
+
+	var req ControlRequest
+	var resp ControlResponse
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		resp = ControlResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+	} else {
+		s.d.logger.Debug("Control request", "command", req.Command, "profile", req.Profile)
+		resp = s.d.handleControl(ctx, req)
+	}
+
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		s.d.logger.Debug("Failed to write control response", "error", err)
+	}
+}
+
+// Close stops accepting requests, waits for in-progress requests to finish
+// and removes the socket.
+func (s *controlServer) Close() error {
+	s.cancel()
+	err := s.listener.Close()
+	s.wg.Wait()
+	_ = os.Remove(s.path)
+	return err
+}
+
+// handleControl executes a control request.
+func (d *Daemon) handleControl(ctx context.Context, req ControlRequest) ControlResponse {
+	var (
+		msg string
+		err error
+	)
+
+	switch req.Command {
+	case ControlStatus:
+		return ControlResponse{OK: true, Status: d.status()}
+	case ControlReload:
+		msg, err = d.requestReload(ctx)
+	case ControlRenew:
+		msg, err = d.requestRenew(ctx, req.Profile)
+	case ControlPause:
+		msg, err = d.pauseProfile(req.Profile)
+	case ControlResume:
+		msg, err = d.resumeProfile(req.Profile)
+	default:
+		err = fmt.Errorf("unknown command %q", req.Command)
+	}
+
+	if err != nil {
+		return ControlResponse{Error: err.Error()}
+	}
+	return ControlResponse{OK: true, Message: msg}
+}
+
+// requestReload asks the run loop to reload the configuration and rescan all
+// profiles, and waits for it to finish.
+func (d *Daemon) requestReload(ctx context.Context) (string, error) {
+	done := make(chan error, 1)
+	select {
+	case d.reloads <- done:
+	case <-ctx.Done():
+		return "", errors.New("daemon is shutting down")
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", fmt.Errorf("configuration not reloaded: %w", err)
+		}
+		return "Configuration reloaded", nil
+	case <-ctx.Done():
+		return "", errors.New("daemon is shutting down")
+	}
+}
+
+// requestRenew schedules an immediate renewal of profile name, regardless of
+// its remaining TTL and any backoff.
+func (d *Daemon) requestRenew(ctx context.Context, name string) (string, error) {
+	prof, err := d.controlProfile(name)
+	if err != nil {
+		return "", err
+	}
+	if d.isPaused(name) {
+		return "", fmt.Errorf("profile %s is paused; resume it first", name)
+	}
+	if d.isInFlight(name) {
+		return fmt.Sprintf("Profile %s is already being renewed", name), nil
+	}
+
+	tm := token.NewTokenManager(ctx, d.store, vault.NewTokenExecutor())
+	if !tm.HasToken(prof) {
+		return "", fmt.Errorf("profile %s has no stored token", name)
+	}
+
+	d.mu.Lock()
+	d.forceRenew[name] = true
+	d.mu.Unlock()
+	d.resetBackoff(name)
+
+	d.queue.Reschedule(name, time.Now())
+	d.wakeRunLoop()
+	d.logger.Info("Renewal requested", "profile", name)
+	return fmt.Sprintf("Renewal of profile %s scheduled", name), nil
+}
+
+// pauseProfile stops renewals of profile name until it is resumed.
+func (d *Daemon) pauseProfile(name string) (string, error) {
+	if _, err := d.controlProfile(name); err != nil {
+		return "", err
+	}
+
+	d.mu.Lock()
+	already := d.paused[name]
+	d.paused[name] = true
+	delete(d.forceRenew, name)
+	d.mu.Unlock()
+
+	if already {
+		return fmt.Sprintf("Profile %s is already paused", name), nil
+	}
+	d.logger.Info("Renewals paused", "profile", name)
+	return fmt.Sprintf("Renewals of profile %s paused", name), nil
+}
+
+// resumeProfile resumes renewals of a paused profile and checks it right away.
+func (d *Daemon) resumeProfile(name string) (string, error) {
+	if _, err := d.controlProfile(name); err != nil {
+		return "", err
+	}
+
+	d.mu.Lock()
+	paused := d.paused[name]
+	delete(d.paused, name)
+	d.mu.Unlock()
+
+	if !paused {
+		return fmt.Sprintf("Profile %s is not paused", name), nil
+	}
+
+	if _, ok := d.queue.Tracked(name); ok {
+		d.queue.Reschedule(name, time.Now())
+		d.wakeRunLoop()
+	}
+	d.logger.Info("Renewals resumed", "profile", name)
+	return fmt.Sprintf("Renewals of profile %s resumed", name), nil
+}
+
+// controlProfile returns the configured profile name refers to.
+func (d *Daemon) controlProfile(name string) (*types.Profile, error) {
+	if name == "" {
+		return nil, errors.New("profile name is required")
+	}
+	conn, err := d.currentConfig().GetConnection(name)
+	if err != nil {
+		return nil, err
+	}
+	return types.FromConnection(conn), nil
+}
+
+// status returns a snapshot of the daemon and every configured profile.
+func (d *Daemon) status() *Status {
+	cfg := d.currentConfig()
+
+	d.mu.Lock()
+	startedAt := d.startedAt
+	d.mu.Unlock()
+
+	st := &Status{
+		PID:       os.Getpid(),
+		StartedAt: startedAt,
+		Profiles:  make([]ProfileStatus, 0, len(cfg.Connections)),
+	}
+	for _, conn := range cfg.Connections {
+		st.Profiles = append(st.Profiles, d.profileStatus(conn.Name))
+	}
+	return st
+}
+
+// profileStatus returns the renewal state of profile name.
+func (d *Daemon) profileStatus(name string) ProfileStatus {
+	ps := ProfileStatus{Name: name, State: ProfileStateUntracked}
+
+	expiresAt, at, tracked := d.queue.Entry(name)
+	ps.ExpiresAt = expiresAt
+
+	d.mu.Lock()
+	paused := d.paused[name]
+	inFlight := d.inFlight[name]
+	needsLogin := false
+	loginDue := d.loginDue[name]
+	if state := d.states[name]; state != nil {
+		ps.Failures = state.FailureCount
+		ps.LastSuccess = state.LastSuccess
+		ps.LastError = state.LastError
+		needsLogin = state.NeedsLogin
+	}
+	d.mu.Unlock()
+
+	switch {
+	case paused:
+		ps.State = ProfileStatePaused
+		return ps
+	case inFlight:
+		ps.State = ProfileStateRenewing
+		return ps
+	case needsLogin:
+		ps.State = ProfileStateLoginRequired
+		return ps
+	case !tracked:
+		return ps
+	case at.IsZero():
+		ps.State = ProfileStateIdle
+		return ps
+	}
+
+	ps.NextActionAt = at
+	switch {
+	case ps.Failures > 0:
+		ps.State = ProfileStateBackoff
+		ps.NextAction = "retry"
+	case expiresAt.IsZero():
+		ps.State = ProfileStateScheduled
+		ps.NextAction = "check"
+	case loginDue:
+		ps.State = ProfileStateScheduled
+		ps.NextAction = "login"
+	default:
+		ps.State = ProfileStateScheduled
+		ps.NextAction = "renew"
+	}
+	return ps
+}
+
+// ControlClient sends commands to a running daemon over its control socket.
+type ControlClient struct {
+	path string
+}
+
+// NewControlClient creates a client for the control socket configured in cfg.
+func NewControlClient(cfg *config.Config) *ControlClient {
+	return &ControlClient{path: ControlSocketPath(cfg)}
+}
+
+// Do sends req to the daemon and returns its response. Requests the daemon
+// rejects are returned as errors.
+func (c *ControlClient) Do(ctx context.Context, req ControlRequest) (*ControlResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, controlTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDaemonNotReachable, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline) //nolint:errcheck // best-effort deadline
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send control request: %w", err)
+	}
+
+	var resp ControlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read control response: %w", err)
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("daemon: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// Status returns the live state of the daemon.
+func (c *ControlClient) Status(ctx context.Context) (*Status, error) {
+	resp, err := c.Do(ctx, ControlRequest{Command: ControlStatus})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status == nil {
+		return nil, errors.New("daemon returned no status")
+	}
+	return resp.Status, nil
+}
+
+// Send sends a command without a structured result and returns the daemon's message.
+func (c *ControlClient) Send(ctx context.Context, command, profile string) (string, error) {
+	resp, err := c.Do(ctx, ControlRequest{Command: command, Profile: profile})
+	if err != nil {
+		return "", err
+	}
+	return resp.Message, nil
+}
@@ -0,0 +1,205 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// startTestControl serves the control socket of d from a short temporary
+// path, as Unix socket paths are limited in length.
+func startTestControl(t *testing.T, d *Daemon, cfg *config.Config) *ControlClient {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "patrol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	cfg.Daemon.ControlSocket = filepath.Join(dir, ControlSocketName)
+
+	ctl, err := d.startControlServer(context.Background())
+	if err != nil {
+		t.Fatalf("startControlServer() failed: %v", err)
+	}
+	t.Cleanup(func() { ctl.Close() })
+
+	info, err := os.Stat(cfg.Daemon.ControlSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("control socket permissions = %o, want 600", perm)
+	}
+
+	return NewControlClient(cfg)
+}
+
+func TestControlStatus(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1", "http://127.0.0.1:2", "http://127.0.0.1:3"})
+	client := startTestControl(t, d, cfg)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour)
+	d.queue.Schedule("profile-0", expiresAt, time.Now().Add(30*time.Minute))
+	d.queue.Schedule("profile-1", expiresAt, time.Time{})
+	d.recordRenewalFailure(cfg, "profile-2", errors.New("connection refused"))
+	d.queue.Schedule("profile-2", expiresAt, time.Now().Add(time.Minute))
+
+	st, err := client.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if st.PID != os.Getpid() {
+		t.Errorf("PID = %d, want %d", st.PID, os.Getpid())
+	}
+
+	want := map[string]struct{ state, action string }{
+		"profile-0": {ProfileStateScheduled, "renew"},
+		"profile-1": {ProfileStateIdle, ""},
+		"profile-2": {ProfileStateBackoff, "retry"},
+	}
+	if len(st.Profiles) != len(want) {
+		t.Fatalf("got %d profiles, want %d", len(st.Profiles), len(want))
+	}
+	for _, p := range st.Profiles {
+		if p.State != want[p.Name].state || p.NextAction != want[p.Name].action {
+			t.Errorf("profile %s: state %q, action %q; want %q, %q",
+				p.Name, p.State, p.NextAction, want[p.Name].state, want[p.Name].action)
+		}
+	}
+}
+
+func TestControlPauseResume(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	client := startTestControl(t, d, cfg)
+	ctx := context.Background()
+
+	d.queue.Schedule("profile-0", time.Now().Add(time.Hour), time.Now())
+
+	if _, err := client.Send(ctx, ControlPause, "profile-0"); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	if !d.isPaused("profile-0") {
+		t.Fatal("profile should be paused")
+	}
+
+	// Due but paused profiles are not handed to workers.
+	d.dispatchDueTokens(ctx, make(chan struct{}, 1))
+	d.workers.Wait()
+	if _, at, _ := d.queue.Entry("profile-0"); !at.IsZero() {
+		t.Error("paused profile should stay unscheduled")
+	}
+	if st := d.profileStatus("profile-0"); st.State != ProfileStatePaused {
+		t.Errorf("state = %q, want %q", st.State, ProfileStatePaused)
+	}
+
+	if _, err := client.Send(ctx, ControlRenew, "profile-0"); err == nil {
+		t.Error("renewing a paused profile should fail")
+	}
+
+	if _, err := client.Send(ctx, ControlResume, "profile-0"); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if d.isPaused("profile-0") {
+		t.Fatal("profile should no longer be paused")
+	}
+	if _, at, _ := d.queue.Entry("profile-0"); at.IsZero() {
+		t.Error("resumed profile should be scheduled for a check")
+	}
+}
+
+func TestControlRenewForcesRenewal(t *testing.T) {
+	var active, peak int32
+	server := httptest.NewServer(renewHandler(0, &active, &peak))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL})
+	client := startTestControl(t, d, cfg)
+	ctx := context.Background()
+
+	// A fresh token that does not need renewal yet.
+	prof := types.FromConnection(&cfg.Connections[0])
+	meta := &types.TokenMetadata{LeaseDuration: 3600, Renewable: true, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := d.store.SetMetadata(prof, meta); err != nil {
+		t.Fatal(err)
+	}
+	d.recordRenewalFailure(cfg, prof.Name, errors.New("connection refused"))
+
+	if _, err := client.Send(ctx, ControlRenew, prof.Name); err != nil {
+		t.Fatalf("renew failed: %v", err)
+	}
+	if _, ok := d.backoffUntil(prof.Name); ok {
+		t.Error("renew request should clear the backoff")
+	}
+
+	d.dispatchDueTokens(ctx, make(chan struct{}, 1))
+	d.workers.Wait()
+
+	if atomic.LoadInt32(&peak) == 0 {
+		t.Error("token was not renewed")
+	}
+	if d.takeForceRenew(prof.Name) {
+		t.Error("renew request should be consumed by the renewal")
+	}
+}
+
+func TestControlErrors(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	client := startTestControl(t, d, cfg)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		command string
+		profile string
+		wantErr string
+	}{
+		{"unknown command", "explode", "", "unknown command"},
+		{"missing profile", ControlPause, "", "profile name is required"},
+		{"unknown profile", ControlRenew, "missing", "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Send(ctx, tt.command, tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Send() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestControlReload(t *testing.T) {
+	d, cfg := newTestDaemon(t, nil)
+	client := startTestControl(t, d, cfg)
+
+	reloadErr := errors.New("bad config")
+	go func() {
+		done := <-d.reloads
+		done <- reloadErr
+	}()
+
+	_, err := client.Send(context.Background(), ControlReload, "")
+	if err == nil || !strings.Contains(err.Error(), "bad config") {
+		t.Errorf("reload error = %v, want the reload failure", err)
+	}
+}
+
+func TestControlClientNotRunning(t *testing.T) {
+	cfg := config.Default()
+	cfg.Daemon.ControlSocket = filepath.Join(t.TempDir(), ControlSocketName)
+
+	_, err := NewControlClient(cfg).Status(context.Background())
+	if !errors.Is(err, ErrDaemonNotReachable) {
+		t.Errorf("Status() error = %v, want ErrDaemonNotReachable", err)
+	}
+}
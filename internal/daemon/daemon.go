@@ -21,25 +21,39 @@ import (
 	"github.com/xabinapal/patrol/internal/vault"
 )
 
-// connectionBackoff tracks the backoff state for a connection.
-type connectionBackoff struct {
-	failureCount int
-	nextRetry    time.Time
-}
-
 // Daemon manages automatic token renewal.
 type Daemon struct {
 	config       *config.Config
 	configPath   string // Path to the config file for reloading
+	pidFile      string // PID file written by Run
+	overrides    Overrides
 	store        tokenstore.TokenStore
+	state        *stateStore
+	usage        *token.UsageLog
 	logger       *Logger
 	healthServer *HealthServer
 	notifier     notify.Notifier
 
-	mu           sync.Mutex
-	running      bool
-	stopChan     chan struct{}
-	backoffState map[string]*connectionBackoff // keyed by connection name
+	mu         sync.Mutex
+	running    bool
+	startedAt  time.Time
+	stopChan   chan struct{}
+	states     map[string]*profileState // renewal state keyed by connection name
+	totals     stateTotals
+	inFlight   map[string]bool          // profiles being renewed by a worker
+	paused     map[string]bool          // profiles paused over the control socket
+	forceRenew map[string]bool          // profiles to renew regardless of their TTL
+	loginDue   map[string]bool          // profiles scheduled for a new login instead of a renewal
+	warned     map[string]expiryWarning // last expiry warning sent per profile
+	idleWarned map[string]time.Time     // last use of the token an idle warning was sent for
+	tokens     map[string]tokenInfo     // tracked token per profile, for metrics
+
+	checkingServers bool // a server health check for metrics is running
+
+	queue   *renewalQueue
+	workers sync.WaitGroup
+	wake    chan struct{}   // signals the run loop that the queue changed
+	reloads chan chan error // reload requests from the control socket
 }
 
 // New creates a new Daemon instance.
@@ -54,20 +68,30 @@ func New(cfg *config.Config, ts tokenstore.TokenStore) *Daemon {
 		logger = &Logger{writer: os.Stderr}
 	}
 
-	// Create notifier based on config
-	notifier := notify.New(cfg.Daemon.Notifications)
-
 	// Get config file path (use default if not set in config)
 	configPath := config.GetPaths().ConfigFile
 
-	return &Daemon{
-		config:       cfg,
-		configPath:   configPath,
-		store:        ts,
-		logger:       logger,
-		notifier:     notifier,
-		backoffState: make(map[string]*connectionBackoff),
+	d := &Daemon{
+		config:     cfg,
+		configPath: configPath,
+		store:      ts,
+		logger:     logger,
+		state:      &stateStore{path: defaultStatePath()},
+		usage:      token.NewUsageLog(token.DefaultUsageDir()),
+		states:     make(map[string]*profileState),
+		inFlight:   make(map[string]bool),
+		paused:     make(map[string]bool),
+		forceRenew: make(map[string]bool),
+		loginDue:   make(map[string]bool),
+		warned:     make(map[string]expiryWarning),
+		idleWarned: make(map[string]time.Time),
+		tokens:     make(map[string]tokenInfo),
+		queue:      newRenewalQueue(),
+		wake:       make(chan struct{}, 1),
+		reloads:    make(chan chan error),
 	}
+	d.notifier = d.newNotifier(cfg)
+	return d
 }
 
 // SetLogger sets a custom logger for the daemon.
@@ -77,6 +101,11 @@ func (d *Daemon) SetLogger(logger *Logger) {
 
 // SetHealthServer sets a health server for the daemon.
 func (d *Daemon) SetHealthServer(server *HealthServer) {
+	if server != nil {
+		server.SetProfileSource(d.profileMetrics)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.healthServer = server
 }
 
@@ -88,6 +117,7 @@ func (d *Daemon) Run(ctx context.Context) error {
 		return errors.New("daemon is already running")
 	}
 	d.running = true
+	d.startedAt = time.Now()
 	d.stopChan = make(chan struct{})
 	d.mu.Unlock()
 
@@ -108,9 +138,12 @@ func (d *Daemon) Run(ctx context.Context) error {
 	}
 
 	d.logger.Info("Starting token renewal daemon")
-	d.logger.Info(fmt.Sprintf("Check interval: %s", d.config.Daemon.CheckInterval))
-	d.logger.Info(fmt.Sprintf("Renewal threshold: %.0f%%", d.config.Daemon.RenewThreshold*100))
-	d.logger.Info(fmt.Sprintf("Minimum TTL for renewal: %s", d.config.Daemon.MinRenewTTL))
+	d.logger.Info("Daemon settings",
+		"rescan_interval", checkInterval(d.config),
+		"renew_threshold", d.config.Daemon.RenewThreshold,
+		"min_renew_ttl", d.config.Daemon.MinRenewTTL,
+		"max_concurrency", maxConcurrency(d.config),
+		"renew_timeout", renewTimeout(d.config))
 
 	// Write PID file for daemon tracking (with file locking to prevent race conditions)
 	if err := d.writePIDFile(); err != nil {
@@ -118,19 +151,25 @@ func (d *Daemon) Run(ctx context.Context) error {
 	}
 	defer d.removePIDFile()
 
-	// Start health server if configured
-	if d.healthServer != nil {
-		if err := d.healthServer.Start(); err != nil {
-			d.logger.Warn(fmt.Sprintf("failed to start health server: %v", err))
+	// Start health server if configured. It may be moved or replaced by a
+	// config reload, so the one running at exit is stopped.
+	if hs := d.health(); hs != nil {
+		if err := hs.Start(); err != nil {
+			d.logger.Warn("Failed to start health server", "error", err)
+			d.mu.Lock()
+			d.healthServer = nil
+			d.mu.Unlock()
 		} else {
-			d.logger.Info("Health server started")
-			defer func() {
-				if err := d.healthServer.Stop(); err != nil {
-					d.logger.Warn(fmt.Sprintf("failed to stop health server: %v", err))
-				}
-			}()
+			d.logger.Info("Health server started", "address", hs.Addr())
 		}
 	}
+	defer func() {
+		if hs := d.health(); hs != nil {
+			if err := hs.Stop(); err != nil {
+				d.logger.Warn("Failed to stop health server", "error", err)
+			}
+		}
+	}()
 
 	// Close logger on exit
 	defer func() {
@@ -139,19 +178,81 @@ func (d *Daemon) Run(ctx context.Context) error {
 		}
 	}()
 
+	// Pending notifications are delivered once the workers stopped, while
+	// failures can still be logged.
+	defer func() {
+		d.drainNotifier(d.currentNotifier())
+	}()
+
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer signal.Stop(sigChan)
 
-	// Create a ticker for periodic checks
-	ticker := time.NewTicker(d.config.Daemon.CheckInterval)
-	defer ticker.Stop()
+	// Workers are canceled on shutdown, and Run waits for them to finish.
+	ctx, cancel := context.WithCancel(ctx)
+	defer d.workers.Wait()
+	defer cancel()
+
+	// Accept live commands (renew, reload, pause) from the CLI.
+	if ctl, err := d.startControlServer(ctx); err != nil {
+		d.logger.Warn("Control socket unavailable", "error", err)
+	} else {
+		d.logger.Info("Control socket listening", "path", ctl.path)
+		defer func() {
+			if err := ctl.Close(); err != nil {
+				d.logger.Debug("Failed to close control socket", "error", err)
+			}
+		}()
+	}
+
+	// Tokens are renewed from a queue ordered by their next renewal time.
+	// The token store is rescanned periodically to pick up new tokens and
+	// tokens changed outside the daemon.
+	rescan := time.NewTicker(checkInterval(d.config))
+	defer rescan.Stop()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	// The config file is reloaded when it changes or on SIGHUP.
+	watcher := newConfigWatcher(d.configPath)
+	watch := time.NewTicker(configPollInterval)
+	defer watch.Stop()
+
+	// Detects system suspend and clock changes, which timers do not notice.
+	clock := newClockMonitor()
+	clockCheck := time.NewTicker(clockCheckInterval)
+	defer clockCheck.Stop()
+
+	// Bounds the number of profiles renewed at the same time. Workers hold
+	// on to the semaphore they started with when the limit changes.
+	sem := make(chan struct{}, maxConcurrency(d.config))
+
+	d.restoreState()
+
+	reload := func() error {
+		old, cur, err := d.reloadConfig()
+		if err != nil {
+			return err
+		}
+		if interval := checkInterval(cur); interval != checkInterval(old) {
+			rescan.Reset(interval)
+			d.logger.Info("Rescan interval changed", "rescan_interval", interval)
+		}
+		if limit := maxConcurrency(cur); limit != maxConcurrency(old) {
+			sem = make(chan struct{}, limit)
+			d.logger.Info("Max concurrent renewals changed", "max_concurrency", limit)
+		}
+		d.rescanProfiles(ctx)
+		return nil
+	}
 
-	// Do an initial check
-	d.checkAndRenewTokens(ctx)
+	d.rescanProfiles(ctx)
 
 	for {
+		d.resetTimer(timer)
+
 		select {
 		case <-ctx.Done():
 			d.logger.Info("Context canceled, shutting down")
@@ -160,15 +261,53 @@ func (d *Daemon) Run(ctx context.Context) error {
 			d.logger.Info("Stop signal received, shutting down")
 			return nil
 		case sig := <-sigChan:
-			d.logger.Info(fmt.Sprintf("Received signal %v, shutting down", sig))
+			if sig == syscall.SIGHUP {
+				d.logger.Info("Received SIGHUP, reloading config")
+				_ = reload() //nolint:errcheck // reload failures are logged
+				continue
+			}
+			d.logger.Info("Received signal, shutting down", "signal", sig.String())
 			return nil
-		case <-ticker.C:
-			d.logger.Info("Starting token renewal check")
-			d.checkAndRenewTokens(ctx)
+		case <-watch.C:
+			if watcher.Changed() {
+				d.logger.Info("Config file changed, reloading")
+				_ = reload() //nolint:errcheck // reload failures are logged
+			}
+		case <-clockCheck.C:
+			if jump, since := clock.observe(); jump > clockJumpThreshold || jump < -clockJumpThreshold {
+				d.handleClockJump(ctx, jump, since, time.Now())
+			}
+		case <-rescan.C:
+			d.logger.Debug("Rescanning profiles")
+			d.rescanProfiles(ctx)
+		case done := <-d.reloads:
+			d.logger.Info("Reload requested, reloading config")
+			done <- reload()
+		case <-timer.C:
+			d.dispatchDueTokens(ctx, sem)
+		case <-d.wake:
+			// A worker rescheduled a profile; re-arm the timer.
 		}
 	}
 }
 
+// resetTimer arms timer for the next scheduled renewal, or stops it if
+// nothing is scheduled.
+func (d *Daemon) resetTimer(timer *time.Timer) {
+	name, at, ok := d.queue.Next()
+	if !ok {
+		timer.Stop()
+		return
+	}
+
+	wait := time.Until(at)
+	if wait < 0 {
+		wait = 0
+	}
+	d.logger.Debug("Next renewal check", "profile", name, "next_in", wait.Round(time.Second))
+	timer.Reset(wait)
+}
+
 // Stop signals the daemon to stop.
 func (d *Daemon) Stop() {
 	d.mu.Lock()
@@ -186,217 +325,530 @@ func (d *Daemon) IsRunning() bool {
 	return d.running
 }
 
-// reloadConfig reloads the configuration from disk.
-// Returns the loaded config or the previous config if reload fails.
-func (d *Daemon) reloadConfig() *config.Config {
-	newCfg, err := config.LoadFrom(d.configPath)
-	if err != nil {
-		d.logger.Warn(fmt.Sprintf("Failed to reload config: %v, using previous config", err))
-		return d.config
-	}
-
-	// Update notifier if notification settings changed
-	if newCfg.Daemon.Notifications != d.config.Daemon.Notifications {
-		d.notifier = notify.New(newCfg.Daemon.Notifications)
-		d.logger.Debug("Notification settings updated")
-	}
-
-	// Log if profiles were added or removed
-	oldProfileNames := make(map[string]bool, len(d.config.Connections))
-	for _, conn := range d.config.Connections {
-		oldProfileNames[conn.Name] = true
-	}
+// currentConfig returns the configuration, which may be replaced by a reload
+// while the control socket is serving requests.
+func (d *Daemon) currentConfig() *config.Config {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.config
+}
 
-	for _, conn := range newCfg.Connections {
-		if !oldProfileNames[conn.Name] {
-			d.logger.Info(fmt.Sprintf("Detected new profile: %s", conn.Name))
-		}
-		delete(oldProfileNames, conn.Name) // Remove found profiles
-	}
+// currentNotifier returns the notifier, which may be replaced by a config
+// reload while workers are running.
+func (d *Daemon) currentNotifier() notify.Notifier {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.notifier
+}
 
-	// Remaining profiles in oldProfileNames were removed
-	for name := range oldProfileNames {
-		d.logger.Info(fmt.Sprintf("Profile removed from config: %s", name))
+// notifierDrainTimeout bounds how long the deliveries of a notifier are
+// waited for when the daemon stops or replaces it.
+const notifierDrainTimeout = 10 * time.Second
+
+// drainNotifier waits, within notifierDrainTimeout, for the deliveries of n
+// that run in the background.
+func (d *Daemon) drainNotifier(n notify.Notifier) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifierDrainTimeout)
+	defer cancel()
+	if err := notify.Drain(ctx, n); err != nil {
+		d.logger.Warn("Notifications left undelivered", "error", err)
 	}
-
-	d.config = newCfg
-	return newCfg
 }
 
-// checkAndRenewTokens checks all stored tokens and renews those that need it.
-func (d *Daemon) checkAndRenewTokens(ctx context.Context) {
-	// Reload config to detect new profiles
-	cfg := d.reloadConfig()
-
-	tokensChecked := 0
-	tokensRenewed := 0
-	tokensSkipped := 0
-	profilesWithoutTokens := 0
+// newNotifier creates the notifier for cfg, with opts applied last. Webhook
+// deliveries complete in the background and report their failures to the log.
+func (d *Daemon) newNotifier(cfg *config.Config, opts ...notify.Option) notify.Notifier {
+	opts = append([]notify.Option{notify.WithSyslog(cfg.Daemon.Syslog), notify.WithErrorHandler(func(err error) {
+		d.logger.Warn("Notification failed", "error", err)
+	})}, opts...)
+	return notify.New(cfg.Daemon.Notifications, opts...)
+}
 
-	// Create TokenManager for token operations
+// rescanProfiles brings the renewal queue in line with the configuration and
+// the token store. Only stored metadata is read, so unchanged tokens cost no
+// server requests; profiles whose token changed since they were scheduled
+// (or that have no metadata yet) are rescheduled.
+func (d *Daemon) rescanProfiles(ctx context.Context) {
+	cfg := d.config
 	tm := token.NewTokenManager(ctx, d.store, vault.NewTokenExecutor())
 
+	configured := make(map[string]bool, len(cfg.Connections))
+	tokensManaged := 0
+	now := time.Now()
+
 	for _, conn := range cfg.Connections {
 		prof := types.FromConnection(&conn)
+		configured[conn.Name] = true
 
-		// Check if token exists for this profile
-		if !tm.HasToken(prof) {
-			d.logger.Debug(fmt.Sprintf("Profile %s: no token stored, skipping", conn.Name))
-			profilesWithoutTokens++
+		if d.isInFlight(conn.Name) || d.isPaused(conn.Name) {
+			// In-flight profiles are rescheduled by their worker, paused
+			// ones when they are resumed.
+			tokensManaged++
 			continue
 		}
 
-		tokensChecked++
+		if !tm.HasToken(prof) {
+			d.forgetTokenInfo(conn.Name)
+			if _, ok := d.queue.Tracked(conn.Name); ok {
+				d.logger.Info("Token removed, no longer tracking", "profile", conn.Name)
+				d.queue.Remove(conn.Name)
+				d.resetBackoff(conn.Name)
+			} else {
+				d.logger.Debug("No token stored, skipping", "profile", conn.Name)
+			}
+			continue
+		}
+		if d.checkIdle(ctx, cfg, &conn, prof, now) {
+			continue
+		}
+		tokensManaged++
 
-		// Look up token to get current TTL
-		tok, err := tm.Lookup(prof)
+		meta, err := tm.GetMetadata(prof)
 		if err != nil {
-			d.logger.Error(fmt.Sprintf("Profile %s: error looking up token: %v", conn.Name, err))
-			if d.healthServer != nil {
-				d.healthServer.RecordError()
+			// No metadata yet (e.g. stored by the token helper): check now,
+			// which looks the token up and records its metadata.
+			if _, ok := d.queue.Tracked(conn.Name); !ok {
+				at := now
+				if nextRetry, ok := d.backoffUntil(conn.Name); ok {
+					// Do not hit a failing server again right after a restart.
+					at = nextRetry
+				}
+				d.queue.Schedule(conn.Name, time.Time{}, at)
 			}
 			continue
 		}
 
-		// Check if token needs renewal
-		minTTL := cfg.Daemon.MinRenewTTL
-		threshold := cfg.Daemon.RenewThreshold
-		needsRenewal := tok.NeedsRenewal(threshold, minTTL)
+		d.warnExpiring(cfg, conn.Name, meta, now)
 
-		// Log token status
-		ttlDuration := time.Duration(tok.LeaseDuration) * time.Second
-		if !needsRenewal {
-			d.logger.Info(fmt.Sprintf("Profile %s: token OK (TTL: %s, renewable: %v)", conn.Name, ttlDuration, tok.Renewable))
-			tokensSkipped++
+		expiresAt, tracked := d.queue.Tracked(conn.Name)
+		if tracked && expiresAt.Equal(meta.ExpiresAt) {
 			continue
 		}
+		if tracked {
+			// The token was replaced, e.g. by a new login.
+			d.logger.Info("Token changed, rescheduling", "profile", conn.Name)
+			d.resetBackoff(conn.Name)
+		}
 
-		// Token needs renewal
-		if !tok.Renewable {
-			d.logger.Warn(fmt.Sprintf("Profile %s: token needs renewal but is not renewable (TTL: %s)",
-				conn.Name, ttlDuration))
-			tokensSkipped++
+		tokenStr, err := tm.Get(prof)
+		if err != nil {
+			d.logger.Error("Error reading token", "profile", conn.Name, "error", err)
 			continue
 		}
+		d.scheduleNext(cfg, conn.Name, meta.Token(tokenStr))
+	}
 
-		// Check if we should skip due to backoff from previous failures
-		if d.shouldSkipDueToBackoff(conn.Name) {
-			backoff := d.getBackoff(conn.Name)
-			retryIn := time.Until(backoff.nextRetry).Round(time.Second)
-			d.logger.Info(fmt.Sprintf("Profile %s: skipping renewal due to backoff (retry in %s, TTL: %s)",
-				conn.Name, retryIn, ttlDuration))
-			tokensSkipped++
-			continue
+	// Stop tracking profiles that were removed from the config.
+	for _, name := range d.queue.Profiles() {
+		if !configured[name] {
+			d.queue.Remove(name)
 		}
+	}
+	for _, name := range d.stateProfiles() {
+		if !configured[name] {
+			d.forgetProfile(name)
+		}
+	}
+
+	if hs := d.health(); hs != nil {
+		hs.RecordCheck(tokensManaged)
+	}
+	d.checkServers(ctx, cfg)
+}
 
-		d.logger.Info(fmt.Sprintf("Profile %s: renewing token (current TTL: %s)", conn.Name, ttlDuration))
+// dispatchDueTokens hands every profile whose renewal time has come to a
+// worker. At most cap(sem) workers renew at the same time; the rest wait for
+// a free slot without blocking the run loop.
+func (d *Daemon) dispatchDueTokens(ctx context.Context, sem chan struct{}) {
+	due := d.queue.PopDue(time.Now())
+	if len(due) == 0 {
+		return
+	}
+	d.logger.Debug("Dispatching due profiles", "count", len(due))
+
+	// Workers use the config current at dispatch time; reloads replace
+	// d.config rather than modify it.
+	cfg := d.config
 
-		_, err = tm.Renew(prof, "")
+	for _, name := range due {
+		conn, err := cfg.GetConnection(name)
 		if err != nil {
-			d.logger.Error(fmt.Sprintf("Profile %s: renewal failed: %v", conn.Name, err))
-			d.recordRenewalFailure(conn.Name)
-			if d.healthServer != nil {
-				d.healthServer.RecordError()
-			}
-			// Send failure notification
-			if notifyErr := d.notifier.NotifyFailure(conn.Name, err); notifyErr != nil {
-				d.logger.Debug(fmt.Sprintf("Failed to send notification: %v", notifyErr))
-			}
+			d.queue.Remove(name)
+			continue
+		}
+		if d.isPaused(name) {
+			// Stays tracked but unscheduled until it is resumed.
+			d.logger.Debug("Paused, skipping", "profile", name)
 			continue
 		}
+		prof := types.FromConnection(conn)
+
+		d.setInFlight(name, true)
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			defer d.wakeRunLoop()
+			defer d.setInFlight(name, false)
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
 
-		// Success - reset backoff state
-		d.resetBackoff(conn.Name)
+			d.renewProfile(ctx, cfg, prof)
+		}()
+	}
+}
 
-		// Get new TTL for notification
-		newTTL := ttlDuration // Use current TTL as estimate
-		if newTok, err := tm.Lookup(prof); err == nil {
-			newTTL = time.Duration(newTok.LeaseDuration) * time.Second
-		}
+// renewProfile checks and renews a single profile, bounded by the per-profile
+// timeout and canceled with the daemon context.
+func (d *Daemon) renewProfile(ctx context.Context, cfg *config.Config, prof *types.Profile) {
+	ctx, cancel := context.WithTimeout(ctx, renewTimeout(cfg))
+	defer cancel()
 
-		d.logger.Info(fmt.Sprintf("Profile %s: token renewed successfully (new TTL: %s)", conn.Name, newTTL))
-		tokensRenewed++
-		if d.healthServer != nil {
-			d.healthServer.RecordRenewal()
-		}
+	tm := token.NewTokenManager(ctx, d.store, vault.NewTokenExecutor())
+	d.checkAndRenewToken(ctx, cfg, tm, prof)
+}
 
-		// Send success notification
-		if notifyErr := d.notifier.NotifyRenewal(conn.Name, newTTL); notifyErr != nil {
-			d.logger.Debug(fmt.Sprintf("Failed to send notification: %v", notifyErr))
-		}
+// wakeRunLoop tells the run loop to re-arm its timer without blocking.
+func (d *Daemon) wakeRunLoop() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// setInFlight marks whether a worker is currently renewing profile name.
+func (d *Daemon) setInFlight(name string, inFlight bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if inFlight {
+		d.inFlight[name] = true
+	} else {
+		delete(d.inFlight, name)
 	}
+}
+
+// isInFlight reports whether a worker is currently renewing profile name.
+func (d *Daemon) isInFlight(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight[name]
+}
 
-	// Log summary of the check
-	d.logger.Info(fmt.Sprintf("Check complete: %d tokens checked, %d renewed, %d skipped, %d profiles without tokens",
-		tokensChecked, tokensRenewed, tokensSkipped, profilesWithoutTokens))
+// isPaused reports whether renewals of profile name are paused.
+func (d *Daemon) isPaused(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.paused[name]
+}
 
-	// Record the check with the health server
-	if d.healthServer != nil {
-		d.healthServer.RecordCheck(tokensChecked)
+// takeForceRenew reports whether a renewal of profile name was requested
+// over the control socket, and clears the request.
+func (d *Daemon) takeForceRenew(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	force := d.forceRenew[name]
+	delete(d.forceRenew, name)
+	return force
+}
+
+// setLoginDue records whether the next action for profile name is a new
+// login rather than a renewal.
+func (d *Daemon) setLoginDue(name string, due bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if due {
+		d.loginDue[name] = true
+	} else {
+		delete(d.loginDue, name)
 	}
 }
 
-// getBackoff returns the backoff state for a connection, creating it if needed.
-func (d *Daemon) getBackoff(connName string) *connectionBackoff {
+// isLoginDue reports whether the next action for profile name is a new
+// login rather than a renewal.
+func (d *Daemon) isLoginDue(name string) bool {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	if d.backoffState[connName] == nil {
-		d.backoffState[connName] = &connectionBackoff{}
+	return d.loginDue[name]
+}
+
+// maxConcurrency returns the configured worker limit.
+func maxConcurrency(cfg *config.Config) int {
+	if cfg.Daemon.MaxConcurrency <= 0 {
+		return config.DefaultMaxConcurrency
 	}
-	return d.backoffState[connName]
+	return cfg.Daemon.MaxConcurrency
 }
 
-// shouldSkipDueToBackoff checks if renewal should be skipped due to backoff.
-func (d *Daemon) shouldSkipDueToBackoff(connName string) bool {
-	backoff := d.getBackoff(connName)
-	if backoff.failureCount == 0 {
+// renewTimeout returns the configured per-profile timeout.
+func renewTimeout(cfg *config.Config) time.Duration {
+	if cfg.Daemon.RenewTimeout <= 0 {
+		return config.DefaultRenewTimeout
+	}
+	return cfg.Daemon.RenewTimeout
+}
+
+// checkAndRenewToken renews the token of prof if it needs it and schedules
+// its next check. It reports whether the token was renewed.
+func (d *Daemon) checkAndRenewToken(ctx context.Context, cfg *config.Config, tm *token.TokenManager, prof *types.Profile) bool {
+	name := prof.Name
+
+	if !tm.HasToken(prof) {
+		d.logger.Debug("No token stored, skipping", "profile", name)
+		d.queue.Remove(name)
+		d.forgetTokenInfo(name)
+		return false
+	}
+
+	// Get current TTL from stored metadata, looking it up if needed
+	tok, err := d.currentToken(tm, prof)
+	if err != nil {
+		if shuttingDown(ctx) {
+			return false
+		}
+		err = timeoutErr(ctx, err)
+		if autoLoginConn(cfg, name) != nil {
+			// The token may have been revoked or expired; a new login
+			// replaces it, or fails the same way if the server is down.
+			d.logger.Warn("Error looking up token", "profile", name, "error", err)
+			return d.autoLogin(ctx, cfg, tm, prof)
+		}
+		d.logger.Error("Error looking up token", "profile", name, "error", err)
+		d.queue.Reschedule(name, d.recordRenewalFailure(cfg, name, err))
+		return false
+	}
+
+	ttlDuration := remainingTTL(tok)
+	now := time.Now()
+	// The time scheduled for this token may be before the threshold because
+	// of jitter; once it is reached the renewal or login is due anyway.
+	due := d.queue.RenewalDue(name, tok.ExpiresAt, now)
+	reloginAt, relogin := d.reloginTime(cfg, name, tok, now)
+	force := d.takeForceRenew(name)
+	relogin = relogin && (!reloginAt.After(now) || due && d.isLoginDue(name) || force && !tok.Renewable)
+	if force && !tok.Renewable && !relogin {
+		d.logger.Warn("Token is not renewable, ignoring renewal request", "profile", name)
+		force = false
+	}
+	if !force && !relogin && !(due && tok.Renewable) && !tok.NeedsRenewal(cfg.Daemon.RenewThreshold, cfg.Daemon.MinRenewTTL) {
+		d.logger.Info("Token OK", "profile", name, "ttl", ttlDuration, "renewable", tok.Renewable)
+		d.scheduleNext(cfg, name, tok)
+		return false
+	}
+
+	// Check if we should skip due to backoff from previous failures
+	if nextRetry, ok := d.backoffUntil(name); ok {
+		retryIn := time.Until(nextRetry).Round(time.Second)
+		d.logger.Info("Skipping renewal due to backoff", "profile", name, "retry_in", retryIn, "ttl", ttlDuration)
+		d.queue.Schedule(name, tok.ExpiresAt, nextRetry)
 		return false
 	}
-	return time.Now().Before(backoff.nextRetry)
+
+	if relogin {
+		return d.autoLogin(ctx, cfg, tm, prof)
+	}
+
+	d.logger.Info("Renewing token", "profile", name, "ttl", ttlDuration)
+
+	start := time.Now()
+	renewed, err := tm.Renew(prof, "")
+	d.observeLatency(opRenewal, name, start, err)
+	if err != nil {
+		if shuttingDown(ctx) {
+			// Renew again right after the next start.
+			d.queue.Schedule(name, tok.ExpiresAt, time.Now())
+			return false
+		}
+		err = timeoutErr(ctx, err)
+		d.logger.Error("Renewal failed", "profile", name, "event", notify.EventFailure, "error", err)
+		d.queue.Schedule(name, tok.ExpiresAt, d.recordRenewalFailure(cfg, name, err))
+		d.notifyFailure(ctx, prof, err)
+		d.runHooks(ctx, cfg, config.HookFailure, prof, ttlDuration, err)
+		return false
+	}
+
+	// Success - reset backoff state
+	d.recordRenewalSuccess(name)
+
+	newTTL := time.Duration(renewed.LeaseDuration) * time.Second
+
+	d.logger.Info("Token renewed", "profile", name, "event", notify.EventRenewal, "ttl", newTTL)
+	// Send success notification
+	if notifyErr := d.currentNotifier().NotifyRenewal(name, newTTL); notifyErr != nil {
+		d.logger.Debug("Failed to send notification", "profile", name, "event", notify.EventRenewal, "error", notifyErr)
+	}
+	d.runHooks(ctx, cfg, config.HookRenewal, prof, newTTL, nil)
+
+	d.scheduleNext(cfg, name, renewed)
+	return true
+}
+
+// notifyFailure sends a notification about a failed renewal or login of
+// prof. If the server turns out to be sealed, that is notified instead, since
+// neither retries nor a new login help until it is unsealed.
+func (d *Daemon) notifyFailure(ctx context.Context, prof *types.Profile, err error) {
+	var notifyErr error
+	health := vault.NewHealthExecutor().CheckHealth(ctx, prof)
+	if hs := d.health(); hs != nil {
+		hs.SetServerHealth(prof.Name, health.Status)
+	}
+	if health.Status == "sealed" {
+		d.logger.Warn("Server is sealed", "profile", prof.Name, "event", notify.EventSealed, "address", prof.Address)
+		notifyErr = d.currentNotifier().NotifySealed(prof.Name, prof.Address)
+	} else {
+		notifyErr = d.currentNotifier().NotifyFailure(prof.Name, err)
+	}
+	if notifyErr != nil {
+		d.logger.Debug("Failed to send notification", "profile", prof.Name, "error", notifyErr)
+	}
+}
+
+// scheduleNext schedules the next renewal of tok for profile name. Tokens
+// that cannot be renewed stay tracked so they are not re-evaluated until
+// they change.
+func (d *Daemon) scheduleNext(cfg *config.Config, name string, tok *types.Token) {
+	d.setTokenInfo(name, tok)
+	now := time.Now()
+	expired := !tok.ExpiresAt.IsZero() && !tok.ExpiresAt.After(now)
+	at, ok := nextRenewalTime(tok, cfg.Daemon.RenewThreshold, cfg.Daemon.MinRenewTTL, now)
+
+	// Profiles with auto_login log in again instead of waiting for the user.
+	reloginAt, relogin := d.reloginTime(cfg, name, tok, now)
+	relogin = relogin && (!ok || reloginAt.Before(at))
+	if d.setNeedsLogin(name, expired && !relogin) {
+		d.logger.Warn("Token expired, login required", "profile", name, "event", notify.EventLoginRequired)
+		if notifyErr := d.currentNotifier().NotifyLoginRequired(name); notifyErr != nil {
+			d.logger.Debug("Failed to send notification", "profile", name, "event", notify.EventLoginRequired, "error", notifyErr)
+		}
+	}
+	d.setLoginDue(name, relogin)
+	if relogin {
+		at = withJitter(reloginAt, now)
+		d.queue.ScheduleRenewal(name, tok.ExpiresAt, at)
+		d.logger.Debug("Next login scheduled", "profile", name, "next_in", at.Sub(now).Round(time.Second))
+		return
+	}
+
+	if !ok {
+		switch {
+		case tok.ExpiresAt.IsZero():
+			d.logger.Debug("Token does not expire, nothing to schedule", "profile", name)
+		case expired:
+			d.logger.Warn("Token expired, login required", "profile", name, "expired_at", tok.ExpiresAt)
+		default:
+			d.logger.Warn("Token is not renewable", "profile", name, "ttl", remainingTTL(tok))
+		}
+		d.queue.Schedule(name, tok.ExpiresAt, time.Time{})
+		return
+	}
+
+	at = withJitter(at, now)
+	d.queue.ScheduleRenewal(name, tok.ExpiresAt, at)
+	d.logger.Debug("Next renewal scheduled", "profile", name, "next_in", at.Sub(now).Round(time.Second))
+}
+
+// currentToken returns the token state for prof from stored metadata while
+// it is still valid, falling back to a server lookup (which refreshes it).
+func (d *Daemon) currentToken(tm *token.TokenManager, prof *types.Profile) (*types.Token, error) {
+	if meta, err := tm.GetMetadata(prof); err == nil && meta.TTL() > 0 {
+		tokenStr, err := tm.Get(prof)
+		if err != nil {
+			return nil, err
+		}
+		return meta.Token(tokenStr), nil
+	}
+	start := time.Now()
+	tok, err := tm.Lookup(prof)
+	d.observeLatency(opLookup, prof.Name, start, err)
+	return tok, err
+}
+
+// shuttingDown reports whether ctx was canceled because the daemon is
+// stopping, as opposed to timing out.
+func shuttingDown(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// timeoutErr annotates err if it was caused by the per-profile timeout.
+func timeoutErr(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out: %w", err)
+	}
+	return err
+}
+
+// remainingTTL returns the time left until tok expires, rounded to seconds.
+func remainingTTL(tok *types.Token) time.Duration {
+	if tok.ExpiresAt.IsZero() {
+		return 0
+	}
+	return time.Until(tok.ExpiresAt).Round(time.Second)
 }
 
-// recordRenewalFailure records a renewal failure and calculates next retry time.
-func (d *Daemon) recordRenewalFailure(connName string) {
-	backoff := d.getBackoff(connName)
-	backoff.failureCount++
+// backoffUntil returns the next retry time if connName is backing off after
+// previous failures.
+func (d *Daemon) backoffUntil(connName string) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ps := d.states[connName]
+	if ps == nil || ps.FailureCount == 0 || !time.Now().Before(ps.NextRetry) {
+		return time.Time{}, false
+	}
+	return ps.NextRetry, true
+}
 
-	// Calculate backoff duration: initialBackoff * 2^(failureCount-1)
-	initialBackoff := d.config.Daemon.InitialRetryBackoff
+// recordRenewalFailure records a failed check or renewal and returns the
+// time of the next retry.
+func (d *Daemon) recordRenewalFailure(cfg *config.Config, connName string, cause error) time.Time {
+	initialBackoff := cfg.Daemon.InitialRetryBackoff
 	if initialBackoff == 0 {
 		initialBackoff = 30 * time.Second
 	}
-	maxBackoff := d.config.Daemon.MaxRetryBackoff
+	maxBackoff := cfg.Daemon.MaxRetryBackoff
 	if maxBackoff == 0 {
 		maxBackoff = 15 * time.Minute
 	}
 
-	// Calculate exponential backoff
-	multiplier := 1 << (backoff.failureCount - 1) // 2^(n-1)
-	backoffDuration := time.Duration(multiplier) * initialBackoff
-	if backoffDuration > maxBackoff {
-		backoffDuration = maxBackoff
+	d.mu.Lock()
+	ps := d.profileStateLocked(connName)
+	ps.FailureCount++
+	ps.Failures++
+	ps.LastError = cause.Error()
+	ps.LastErrorAt = time.Now().UTC()
+	d.totals.Errors++
+	failureCount := ps.FailureCount
+
+	// Calculate exponential backoff: initialBackoff * 2^(failureCount-1), doubling
+	// only until the maximum is reached so long failure streaks cannot overflow
+	backoffDuration := initialBackoff
+	for i := 1; i < failureCount && backoffDuration < maxBackoff; i++ {
+		backoffDuration *= 2
 	}
+	backoffDuration = min(backoffDuration, maxBackoff)
 
-	backoff.nextRetry = time.Now().Add(backoffDuration)
-	d.logger.Warn(fmt.Sprintf("Renewal failed for %s (attempt %d), will retry in %s",
-		connName, backoff.failureCount, backoffDuration))
-}
+	// Wall clock time, so backoff also elapses while the system is suspended.
+	ps.NextRetry = time.Now().Add(backoffDuration).Round(0)
+	nextRetry := ps.NextRetry
+	d.mu.Unlock()
 
-// resetBackoff resets the backoff state for a connection after successful renewal.
-func (d *Daemon) resetBackoff(connName string) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	delete(d.backoffState, connName)
+	if hs := d.health(); hs != nil {
+		hs.RecordError()
+	}
+	d.saveState()
+
+	d.logger.Warn("Renewal failed, will retry", "profile", connName, "attempt", failureCount,
+		"retry_in", backoffDuration, "error", cause)
+	return nextRetry
 }
 
 // writePIDFile writes the current process ID to the configured PID file.
 // It uses exclusive file creation to prevent multiple instances from starting simultaneously.
 func (d *Daemon) writePIDFile() error {
-	pidFile := d.config.Daemon.PIDFile
-	if pidFile == "" {
-		paths := config.GetPaths()
-		pidFile = filepath.Join(paths.DataDir, "patrol.pid")
-	}
+	pidFile := pidFilePath(d.config)
 
 	// Ensure directory exists
 	dir := filepath.Dir(pidFile)
@@ -435,6 +887,7 @@ func (d *Daemon) writePIDFile() error {
 
 		// Successfully created the file - write PID
 		defer file.Close()
+		d.pidFile = pidFile
 
 		pid := os.Getpid()
 		if _, err := file.WriteString(strconv.Itoa(pid)); err != nil {
@@ -454,23 +907,25 @@ func (d *Daemon) writePIDFile() error {
 	return fmt.Errorf("failed to acquire daemon lock after %d attempts", maxRetries)
 }
 
-// removePIDFile removes the PID file.
+// removePIDFile removes the PID file written at startup, even if the
+// configured path has changed since.
 func (d *Daemon) removePIDFile() {
-	pidFile := d.config.Daemon.PIDFile
-	if pidFile == "" {
-		paths := config.GetPaths()
-		pidFile = filepath.Join(paths.DataDir, "patrol.pid")
+	if d.pidFile != "" {
+		_ = os.Remove(d.pidFile)
 	}
-	_ = os.Remove(pidFile)
+}
+
+// pidFilePath returns the configured PID file path.
+func pidFilePath(cfg *config.Config) string {
+	if cfg.Daemon.PIDFile != "" {
+		return cfg.Daemon.PIDFile
+	}
+	return filepath.Join(config.GetPaths().DataDir, "patrol.pid")
 }
 
 // GetPID reads the PID from the PID file, if it exists.
 func GetPID(cfg *config.Config) (int, error) {
-	pidFile := cfg.Daemon.PIDFile
-	if pidFile == "" {
-		paths := config.GetPaths()
-		pidFile = filepath.Join(paths.DataDir, "patrol.pid")
-	}
+	pidFile := pidFilePath(cfg)
 
 	// #nosec G304 - pidFile is from config paths (controlled)
 	data, err := os.ReadFile(pidFile)
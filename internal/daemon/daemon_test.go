@@ -0,0 +1,263 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/tokenstore"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// renewHandler answers renew-self requests like Vault, after delay.
+func renewHandler(delay time.Duration, active, peak *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if active != nil {
+			n := atomic.AddInt32(active, 1)
+			defer atomic.AddInt32(active, -1)
+			for {
+				p := atomic.LoadInt32(peak)
+				if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+					break
+				}
+			}
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"auth":{"client_token":"hvs.renewed","lease_duration":3600,"renewable":true}}`)
+	}
+}
+
+// newTestDaemon creates a daemon with one profile per server address, each
+// holding a token that is due for renewal.
+func newTestDaemon(t *testing.T, addrs []string) (*Daemon, *config.Config) {
+	t.Helper()
+
+	store, err := tokenstore.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.Daemon.InitialRetryBackoff = time.Minute
+	for i, addr := range addrs {
+		name := fmt.Sprintf("profile-%d", i)
+		cfg.Connections = append(cfg.Connections, config.Connection{Name: name, Address: addr})
+
+		prof := &types.Profile{Name: name, Address: addr}
+		if err := store.Set(prof, "hvs.token"); err != nil {
+			t.Fatal(err)
+		}
+		meta := &types.TokenMetadata{LeaseDuration: 3600, Renewable: true, ExpiresAt: time.Now().Add(time.Minute)}
+		if err := store.SetMetadata(prof, meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := New(cfg, store)
+	d.SetLogger(&Logger{writer: io.Discard})
+	d.state = &stateStore{path: filepath.Join(t.TempDir(), StateFileName)}
+	d.usage = token.NewUsageLog(filepath.Join(t.TempDir(), token.UsageDirName))
+	return d, cfg
+}
+
+func TestRenewProfileTimeout(t *testing.T) {
+	server := httptest.NewServer(renewHandler(time.Minute, nil, nil))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL})
+	cfg.Daemon.RenewTimeout = 100 * time.Millisecond
+	prof := types.FromConnection(&cfg.Connections[0])
+
+	start := time.Now()
+	d.renewProfile(context.Background(), cfg, prof)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("renewProfile() took %s, expected the timeout to stop it", elapsed)
+	}
+
+	nextRetry, ok := d.backoffUntil(prof.Name)
+	if !ok {
+		t.Fatal("timed out renewal should record a backoff")
+	}
+	_, at, ok := d.queue.Next()
+	if !ok || !at.Equal(nextRetry) {
+		t.Errorf("profile scheduled at %v, want retry at %v", at, nextRetry)
+	}
+}
+
+func TestDispatchDueTokensBoundedConcurrency(t *testing.T) {
+	var active, peak int32
+	handler := renewHandler(100*time.Millisecond, &active, &peak)
+
+	addrs := make([]string, 6)
+	for i := range addrs {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		addrs[i] = server.URL
+	}
+
+	d, cfg := newTestDaemon(t, addrs)
+	cfg.Daemon.MaxConcurrency = 2
+
+	now := time.Now()
+	for _, conn := range cfg.Connections {
+		d.queue.Schedule(conn.Name, time.Time{}, now)
+	}
+
+	sem := make(chan struct{}, maxConcurrency(cfg))
+	d.dispatchDueTokens(context.Background(), sem)
+	d.workers.Wait()
+
+	if got := atomic.LoadInt32(&peak); got > 2 {
+		t.Errorf("peak concurrent renewals = %d, want at most 2", got)
+	}
+	for _, conn := range cfg.Connections {
+		if _, ok := d.backoffUntil(conn.Name); ok {
+			t.Errorf("profile %s failed to renew", conn.Name)
+		}
+		if d.isInFlight(conn.Name) {
+			t.Errorf("profile %s still marked in flight", conn.Name)
+		}
+	}
+	if name, at, ok := d.queue.Next(); !ok || time.Until(at) < 30*time.Minute {
+		t.Errorf("next renewal %s at %v, expected all profiles rescheduled after renewal", name, at)
+	}
+}
+
+func TestDispatchDueTokensSlowServerDoesNotBlockOthers(t *testing.T) {
+	slow := httptest.NewServer(renewHandler(time.Minute, nil, nil))
+	defer slow.Close()
+	fast := httptest.NewServer(renewHandler(0, nil, nil))
+	defer fast.Close()
+
+	d, cfg := newTestDaemon(t, []string{slow.URL, fast.URL})
+	cfg.Daemon.MaxConcurrency = 2
+
+	now := time.Now()
+	d.queue.Schedule("profile-0", time.Time{}, now)
+	d.queue.Schedule("profile-1", time.Time{}, now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	sem := make(chan struct{}, maxConcurrency(cfg))
+	d.dispatchDueTokens(ctx, sem)
+
+	// Wait for the fast profile to be renewed and rescheduled.
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, at, ok := d.queue.Next(); ok && time.Until(at) > 30*time.Minute {
+			break
+		}
+		select {
+		case <-d.wake:
+		case <-deadline:
+			t.Fatal("fast profile was not renewed while the slow one was pending")
+		}
+	}
+
+	if !d.isInFlight("profile-0") {
+		t.Error("slow profile should still be in flight")
+	}
+
+	// Canceling the daemon context stops the pending renewal without a backoff.
+	cancel()
+	d.workers.Wait()
+	if _, ok := d.backoffUntil("profile-0"); ok {
+		t.Error("canceled renewal should not be recorded as a failure")
+	}
+}
+
+func TestScheduleNextNotifiesLoginRequired(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	notifier := &recordingNotifier{}
+	d.notifier = notifier
+
+	expired := &types.Token{ClientToken: "hvs.token", LeaseDuration: 3600, Renewable: true, ExpiresAt: time.Now().Add(-time.Minute)}
+	d.scheduleNext(cfg, "profile-0", expired)
+	d.scheduleNext(cfg, "profile-0", expired)
+
+	if len(notifier.logins) != 1 || notifier.logins[0] != "profile-0" {
+		t.Errorf("login required notifications = %v, want one for profile-0", notifier.logins)
+	}
+	if st := d.profileStatus("profile-0"); st.State != ProfileStateLoginRequired {
+		t.Errorf("profile-0 state = %q, want %q", st.State, ProfileStateLoginRequired)
+	}
+}
+
+func TestRenewalFailureOnSealedServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL, "http://127.0.0.1:1"})
+	notifier := &recordingNotifier{}
+	d.notifier = notifier
+
+	for i := range cfg.Connections {
+		d.renewProfile(context.Background(), cfg, types.FromConnection(&cfg.Connections[i]))
+	}
+
+	if len(notifier.sealed) != 1 || notifier.sealed[0] != "profile-0" {
+		t.Errorf("sealed notifications = %v, want one for profile-0", notifier.sealed)
+	}
+	if len(notifier.failures) != 1 || notifier.failures[0] != "profile-1" {
+		t.Errorf("failure notifications = %v, want one for the unreachable profile-1", notifier.failures)
+	}
+}
+
+func TestJitteredRenewalRenewsOnFirstDispatch(t *testing.T) {
+	var renewals int32
+	handler := renewHandler(0, nil, nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&renewals, 1)
+		handler(w, r)
+	}))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL, server.URL})
+	now := time.Now()
+	for i := range cfg.Connections {
+		prof := types.FromConnection(&cfg.Connections[i])
+		// Not due by threshold or minimum TTL for another five minutes.
+		meta := &types.TokenMetadata{LeaseDuration: 3600, Renewable: true, ExpiresAt: now.Add(20 * time.Minute)}
+		if err := d.store.SetMetadata(prof, meta); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			// Jitter moved the scheduled renewal before the threshold.
+			d.queue.ScheduleRenewal(prof.Name, meta.ExpiresAt, now)
+		} else {
+			// A plain check, e.g. after the profile was resumed.
+			d.queue.Schedule(prof.Name, meta.ExpiresAt, now)
+		}
+	}
+
+	sem := make(chan struct{}, maxConcurrency(cfg))
+	d.dispatchDueTokens(context.Background(), sem)
+	d.workers.Wait()
+
+	if got := atomic.LoadInt32(&renewals); got != 1 {
+		t.Fatalf("renew requests = %d, want 1 for the jittered profile only", got)
+	}
+	if _, at, _ := d.queue.Entry("profile-0"); time.Until(at) < 30*time.Minute {
+		t.Errorf("profile-0 next renewal at %v, expected it rescheduled after renewal", at)
+	}
+	if _, at, _ := d.queue.Entry("profile-1"); time.Until(at) > 10*time.Minute {
+		t.Errorf("profile-1 next renewal at %v, expected it kept at its threshold", at)
+	}
+}
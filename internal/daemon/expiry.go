@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"slices"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/notify"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// hardExpiryTolerance is how far the forecast hard expiry of a token may move
+// between checks while still being taken as the same token. Lease durations
+// are only known to the second and renewals of a capped token shift it a bit.
+const hardExpiryTolerance = time.Minute
+
+// expiryWarning is the last expiry warning sent for a profile.
+type expiryWarning struct {
+	hardExpiry time.Time
+	// before is the shortest warning time already used for this token.
+	before time.Duration
+}
+
+// expiryWarningTimes returns the configured warning times, longest first.
+func expiryWarningTimes(cfg *config.Config) []time.Duration {
+	times := make([]time.Duration, 0, len(cfg.Daemon.Notifications.ExpiryWarnings))
+	for _, before := range cfg.Daemon.Notifications.ExpiryWarnings {
+		if before > 0 {
+			times = append(times, before)
+		}
+	}
+	slices.Sort(times)
+	slices.Reverse(times)
+	return times
+}
+
+// warnExpiring warns when the token of profile name comes within one of the
+// configured warning times of its hard expiry, the point after which renewals
+// can no longer keep it alive. Each warning time is used once per token; a
+// token first seen close to its end gets a single warning. Profiles with
+// auto_login are skipped, since the daemon logs them in again.
+func (d *Daemon) warnExpiring(cfg *config.Config, name string, meta *types.TokenMetadata, now time.Time) {
+	hardExpiry, ok := meta.HardExpiry()
+	if !ok || autoLoginConn(cfg, name) != nil {
+		return
+	}
+	remaining := hardExpiry.Sub(now)
+	if remaining <= 0 {
+		return
+	}
+
+	var due time.Duration
+	for _, before := range expiryWarningTimes(cfg) {
+		if remaining <= before {
+			due = before
+		}
+	}
+	if due == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	last, warned := d.warned[name]
+	if warned && (hardExpiry.Sub(last.hardExpiry)).Abs() < hardExpiryTolerance && last.before <= due {
+		d.mu.Unlock()
+		return
+	}
+	d.warned[name] = expiryWarning{hardExpiry: hardExpiry, before: due}
+	d.mu.Unlock()
+
+	d.logger.Warn("Token cannot be renewed further, login required before it expires",
+		"profile", name, "event", notify.EventExpiring, "expires_at", hardExpiry, "ttl", remaining.Round(time.Second))
+	if notifyErr := d.currentNotifier().NotifyExpiring(name, remaining.Round(time.Second)); notifyErr != nil {
+		d.logger.Debug("Failed to send notification", "profile", name, "event", notify.EventExpiring, "error", notifyErr)
+	}
+}
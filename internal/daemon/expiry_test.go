@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestWarnExpiring(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1", "http://127.0.0.1:2"})
+	notifier := &recordingNotifier{}
+	d.notifier = notifier
+
+	base := time.Now()
+	meta := &types.TokenMetadata{LeaseDuration: 7200, Renewable: false, ExpiresAt: base.Add(50 * time.Minute)}
+
+	// Checks at increasing times: only crossing a warning time notifies.
+	for _, offset := range []time.Duration{0, 5 * time.Minute, 41 * time.Minute, 45 * time.Minute, 51 * time.Minute} {
+		d.warnExpiring(cfg, "profile-0", meta, base.Add(offset))
+	}
+	want := []time.Duration{50 * time.Minute, 9 * time.Minute}
+	if len(notifier.expiring) != len(want) {
+		t.Fatalf("warnings = %v, want %v", notifier.expiring, want)
+	}
+	for i := range want {
+		if notifier.expiring[i] != want[i] {
+			t.Errorf("warning %d = %s, want %s", i, notifier.expiring[i], want[i])
+		}
+	}
+
+	// A new token with its own hard expiry is warned about again.
+	renewed := &types.TokenMetadata{
+		LeaseDuration:  3600,
+		Renewable:      true,
+		IssueTime:      base,
+		ExplicitMaxTTL: 7200,
+		ExpiresAt:      base.Add(time.Hour),
+	}
+	d.warnExpiring(cfg, "profile-0", renewed, base.Add(time.Hour+55*time.Minute))
+	if len(notifier.expiring) != 3 {
+		t.Errorf("got %d warnings, want a warning for the new token", len(notifier.expiring))
+	}
+
+	// Profiles that log in again on their own are not warned about.
+	cfg.Connections[1].AutoLogin = config.AutoLoginConfig{Method: "cert"}
+	d.warnExpiring(cfg, "profile-1", meta, base)
+	if len(notifier.expiring) != 3 {
+		t.Errorf("got %d warnings, want none for a profile with auto_login", len(notifier.expiring))
+	}
+}
+
+func TestExpiryWarningTimes(t *testing.T) {
+	cfg := config.Default()
+	cfg.Daemon.Notifications.ExpiryWarnings = []time.Duration{10 * time.Minute, 0, 24 * time.Hour, time.Hour}
+
+	got := expiryWarningTimes(cfg)
+	want := []time.Duration{24 * time.Hour, time.Hour, 10 * time.Minute}
+	if len(got) != len(want) {
+		t.Fatalf("expiryWarningTimes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expiryWarningTimes()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
@@ -1,21 +1,31 @@
 package daemon
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/xabinapal/patrol/internal/config"
 	"github.com/xabinapal/patrol/internal/utils"
 )
 
 // HealthServer provides an HTTP health endpoint.
 type HealthServer struct {
-	addr   string
-	server *http.Server
+	addr     string
+	cfg      config.HealthConfig
+	server   *http.Server
+	listener net.Listener
 
 	mu            sync.RWMutex
 	startTime     time.Time
@@ -23,6 +33,10 @@ type HealthServer struct {
 	tokensManaged int
 	renewalsTotal int
 	errorsTotal   int
+
+	profiles  func() []ProfileMetrics // per-profile state, see SetProfileSource
+	latencies map[latencyKey]*histogram
+	servers   map[string]string // server health status per profile
 }
 
 // HealthStatus represents the health endpoint response.
@@ -39,9 +53,38 @@ type HealthStatus struct {
 // DefaultHealthAddr is the default address for the health server.
 const DefaultHealthAddr = "localhost:9090"
 
+// unixAddrPrefix marks health server addresses that are Unix socket paths.
+const unixAddrPrefix = "unix:"
+
+// HealthOption configures a HealthServer.
+type HealthOption func(*HealthServer)
+
+// WithHealthConfig secures the health server with TLS and authentication.
+func WithHealthConfig(cfg config.HealthConfig) HealthOption {
+	return func(h *HealthServer) {
+		h.cfg = cfg
+	}
+}
+
 // NewHealthServer creates a new health server.
 // For security, addresses without an explicit host default to localhost.
-func NewHealthServer(addr string) *HealthServer {
+func NewHealthServer(addr string, opts ...HealthOption) *HealthServer {
+	h := &HealthServer{
+		addr:      normalizeHealthAddr(addr),
+		startTime: time.Now(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// normalizeHealthAddr makes addresses without an explicit host listen on localhost.
+// Unix socket addresses are written as unix:<path>.
+func normalizeHealthAddr(addr string) string {
+	if path, ok := strings.CutPrefix(addr, unixAddrPrefix); ok {
+		return unixAddrPrefix + strings.TrimPrefix(path, "//")
+	}
 	// Security: Default to localhost:9090 if empty
 	if addr == "" {
 		addr = DefaultHealthAddr
@@ -54,11 +97,7 @@ func NewHealthServer(addr string) *HealthServer {
 	if !strings.Contains(addr, ":") {
 		addr = "localhost:" + addr
 	}
-
-	return &HealthServer{
-		addr:      addr,
-		startTime: time.Now(),
-	}
+	return addr
 }
 
 // securityHeaders adds security headers to HTTP responses.
@@ -72,35 +111,271 @@ func securityHeaders(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// Start starts the health server.
+// Addr returns the address the health server listens on.
+func (h *HealthServer) Addr() string {
+	return h.addr
+}
+
+// URL returns the base URL of the health server, or unix:<path> for a Unix
+// socket.
+func (h *HealthServer) URL() string {
+	if strings.HasPrefix(h.addr, unixAddrPrefix) {
+		return h.addr
+	}
+	if h.cfg.TLSEnabled() {
+		return "https://" + h.addr
+	}
+	return "http://" + h.addr
+}
+
+// Start starts the health server. It returns an error if the address cannot
+// be listened on or the TLS and authentication settings cannot be loaded.
 func (h *HealthServer) Start() error {
+	listener, token, err := listenHealth(h.addr, h.cfg)
+	if err != nil {
+		return err
+	}
+	h.serve(listener, token)
+	return nil
+}
+
+// Restart moves the health server to addr, applying opts and keeping its
+// counters. The new settings are loaded and a new address is listened on
+// before the old one is released, so on error the server keeps running where
+// it was; the same address can only be listened on again once released, and
+// the server is left stopped if that fails.
+func (h *HealthServer) Restart(addr string, opts ...HealthOption) error {
+	addr = normalizeHealthAddr(addr)
+	next := &HealthServer{cfg: h.cfg}
+	for _, opt := range opts {
+		opt(next)
+	}
+
+	token, tlsConfig, err := loadHealthSettings(next.cfg)
+	if err != nil {
+		return err
+	}
+
+	if addr == h.addr {
+		if err := h.Stop(); err != nil {
+			return err
+		}
+		listener, err := listenHealthAddr(addr, tlsConfig)
+		if err != nil {
+			h.server = nil
+			return err
+		}
+		h.cfg = next.cfg
+		h.serve(listener, token)
+		return nil
+	}
+
+	listener, err := listenHealthAddr(addr, tlsConfig)
+	if err != nil {
+		return err
+	}
+	if err := h.Stop(); err != nil {
+		_ = listener.Close()
+		return err
+	}
+	h.addr = addr
+	h.cfg = next.cfg
+	h.serve(listener, token)
+	return nil
+}
+
+// running reports whether the health server is serving.
+func (h *HealthServer) running() bool {
+	return h.server != nil
+}
+
+// listenHealth listens on addr, over TLS if configured, and returns the
+// bearer token clients must send, if any.
+func listenHealth(addr string, cfg config.HealthConfig) (net.Listener, string, error) {
+	token, tlsConfig, err := loadHealthSettings(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	listener, err := listenHealthAddr(addr, tlsConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	return listener, token, nil
+}
+
+// loadHealthSettings validates cfg and loads the bearer token and, if TLS is
+// enabled, the TLS configuration.
+func loadHealthSettings(cfg config.HealthConfig) (string, *tls.Config, error) {
+	if err := cfg.Validate(); err != nil {
+		return "", nil, err
+	}
+	token, err := loadHealthToken(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.TLSEnabled() {
+		if tlsConfig, err = healthTLSConfig(cfg); err != nil {
+			return "", nil, err
+		}
+	}
+	return token, tlsConfig, nil
+}
+
+// listenHealthAddr listens on addr, over TLS if tlsConfig is not nil.
+func listenHealthAddr(addr string, tlsConfig *tls.Config) (net.Listener, error) {
+	var listener net.Listener
+	var err error
+	if path, ok := strings.CutPrefix(addr, unixAddrPrefix); ok {
+		listener, err = listenUnix(path)
+	} else if listener, err = net.Listen("tcp", addr); err != nil {
+		err = fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	return listener, nil
+}
+
+// listenUnix listens on a Unix socket at path, readable only by the user,
+// replacing a socket left behind by a previous run.
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create health socket directory: %w", err)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("failed to listen on %s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale health socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set health socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// healthTLSConfig loads the server certificate and, for mTLS, the CA client
+// certificates are verified against.
+func healthTLSConfig(cfg config.HealthConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("health: failed to load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCA != "" {
+		// #nosec G304 - path comes from the user's daemon configuration
+		pem, err := os.ReadFile(cfg.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("health: failed to read client_ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("health: no certificates found in %s", cfg.ClientCA)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// loadHealthToken returns the bearer token clients must send, or "" if
+// token authentication is disabled.
+func loadHealthToken(cfg config.HealthConfig) (string, error) {
+	switch {
+	case cfg.TokenFile != "":
+		// #nosec G304 - path comes from the user's daemon configuration
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("health: failed to read token_file: %w", err)
+		}
+		token := string(bytes.TrimSpace(data))
+		if token == "" {
+			return "", fmt.Errorf("health: token_file %s is empty", cfg.TokenFile)
+		}
+		return token, nil
+	case cfg.TokenEnv != "":
+		token := os.Getenv(cfg.TokenEnv)
+		if token == "" {
+			return "", fmt.Errorf("health: environment variable %s is not set", cfg.TokenEnv)
+		}
+		return token, nil
+	}
+	return "", nil
+}
+
+// requireToken rejects requests that do not carry token as bearer token.
+func requireToken(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="patrol"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve serves the health endpoints on listener in the background, requiring
+// token as bearer token if it is not empty.
+func (h *HealthServer) serve(listener net.Listener, token string) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", securityHeaders(h.handleHealth))
+	mux.HandleFunc("/livez", securityHeaders(h.handleLive))
+	mux.HandleFunc("/readyz", securityHeaders(h.handleReady))
 	mux.HandleFunc("/metrics", securityHeaders(h.handleMetrics))
 	mux.HandleFunc("/", securityHeaders(h.handleRoot))
 
-	h.server = &http.Server{
+	var handler http.Handler = mux
+	if token != "" {
+		handler = requireToken(token, mux)
+	}
+
+	server := &http.Server{
 		Addr:         h.addr,
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 5 * time.Second,
 	}
+	h.server = server
+	h.listener = listener
 
 	go func() {
-		if err := h.server.ListenAndServe(); err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
 			fmt.Printf("Health server error: %v\n", err)
 		}
 	}()
-
-	return nil
 }
 
-// Stop stops the health server.
+// Stop stops the health server. The listener is closed before returning,
+// which also removes a Unix socket, so its address can be listened on again.
 func (h *HealthServer) Stop() error {
 	if h.server != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		return h.server.Shutdown(ctx)
+		err := h.server.Shutdown(ctx)
+		// Shutdown only closes the listener once serving started.
+		h.listener.Close() //nolint:errcheck // already closed by Shutdown
+		return err
 	}
 	return nil
 }
@@ -120,6 +395,14 @@ func (h *HealthServer) RecordRenewal() {
 	h.renewalsTotal++
 }
 
+// SetTotals sets the cumulative counters, restored from a previous run.
+func (h *HealthServer) SetTotals(renewals, errors int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.renewalsTotal = renewals
+	h.errorsTotal = errors
+}
+
 // RecordError records an error.
 func (h *HealthServer) RecordError() {
 	h.mu.Lock()
@@ -136,6 +419,8 @@ func (h *HealthServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 <h1>Patrol Daemon</h1>
 <ul>
 <li><a href="/health">Health Status (JSON)</a></li>
+<li><a href="/livez">Liveness</a></li>
+<li><a href="/readyz">Readiness (JSON)</a></li>
 <li><a href="/metrics">Metrics (Prometheus)</a></li>
 </ul>
 </body>
@@ -143,12 +428,14 @@ func (h *HealthServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *HealthServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	ready := h.readiness(h.profileSnapshot(), time.Now())
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	uptime := time.Since(h.startTime)
 	status := HealthStatus{
-		Status:        "ok",
+		Status:        ready.Status,
 		Uptime:        utils.FormatUptime(uptime),
 		UptimeSeconds: uptime.Seconds(),
 		LastCheck:     h.lastCheck,
@@ -165,10 +452,13 @@ func (h *HealthServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *HealthServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	profiles := h.profileSnapshot()
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	uptime := time.Since(h.startTime)
+	now := time.Now()
+	uptime := now.Sub(h.startTime)
 
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
 
@@ -196,4 +486,6 @@ func (h *HealthServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	} else {
 		fmt.Fprintf(w, "patrol_last_check_timestamp 0\n")
 	}
+
+	h.writeProfileMetrics(w, profiles, now)
 }
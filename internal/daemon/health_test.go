@@ -1,13 +1,29 @@
 package daemon
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
 	"github.com/xabinapal/patrol/internal/utils"
 )
 
@@ -249,3 +265,381 @@ func TestSecurityHeaders(t *testing.T) {
 		t.Errorf("Cache-Control = %q, want %q", v, "no-store")
 	}
 }
+
+func TestHealthServer_Restart(t *testing.T) {
+	server := NewHealthServer("localhost:0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer server.Stop()
+	server.RecordRenewal()
+
+	if err := server.Restart("localhost:0"); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	if server.renewalsTotal != 1 {
+		t.Errorf("renewalsTotal = %d after restart, want 1", server.renewalsTotal)
+	}
+
+	// An address that cannot be listened on keeps the server where it was.
+	addr := server.Addr()
+	if err := server.Restart("256.0.0.1:1"); err == nil {
+		t.Error("Restart() should fail for an invalid address")
+	}
+	if server.Addr() != addr {
+		t.Errorf("Addr() = %q after failed restart, want %q", server.Addr(), addr)
+	}
+}
+
+func TestHealthServer_ProfileMetrics(t *testing.T) {
+	server := NewHealthServer("localhost:0")
+	expiresAt := time.Now().Add(30 * time.Minute)
+	server.SetProfileSource(func() []ProfileMetrics {
+		return []ProfileMetrics{
+			{Profile: "prod", HasToken: true, ExpiresAt: expiresAt, Renewable: true, LastSuccess: time.Unix(1700000000, 0), ConsecutiveFailures: 0},
+			{Profile: `odd"name`, ConsecutiveFailures: 3},
+		}
+	})
+	server.ObserveLatency(opRenewal, "prod", 200*time.Millisecond, nil)
+	server.ObserveLatency(opRenewal, "prod", 3*time.Second, nil)
+	server.ObserveLatency(opLookup, "prod", 2*time.Minute, errors.New("timed out"))
+	server.SetServerHealth("prod", "sealed")
+
+	w := httptest.NewRecorder()
+	server.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`patrol_token_ttl_seconds{profile="prod"} 1`,
+		fmt.Sprintf(`patrol_token_expiry_timestamp_seconds{profile="prod"} %d`, expiresAt.Unix()),
+		`patrol_token_renewable{profile="prod"} 1`,
+		`patrol_last_renewal_success_timestamp_seconds{profile="prod"} 1700000000`,
+		`patrol_consecutive_failures{profile="prod"} 0`,
+		`patrol_consecutive_failures{profile="odd\"name"} 3`,
+		`patrol_server_health{profile="prod",status="sealed"} 1`,
+		`patrol_server_health{profile="prod",status="healthy"} 0`,
+		`patrol_renewal_duration_seconds_bucket{profile="prod",result="success",le="0.1"} 0`,
+		`patrol_renewal_duration_seconds_bucket{profile="prod",result="success",le="0.25"} 1`,
+		`patrol_renewal_duration_seconds_bucket{profile="prod",result="success",le="5"} 2`,
+		`patrol_renewal_duration_seconds_count{profile="prod",result="success"} 2`,
+		`patrol_lookup_duration_seconds_bucket{profile="prod",result="failure",le="60"} 0`,
+		`patrol_lookup_duration_seconds_bucket{profile="prod",result="failure",le="+Inf"} 1`,
+		"# TYPE patrol_renewal_duration_seconds histogram",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+	// Profiles without a token have no token series.
+	if strings.Contains(body, `patrol_token_renewable{profile="odd\"name"}`) {
+		t.Error("profile without a token should have no patrol_token_renewable series")
+	}
+
+	server.ForgetProfile("prod")
+	w = httptest.NewRecorder()
+	server.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(w.Body.String(), "patrol_renewal_duration_seconds_count") {
+		t.Error("latencies of a forgotten profile should be dropped")
+	}
+}
+
+func TestDaemonMetrics(t *testing.T) {
+	server := httptest.NewServer(renewHandler(0, nil, nil))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL, "http://127.0.0.1:1"})
+	d.notifier = &recordingNotifier{}
+	hs := NewHealthServer("localhost:0")
+	d.SetHealthServer(hs)
+
+	for i := range cfg.Connections {
+		d.renewProfile(context.Background(), cfg, types.FromConnection(&cfg.Connections[i]))
+	}
+
+	w := httptest.NewRecorder()
+	hs.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`patrol_token_ttl_seconds{profile="profile-0"} 3600`,
+		`patrol_token_renewable{profile="profile-0"} 1`,
+		`patrol_last_renewal_success_timestamp_seconds{profile="profile-0"}`,
+		`patrol_consecutive_failures{profile="profile-0"} 0`,
+		`patrol_consecutive_failures{profile="profile-1"} 1`,
+		`patrol_renewal_duration_seconds_count{profile="profile-0",result="success"} 1`,
+		`patrol_renewal_duration_seconds_count{profile="profile-1",result="failure"} 1`,
+		`patrol_server_health{profile="profile-1",status="error"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
+
+func TestHealthServer_Readiness(t *testing.T) {
+	now := time.Now()
+	valid := ProfileMetrics{Profile: "ok", HasToken: true, ExpiresAt: now.Add(time.Hour)}
+	backingOff := ProfileMetrics{Profile: "backoff", HasToken: true, ExpiresAt: now.Add(time.Hour),
+		ConsecutiveFailures: 2, NextRetry: now.Add(time.Minute), LastError: "connection refused"}
+	expired := ProfileMetrics{Profile: "expired", HasToken: true, ExpiresAt: now.Add(-time.Minute), NeedsLogin: true}
+	noToken := ProfileMetrics{Profile: "none"}
+
+	tests := []struct {
+		name       string
+		profiles   []ProfileMetrics
+		checked    bool
+		wantStatus string
+		wantCode   int
+	}{
+		{"starting", []ProfileMetrics{valid}, false, ReadyStarting, http.StatusServiceUnavailable},
+		{"all valid", []ProfileMetrics{valid, noToken}, true, ReadyOK, http.StatusOK},
+		{"backing off", []ProfileMetrics{valid, backingOff}, true, ReadyDegraded, http.StatusOK},
+		{"one expired", []ProfileMetrics{valid, expired}, true, ReadyDegraded, http.StatusOK},
+		{"all expired", []ProfileMetrics{expired, noToken}, true, ReadyFailing, http.StatusServiceUnavailable},
+		{"no tokens", []ProfileMetrics{noToken}, true, ReadyOK, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewHealthServer("localhost:0")
+			server.SetProfileSource(func() []ProfileMetrics { return tt.profiles })
+			if tt.checked {
+				server.RecordCheck(len(tt.profiles))
+			}
+
+			w := httptest.NewRecorder()
+			server.handleReady(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", w.Code, tt.wantCode)
+			}
+			var status ReadinessStatus
+			if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if status.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status.Status, tt.wantStatus)
+			}
+			if len(status.Profiles) != len(tt.profiles) {
+				t.Errorf("got %d profiles, want %d", len(status.Profiles), len(tt.profiles))
+			}
+		})
+	}
+
+	r := profileReadiness(backingOff, now)
+	if r.Status != ReadyDegraded || r.ConsecutiveFailures != 2 || r.LastError != "connection refused" || r.NextRetry.IsZero() {
+		t.Errorf("profileReadiness(backing off) = %+v", r)
+	}
+}
+
+func TestHealthServer_Livez(t *testing.T) {
+	server := NewHealthServer("localhost:0")
+	server.SetProfileSource(func() []ProfileMetrics {
+		return []ProfileMetrics{{Profile: "expired", HasToken: true, NeedsLogin: true}}
+	})
+
+	w := httptest.NewRecorder()
+	server.handleLive(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("/livez status code = %d, want 200 regardless of the tokens", w.Code)
+	}
+}
+
+// startHealthServer serves h on a free local port with cfg and returns its
+// base URL.
+func startHealthServer(t *testing.T, h *HealthServer, cfg config.HealthConfig) string {
+	t.Helper()
+	listener, token, err := listenHealth("127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("listenHealth() failed: %v", err)
+	}
+	h.cfg = cfg
+	h.serve(listener, token)
+	t.Cleanup(func() {
+		if err := h.Stop(); err != nil {
+			t.Errorf("Stop() failed: %v", err)
+		}
+	})
+
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
+	return scheme + "://" + listener.Addr().String()
+}
+
+func TestHealthServer_BearerToken(t *testing.T) {
+	t.Setenv("PATROL_TEST_HEALTH_TOKEN", "s3cret")
+	url := startHealthServer(t, NewHealthServer("localhost:0"), config.HealthConfig{TokenEnv: "PATROL_TEST_HEALTH_TOKEN"})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "Bearer nope", http.StatusUnauthorized},
+		{"valid", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, url+"/livez", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status code = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := listenHealth("127.0.0.1:0", config.HealthConfig{TokenEnv: "PATROL_TEST_HEALTH_UNSET"}); err == nil {
+		t.Error("listenHealth() should fail when the token variable is not set")
+	}
+}
+
+// testCert is a certificate and key written to PEM files.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate for 127.0.0.1 signed by parent, or a
+// self-signed CA if parent is nil, and writes it to dir.
+func newTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &testCert{cert: cert, key: key, certFile: filepath.Join(dir, name+".pem"), keyFile: filepath.Join(dir, name+"-key.pem")}
+	if err := os.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+func TestHealthServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil)
+	serverCert := newTestCert(t, dir, "server", ca)
+	clientCert := newTestCert(t, dir, "client", ca)
+
+	url := startHealthServer(t, NewHealthServer("localhost:0"), config.HealthConfig{
+		TLSCert:  serverCert.certFile,
+		TLSKey:   serverCert.keyFile,
+		ClientCA: ca.certFile,
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs, MinVersion: tls.VersionTLS12},
+		}}
+	}
+
+	if resp, err := client().Get(url + "/livez"); err == nil {
+		resp.Body.Close()
+		t.Error("request without a client certificate should fail")
+	}
+
+	pair, err := tls.LoadX509KeyPair(clientCert.certFile, clientCert.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client(pair).Get(url + "/livez")
+	if err != nil {
+		t.Fatalf("request with a client certificate failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status code = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestHealthServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.sock")
+	server := NewHealthServer("unix://" + path)
+	if server.Addr() != "unix:"+path {
+		t.Errorf("Addr() = %q, want unix:%s", server.Addr(), path)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("health socket permissions = %o, want 600", perm)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://patrol/livez")
+	if err != nil {
+		t.Fatalf("request over the socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status code = %d, want 200", resp.StatusCode)
+	}
+
+	// Restarting on the same socket releases it first.
+	if err := server.Restart("unix:" + path); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	client.CloseIdleConnections()
+	if err := server.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed on stop, stat error = %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/hooks"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// runHooks runs the hooks configured for an event of type event on prof in
+// the background, so a slow hook does not hold up renewals. Hooks are
+// bounded by their own timeout rather than the renewal's, and shutdown waits
+// for them like for renewal workers.
+func (d *Daemon) runHooks(ctx context.Context, cfg *config.Config, event string, prof *types.Profile, ttl time.Duration, err error) {
+	ev := &hooks.Event{Type: event, Profile: prof, TTL: ttl, Err: err}
+	runner := hooks.NewRunner(cfg.Hooks, d.logger)
+	if len(runner.Matching(ev)) == 0 {
+		return
+	}
+	if event != config.HookLogout && runner.NeedsToken(ev) {
+		ev.Token, _ = d.store.Get(prof) //nolint:errcheck // hooks run without the token if it cannot be read
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	d.workers.Add(1)
+	go func() {
+		defer d.workers.Done()
+		runner.Run(ctx, ev)
+	}()
+}
@@ -0,0 +1,43 @@
+package daemon
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestRenewalRunsHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use sh")
+	}
+	server := httptest.NewServer(renewHandler(0, nil, nil))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL})
+	out := filepath.Join(t.TempDir(), "hook")
+	cfg.Hooks = []config.HookConfig{{
+		Command:   "sh",
+		Args:      []string{"-c", `echo "$PATROL_EVENT $PATROL_PROFILE $PATROL_TOKEN" > ` + out},
+		Events:    []string{config.HookRenewal},
+		PassToken: true,
+	}}
+	prof := types.FromConnection(&cfg.Connections[0])
+
+	d.renewProfile(context.Background(), cfg, prof)
+	d.workers.Wait()
+
+	data, err := os.ReadFile(out) // #nosec G304 - test file
+	if err != nil {
+		t.Fatalf("renewal hook did not run: %v", err)
+	}
+	if got, want := strings.TrimSpace(string(data)), "renewal profile-0 hvs.token"; got != want {
+		t.Errorf("hook saw %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/notify"
+	"github.com/xabinapal/patrol/internal/token"
+	"github.com/xabinapal/patrol/internal/types"
+	"github.com/xabinapal/patrol/internal/vault"
+)
+
+// idleSince returns when the token of prof was last used: its last recorded
+// use, or its creation if that is later, so a new login is never taken for
+// an idle token. It returns the zero time if neither is known.
+func (d *Daemon) idleSince(prof *types.Profile) (time.Time, error) {
+	lastUsed, err := d.usage.LastUsed(prof)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if meta, err := d.store.GetMetadata(prof); err == nil && meta.IssueTime.After(lastUsed) {
+		lastUsed = meta.IssueTime
+	}
+	return lastUsed, nil
+}
+
+// checkIdle enforces the idle_timeout of conn: it warns once shortly before
+// the token is revoked, and hands the token to a worker for revocation once
+// it has not been used for idle_timeout. It reports whether the token is
+// being revoked.
+func (d *Daemon) checkIdle(ctx context.Context, cfg *config.Config, conn *config.Connection, prof *types.Profile, now time.Time) bool {
+	if conn.IdleTimeout <= 0 {
+		return false
+	}
+
+	lastUsed, err := d.idleSince(prof)
+	if err != nil {
+		d.logger.Error("Error reading last token use", "profile", conn.Name, "error", err)
+		return false
+	}
+	if lastUsed.IsZero() {
+		// No use recorded yet, e.g. idle_timeout was just set: the idle
+		// period starts now.
+		if err := d.usage.Touch(prof, now); err != nil {
+			d.logger.Error("Error recording token use", "profile", conn.Name, "error", err)
+		}
+		return false
+	}
+
+	remaining := conn.IdleTimeout - now.Sub(lastUsed)
+	if remaining > 0 {
+		d.warnIdle(conn, lastUsed, remaining)
+		return false
+	}
+
+	// Keep the profile away from workers and rescans while it is revoked.
+	d.queue.Remove(conn.Name)
+	d.setInFlight(conn.Name, true)
+	d.workers.Add(1)
+	go func() {
+		defer d.workers.Done()
+		defer d.wakeRunLoop()
+		defer d.setInFlight(conn.Name, false)
+
+		d.revokeIdle(ctx, cfg, prof, now.Sub(lastUsed))
+	}()
+	return true
+}
+
+// warnIdle warns once per period of inactivity when the token of conn gets
+// within its idle warning time of being revoked.
+func (d *Daemon) warnIdle(conn *config.Connection, lastUsed time.Time, remaining time.Duration) {
+	if remaining > conn.IdleWarning() {
+		return
+	}
+
+	d.mu.Lock()
+	if d.idleWarned[conn.Name].Equal(lastUsed) {
+		d.mu.Unlock()
+		return
+	}
+	d.idleWarned[conn.Name] = lastUsed
+	d.mu.Unlock()
+
+	d.logger.Warn("Token unused, revoking it soon", "profile", conn.Name, "event", notify.EventIdleWarning,
+		"last_used", lastUsed, "revoke_in", remaining.Round(time.Second))
+	if notifyErr := d.currentNotifier().NotifyIdle(conn.Name, remaining.Round(time.Second)); notifyErr != nil {
+		d.logger.Debug("Failed to send notification", "profile", conn.Name, "event", notify.EventIdleWarning, "error", notifyErr)
+	}
+}
+
+// revokeIdle revokes the token of prof on the server and deletes it from the
+// store. The token is deleted even if it cannot be revoked: nothing renews it
+// afterwards, so it still ends at its next expiry.
+func (d *Daemon) revokeIdle(ctx context.Context, cfg *config.Config, prof *types.Profile, idle time.Duration) {
+	name := prof.Name
+	ctx, cancel := context.WithTimeout(ctx, renewTimeout(cfg))
+	defer cancel()
+
+	tm := token.NewTokenManager(ctx, d.store, vault.NewTokenExecutor())
+	if err := tm.Revoke(prof); err != nil {
+		d.logger.Warn("Failed to revoke idle token, deleting it anyway", "profile", name, "error", timeoutErr(ctx, err))
+	}
+	if err := tm.Delete(prof); err != nil {
+		d.logger.Error("Failed to delete idle token", "profile", name, "error", err)
+		return
+	}
+	if err := d.usage.Forget(prof); err != nil {
+		d.logger.Debug("Failed to forget token use", "profile", name, "error", err)
+	}
+	d.forgetTokenInfo(name)
+
+	d.resetBackoff(name)
+	d.mu.Lock()
+	delete(d.idleWarned, name)
+	d.mu.Unlock()
+
+	d.logger.Warn("Token revoked and deleted without use", "profile", name, "event", notify.EventIdleRevoked,
+		"idle", idle.Round(time.Second))
+	if notifyErr := d.currentNotifier().NotifyIdle(name, 0); notifyErr != nil {
+		d.logger.Debug("Failed to send notification", "profile", name, "event", notify.EventIdleRevoked, "error", notifyErr)
+	}
+	d.runHooks(ctx, cfg, config.HookLogout, prof, 0, nil)
+}
@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestCheckIdleWarnsOnce(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	notifier := &recordingNotifier{}
+	d.notifier = notifier
+	conn := &cfg.Connections[0]
+	conn.IdleTimeout = time.Hour
+	prof := types.FromConnection(conn)
+
+	now := time.Now().Truncate(time.Second)
+	// The first check starts the idle period of a token without recorded use.
+	if d.checkIdle(context.Background(), cfg, conn, prof, now) {
+		t.Fatal("token without recorded use was revoked")
+	}
+	if lastUsed, _ := d.usage.LastUsed(prof); !lastUsed.Equal(now) {
+		t.Fatalf("last use = %v, want the first check %v", lastUsed, now)
+	}
+
+	for _, offset := range []time.Duration{30 * time.Minute, 52 * time.Minute, 55 * time.Minute} {
+		if d.checkIdle(context.Background(), cfg, conn, prof, now.Add(offset)) {
+			t.Fatalf("token revoked after %s of a %s idle timeout", offset, conn.IdleTimeout)
+		}
+	}
+	if len(notifier.idle) != 1 || notifier.idle[0] != 8*time.Minute {
+		t.Fatalf("idle warnings = %v, want one 8m before revocation", notifier.idle)
+	}
+
+	// Using the token starts a new idle period with its own warning.
+	if err := d.usage.Touch(prof, now.Add(56*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	d.checkIdle(context.Background(), cfg, conn, prof, now.Add(time.Hour+50*time.Minute))
+	if len(notifier.idle) != 2 {
+		t.Errorf("idle warnings = %v, want a second warning after the token was used", notifier.idle)
+	}
+}
+
+func TestCheckIdleRevokes(t *testing.T) {
+	var revoked int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/token/revoke-self" {
+			atomic.AddInt32(&revoked, 1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d, cfg := newTestDaemon(t, []string{server.URL})
+	notifier := &recordingNotifier{}
+	d.notifier = notifier
+	conn := &cfg.Connections[0]
+	conn.IdleTimeout = time.Hour
+	prof := types.FromConnection(conn)
+
+	now := time.Now()
+	if err := d.usage.Touch(prof, now.Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	d.queue.Schedule(conn.Name, now.Add(time.Minute), now.Add(time.Minute))
+
+	if !d.checkIdle(context.Background(), cfg, conn, prof, now) {
+		t.Fatal("idle token was not revoked")
+	}
+	d.workers.Wait()
+
+	if atomic.LoadInt32(&revoked) != 1 {
+		t.Errorf("revoke requests = %d, want 1", revoked)
+	}
+	if _, err := d.store.Get(prof); err == nil {
+		t.Error("idle token is still stored")
+	}
+	if _, ok := d.queue.Tracked(conn.Name); ok {
+		t.Error("revoked profile is still scheduled")
+	}
+	if lastUsed, _ := d.usage.LastUsed(prof); !lastUsed.IsZero() {
+		t.Errorf("last use = %v, want the record removed", lastUsed)
+	}
+	if len(notifier.idle) != 1 || notifier.idle[0] != 0 {
+		t.Errorf("idle notifications = %v, want one about the revocation", notifier.idle)
+	}
+}
+
+func TestIdleSinceLogin(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	prof := types.FromConnection(&cfg.Connections[0])
+
+	now := time.Now().Truncate(time.Second)
+	if err := d.usage.Touch(prof, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// A token logged in after the last recorded use is not idle since then.
+	meta := &types.TokenMetadata{LeaseDuration: 3600, Renewable: true, IssueTime: now, ExpiresAt: now.Add(time.Hour)}
+	if err := d.store.SetMetadata(prof, meta); err != nil {
+		t.Fatal(err)
+	}
+	if since, err := d.idleSince(prof); err != nil || !since.Equal(now) {
+		t.Errorf("idleSince() = %v, %v; want the issue time %v", since, err, now)
+	}
+}
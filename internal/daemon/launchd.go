@@ -256,3 +256,8 @@ func (m *LaunchdManager) Status() (ServiceStatus, error) {
 func (m *LaunchdManager) ServiceFilePath() string {
 	return m.plistPath
 }
+
+// Logs returns the file the agent's output is written to.
+func (m *LaunchdManager) Logs() ServiceLogs {
+	return ServiceLogs{Path: m.cfg.LogPath}
+}
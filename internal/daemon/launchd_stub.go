@@ -35,3 +35,6 @@ func (m *LaunchdManager) Status() (ServiceStatus, error) {
 
 // ServiceFilePath is not supported on this platform.
 func (m *LaunchdManager) ServiceFilePath() string { return "" }
+
+// Logs is not supported on this platform.
+func (m *LaunchdManager) Logs() ServiceLogs { return ServiceLogs{} }
@@ -1,14 +1,22 @@
 package daemon
 
 import (
-	"encoding/json"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/logsink"
 )
 
 // LogLevel represents logging severity.
@@ -41,6 +49,49 @@ func (l LogLevel) String() string {
 	}
 }
 
+// Priority returns the syslog priority of the log level.
+func (l LogLevel) Priority() logsink.Priority {
+	switch l {
+	case LogLevelDebug:
+		return logsink.PriorityDebug
+	case LogLevelWarn:
+		return logsink.PriorityWarning
+	case LogLevelError:
+		return logsink.PriorityErr
+	default:
+		return logsink.PriorityInfo
+	}
+}
+
+// Level returns the slog level of the log level.
+func (l LogLevel) Level() slog.Level {
+	switch l {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// logLevelOf returns the log level of a slog level, rounding down levels
+// in between.
+func logLevelOf(level slog.Level) LogLevel {
+	switch {
+	case level >= slog.LevelError:
+		return LogLevelError
+	case level >= slog.LevelWarn:
+		return LogLevelWarn
+	case level >= slog.LevelInfo:
+		return LogLevelInfo
+	default:
+		return LogLevelDebug
+	}
+}
+
 // ParseLogLevel parses a log level string.
 func ParseLogLevel(s string) (LogLevel, error) {
 	switch s {
@@ -57,17 +108,24 @@ func ParseLogLevel(s string) (LogLevel, error) {
 	}
 }
 
-// Logger provides structured logging for the daemon.
+// Logger provides structured logging for the daemon. Messages carry
+// key/value attributes, as with log/slog, and are written as text or JSON
+// lines, or sent to the system log. Handler exposes it as a slog.Handler.
 type Logger struct {
 	mu       sync.Mutex
 	writer   io.Writer
+	sink     logsink.Sink // replaces writer when logging to the system log
 	level    LogLevel
 	jsonMode bool
 
 	// For log rotation
-	filePath    string
-	maxSize     int64 // bytes
-	currentSize int64
+	filePath       string
+	maxSize        int64 // bytes
+	maxFiles       int
+	compress       bool
+	rotateInterval time.Duration
+	currentSize    int64
+	lastWrite      time.Time
 }
 
 // LoggerConfig configures the logger.
@@ -76,18 +134,40 @@ type LoggerConfig struct {
 	FilePath string
 	JSONMode bool
 	MaxSize  int64 // Max file size before rotation (0 = no rotation)
+	// MaxFiles is the number of rotated files kept (0 = all).
+	MaxFiles int
+	// Compress gzips rotated files.
+	Compress bool
+	// RotateInterval rotates the file when an interval, aligned to UTC,
+	// ends (0 = no time-based rotation).
+	RotateInterval time.Duration
+	// Sink is config.LogSinkSyslog or config.LogSinkJournald to log to the
+	// system log instead of FilePath.
+	Sink   string
+	Syslog config.SyslogConfig
 }
 
 // NewLogger creates a new Logger.
 func NewLogger(cfg LoggerConfig) (*Logger, error) {
 	l := &Logger{
-		level:    cfg.Level,
-		jsonMode: cfg.JSONMode,
-		filePath: cfg.FilePath,
-		maxSize:  cfg.MaxSize,
+		level:          cfg.Level,
+		jsonMode:       cfg.JSONMode,
+		filePath:       cfg.FilePath,
+		maxSize:        cfg.MaxSize,
+		maxFiles:       cfg.MaxFiles,
+		compress:       cfg.Compress,
+		rotateInterval: cfg.RotateInterval,
 	}
 
-	if cfg.FilePath != "" {
+	switch {
+	case cfg.Sink != "" && cfg.Sink != config.LogSinkFile:
+		sink, err := logsink.New(cfg.Sink, cfg.Syslog)
+		if err != nil {
+			return nil, err
+		}
+		l.sink = sink
+		l.filePath = ""
+	case cfg.FilePath != "":
 		// Ensure directory exists
 		dir := filepath.Dir(cfg.FilePath)
 		if err := os.MkdirAll(dir, 0700); err != nil {
@@ -99,97 +179,292 @@ func NewLogger(cfg LoggerConfig) (*Logger, error) {
 			return nil, fmt.Errorf("failed to open log file: %w", err)
 		}
 
-		// Get current file size
+		// Get current file size, and when it was last written so a file
+		// left from a previous interval is rotated on the first write.
 		if info, err := f.Stat(); err == nil {
 			l.currentSize = info.Size()
+			if info.Size() > 0 {
+				l.lastWrite = info.ModTime()
+			}
 		}
 
 		l.writer = f
 		l.filePath = cfg.FilePath
-	} else {
+	default:
 		l.writer = os.Stderr
 	}
 
 	return l, nil
 }
 
+// Reconfigure applies cfg to a logger that may be in use, reopening its
+// output. On error the logger keeps its previous settings.
+func (l *Logger) Reconfigure(cfg LoggerConfig) error {
+	next, err := NewLogger(cfg)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	prev, prevSink := l.writer, l.sink
+	l.writer = next.writer
+	l.sink = next.sink
+	l.level = next.level
+	l.jsonMode = next.jsonMode
+	l.filePath = next.filePath
+	l.maxSize = next.maxSize
+	l.maxFiles = next.maxFiles
+	l.compress = next.compress
+	l.rotateInterval = next.rotateInterval
+	l.currentSize = next.currentSize
+	l.lastWrite = next.lastWrite
+	l.mu.Unlock()
+
+	if prevSink != nil {
+		return prevSink.Close()
+	}
+	if f, ok := prev.(*os.File); ok && f != os.Stderr && f != os.Stdout {
+		return f.Close()
+	}
+	return nil
+}
+
 // Close closes the logger.
 func (l *Logger) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	if l.sink != nil {
+		return l.sink.Close()
+	}
 	if f, ok := l.writer.(*os.File); ok && f != os.Stderr && f != os.Stdout {
 		return f.Close()
 	}
 	return nil
 }
 
-// logEntry represents a JSON log entry.
-type logEntry struct {
-	Time    string `json:"time"`
-	Level   string `json:"level"`
-	Message string `json:"message"`
-	Data    any    `json:"data,omitempty"`
+// messageKey is the key of the message in log records, as in the JSON log
+// of earlier versions.
+const messageKey = "message"
+
+// Handler returns a slog.Handler writing to the logger, so it can back a
+// slog.Logger.
+func (l *Logger) Handler() slog.Handler {
+	return &logHandler{l: l}
 }
 
-func (l *Logger) log(level LogLevel, msg string, data any) {
-	if level < l.level {
+// enabled reports whether messages at level are logged.
+func (l *Logger) enabled(level LogLevel) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return level >= l.level
+}
+
+// log logs msg with args, which are key/value pairs or slog.Attr values as
+// in slog.Logger.Info. A single value that is not an attribute is logged
+// as data.
+func (l *Logger) log(level LogLevel, msg string, args []any) {
+	if !l.enabled(level) {
 		return
 	}
 
+	r := slog.NewRecord(time.Now(), level.Level(), msg, 0)
+	if len(args) == 1 {
+		if _, ok := args[0].(slog.Attr); !ok {
+			args = []any{"data", args[0]}
+		}
+	}
+	r.Add(args...)
+	l.handle(r, nil)
+}
+
+// handle writes r, with the attributes of a derived handler before its own
+// and groups flattened into keys joined with dots, to the output.
+func (l *Logger) handle(r slog.Record, attrs []slog.Attr) {
+	flat := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	flat.AddAttrs(attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		flat.AddAttrs(appendAttr(nil, "", a)...)
+		return true
+	})
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	timestamp := time.Now().Format(time.RFC3339)
+	if logLevelOf(r.Level) < l.level {
+		return
+	}
+	l.output().Handle(context.Background(), flat) //nolint:errcheck // nowhere left to report it
+}
+
+// output returns the handler formatting records for the output of the
+// logger. l.mu must be held.
+func (l *Logger) output() slog.Handler {
+	if l.sink != nil {
+		return &sinkHandler{sink: l.sink}
+	}
+	return newLogHandler(logWriter{l}, l.jsonMode)
+}
+
+// newLogHandler returns the slog handler writing the text or JSON format
+// of the log to w.
+func newLogHandler(w io.Writer, jsonMode bool) slog.Handler {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: replaceAttr}
+	if jsonMode {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
 
-	var line string
-	if l.jsonMode {
-		entry := logEntry{
-			Time:    timestamp,
-			Level:   level.String(),
-			Message: msg,
-			Data:    data,
+// replaceAttr formats the attributes of log records: the level is named
+// after its LogLevel, the message is under messageKey, and times and
+// durations are written as in formatValue.
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 {
+		switch a.Key {
+		case slog.LevelKey:
+			if level, ok := a.Value.Any().(slog.Level); ok {
+				return slog.String(slog.LevelKey, logLevelOf(level).String())
+			}
+		case slog.MessageKey:
+			a.Key = messageKey
 		}
-		b, err := json.Marshal(entry)
-		if err != nil {
-			// Fall back to simple format if JSON marshal fails
-			line = fmt.Sprintf("%s [%s] %s\n", timestamp, level.String(), msg)
-		} else {
-			line = string(b) + "\n"
+	}
+	a.Value = formatValue(a.Value)
+	return a
+}
+
+// formatValue returns times as RFC 3339 strings and durations in their Go
+// syntax (1h0m0s), in both formats of the log.
+func formatValue(v slog.Value) slog.Value {
+	switch v.Kind() {
+	case slog.KindTime:
+		return slog.StringValue(v.Time().Format(time.RFC3339))
+	case slog.KindDuration:
+		return slog.StringValue(v.Duration().String())
+	}
+	return v
+}
+
+// appendAttr appends a to attrs, resolving its value and flattening groups
+// into keys joined with dots.
+func appendAttr(attrs []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
 		}
-	} else {
-		if data != nil {
-			line = fmt.Sprintf("%s [%s] %s %v\n", timestamp, level.String(), msg, data)
-		} else {
-			line = fmt.Sprintf("%s [%s] %s\n", timestamp, level.String(), msg)
+		for _, ga := range a.Value.Group() {
+			attrs = appendAttr(attrs, prefix, ga)
 		}
+		return attrs
 	}
+	a.Key = prefix + a.Key
+	return append(attrs, a)
+}
+
+// logWriter writes the lines formatted for a logger to its output. The
+// handlers writing to it run with the lock of the logger held.
+type logWriter struct {
+	l *Logger
+}
 
-	// Check if rotation needed
-	if l.maxSize > 0 && l.filePath != "" {
-		l.currentSize += int64(len(line))
-		if l.currentSize > l.maxSize {
+// Write writes a line, first rotating a non-empty log file the line would
+// take past its maximum size, or that was last written in an earlier
+// interval.
+func (w logWriter) Write(line []byte) (int, error) {
+	l := w.l
+	if l.filePath != "" {
+		now := time.Now()
+		size := int64(len(line))
+		if l.currentSize > 0 && ((l.maxSize > 0 && l.currentSize+size > l.maxSize) || l.intervalEnded(now)) {
 			l.rotate()
 		}
+		l.currentSize += size
+		l.lastWrite = now
+	}
+	return l.writer.Write(line)
+}
+
+// sinkHandler is a slog.Handler sending records to the system log, which
+// records the time and level itself.
+type sinkHandler struct {
+	sink   logsink.Sink
+	attrs  []slog.Attr
+	prefix string // group prefix of attributes added later
+}
+
+// Enabled implements slog.Handler.
+func (h *sinkHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func (h *sinkHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := slices.Clip(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendAttr(attrs, h.prefix, a)
+		return true
+	})
+	fields := make([]logsink.Field, 0, len(attrs))
+	for _, a := range attrs {
+		fields = append(fields, logsink.Field{Key: a.Key, Value: formatValue(a.Value).String()})
+	}
+	return h.sink.Send(logLevelOf(r.Level).Priority(), r.Message, fields...)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *sinkHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &sinkHandler{sink: h.sink, attrs: slices.Clip(h.attrs), prefix: h.prefix}
+	for _, a := range attrs {
+		next.attrs = appendAttr(next.attrs, h.prefix, a)
+	}
+	return next
+}
+
+// WithGroup implements slog.Handler.
+func (h *sinkHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
 	}
+	return &sinkHandler{sink: h.sink, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
 
-	if _, err := l.writer.Write([]byte(line)); err != nil {
-		// Log write errors are non-fatal, but we can't do much about them
-		_ = err
+// intervalEnded reports whether the log file was last written in an
+// earlier rotation interval than now.
+func (l *Logger) intervalEnded(now time.Time) bool {
+	if l.rotateInterval <= 0 || l.lastWrite.IsZero() {
+		return false
 	}
+	return !now.Truncate(l.rotateInterval).Equal(l.lastWrite.Truncate(l.rotateInterval))
 }
 
+// rotate renames the log file aside, compressing it if configured, opens a
+// new one and removes the rotated files beyond maxFiles.
 func (l *Logger) rotate() {
 	// Close current file
-	if f, ok := l.writer.(*os.File); ok {
+	if f, ok := l.writer.(*os.File); ok && f != os.Stderr {
 		_ = f.Close()
 	}
 
-	// Rename current file with timestamp
+	// Rename current file with timestamp, without replacing a file rotated
+	// within the same second
 	rotatedPath := l.filePath + "." + time.Now().Format("20060102-150405")
-	if err := os.Rename(l.filePath, rotatedPath); err != nil {
-		// Log rotation failure is non-fatal
-		_ = err
+	for i := 1; fileExists(rotatedPath) || fileExists(rotatedPath+".gz"); i++ {
+		rotatedPath = fmt.Sprintf("%s.%s-%d", l.filePath, time.Now().Format("20060102-150405"), i)
+	}
+	renameErr := os.Rename(l.filePath, rotatedPath)
+	if renameErr != nil {
+		// The file keeps its size, so rotation is tried again on the next write
+		fmt.Fprintf(os.Stderr, "patrol: failed to rotate log file: %v\n", renameErr)
+	} else if l.compress {
+		if err := compressFile(rotatedPath); err != nil {
+			// The rotated file is kept uncompressed
+			fmt.Fprintf(os.Stderr, "patrol: failed to compress rotated log file: %v\n", err)
+		}
 	}
 
 	// Open new file
@@ -199,15 +474,61 @@ func (l *Logger) rotate() {
 		l.writer = os.Stderr
 		return
 	}
-
 	l.writer = f
+	if renameErr != nil {
+		return
+	}
 	l.currentSize = 0
 
-	// Clean up old rotated files (keep last 5)
+	// Clean up old rotated files
 	l.cleanupOldLogs()
 }
 
+// fileExists reports whether a file exists at path.
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// compressFile gzips the file at path to path.gz and removes it. On error
+// the original is left in place.
+func compressFile(path string) (err error) {
+	// #nosec G304 - path is a rotated log file next to the configured log file
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// #nosec G304 - path is a rotated log file next to the configured log file
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(dst.Name()) //nolint:errcheck // best effort cleanup of a partial file
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close() //nolint:errcheck // the copy error is returned
+		return err
+	}
+	if err := errors.Join(zw.Close(), dst.Close()); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// cleanupOldLogs removes the oldest rotated files, keeping maxFiles of them.
 func (l *Logger) cleanupOldLogs() {
+	if l.maxFiles <= 0 {
+		return
+	}
+
 	dir := filepath.Dir(l.filePath)
 	base := filepath.Base(l.filePath)
 	pattern := base + ".*"
@@ -217,53 +538,37 @@ func (l *Logger) cleanupOldLogs() {
 		// Glob failure is non-fatal
 		return
 	}
-	if len(matches) <= 5 {
+	if len(matches) <= l.maxFiles {
 		return
 	}
 
 	// Sort to get oldest first
 	sort.Strings(matches)
 
-	// Remove oldest files, keeping last 5
-	for i := 0; i < len(matches)-5; i++ {
+	// Remove oldest files, keeping the last maxFiles
+	for i := 0; i < len(matches)-l.maxFiles; i++ {
 		_ = os.Remove(matches[i])
 	}
 }
 
-// Debug logs a debug message.
-func (l *Logger) Debug(msg string, data ...any) {
-	var d any
-	if len(data) > 0 {
-		d = data[0]
-	}
-	l.log(LogLevelDebug, msg, d)
+// Debug logs a debug message with attributes given as key/value pairs.
+func (l *Logger) Debug(msg string, args ...any) {
+	l.log(LogLevelDebug, msg, args)
 }
 
-// Info logs an info message.
-func (l *Logger) Info(msg string, data ...any) {
-	var d any
-	if len(data) > 0 {
-		d = data[0]
-	}
-	l.log(LogLevelInfo, msg, d)
+// Info logs an info message with attributes given as key/value pairs.
+func (l *Logger) Info(msg string, args ...any) {
+	l.log(LogLevelInfo, msg, args)
 }
 
-// Warn logs a warning message.
-func (l *Logger) Warn(msg string, data ...any) {
-	var d any
-	if len(data) > 0 {
-		d = data[0]
-	}
-	l.log(LogLevelWarn, msg, d)
+// Warn logs a warning message with attributes given as key/value pairs.
+func (l *Logger) Warn(msg string, args ...any) {
+	l.log(LogLevelWarn, msg, args)
 }
 
-// Error logs an error message.
-func (l *Logger) Error(msg string, data ...any) {
-	var d any
-	if len(data) > 0 {
-		d = data[0]
-	}
-	l.log(LogLevelError, msg, d)
+// Error logs an error message with attributes given as key/value pairs.
+func (l *Logger) Error(msg string, args ...any) {
+	l.log(LogLevelError, msg, args)
 }
 
 // Println logs an info message (for compatibility with standard log.Logger).
@@ -278,6 +583,8 @@ func (l *Logger) Printf(format string, v ...any) {
 
 // GetLevel returns the current log level.
 func (l *Logger) GetLevel() LogLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.level
 }
 
@@ -287,3 +594,50 @@ func (l *Logger) SetLevel(level LogLevel) {
 	defer l.mu.Unlock()
 	l.level = level
 }
+
+// logHandler is the slog.Handler of a Logger. Attributes added with
+// WithAttrs and WithGroup are kept by the handler, so derived loggers share
+// the output and settings of the Logger.
+type logHandler struct {
+	l      *Logger
+	attrs  []slog.Attr
+	prefix string // group prefix of attributes added later
+}
+
+// Enabled implements slog.Handler.
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.l.enabled(logLevelOf(level))
+}
+
+// Handle implements slog.Handler.
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	if h.prefix != "" {
+		grouped := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		var attrs []any
+		r.Attrs(func(a slog.Attr) bool {
+			attrs = append(attrs, a)
+			return true
+		})
+		grouped.AddAttrs(slog.Group(strings.TrimSuffix(h.prefix, "."), attrs...))
+		r = grouped
+	}
+	h.l.handle(r, h.attrs)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &logHandler{l: h.l, attrs: slices.Clip(h.attrs), prefix: h.prefix}
+	for _, a := range attrs {
+		next.attrs = appendAttr(next.attrs, h.prefix, a)
+	}
+	return next
+}
+
+// WithGroup implements slog.Handler.
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &logHandler{l: h.l, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
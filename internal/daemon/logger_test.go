@@ -2,13 +2,29 @@ package daemon
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
 )
 
+// logEntry holds the fixed fields of a JSON log entry.
+type logEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
 func TestLogLevel_String(t *testing.T) {
 	tests := []struct {
 		level LogLevel
@@ -77,8 +93,8 @@ func TestLogger_TextOutput(t *testing.T) {
 	logger.Info("test message")
 
 	output := buf.String()
-	if !strings.Contains(output, "[INFO]") {
-		t.Errorf("Expected output to contain [INFO], got: %s", output)
+	if !strings.Contains(output, "level=INFO") {
+		t.Errorf("Expected output to contain level=INFO, got: %s", output)
 	}
 	if !strings.Contains(output, "test message") {
 		t.Errorf("Expected output to contain 'test message', got: %s", output)
@@ -247,3 +263,208 @@ func TestNewLogger_CreateDirectory(t *testing.T) {
 		t.Error("Expected directory to be created")
 	}
 }
+
+func TestLogger_SyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() failed: %v", err)
+	}
+	defer conn.Close()
+
+	logger, err := NewLogger(LoggerConfig{
+		Level:  LogLevelInfo,
+		Sink:   config.LogSinkSyslog,
+		Syslog: config.SyslogConfig{Network: config.SyslogUDP, Address: conn.LocalAddr().String()},
+	})
+	if err != nil {
+		t.Fatalf("NewLogger() failed: %v", err)
+	}
+	defer logger.Close()
+
+	logger.Debug("filtered out")
+	logger.Error("Profile prod: renewal failed: connection refused")
+
+	buf := make([]byte, 8192)
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no syslog message received: %v", err)
+	}
+	// daemon (3) * 8 + err (3) = 27
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<27>1 ") || !strings.HasSuffix(msg, " Profile prod: renewal failed: connection refused") {
+		t.Errorf("syslog message = %q, want the error at err priority", msg)
+	}
+}
+
+func TestLogger_Attributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{writer: &buf, level: LogLevelDebug}
+
+	logger.Warn("Renewal failed, will retry", "profile", "prod", "attempt", 2,
+		"retry_in", 30*time.Second, "error", errors.New("connection refused"))
+	want := ` level=WARN message="Renewal failed, will retry" profile=prod attempt=2 retry_in=30s error="connection refused"` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("text output = %q, want suffix %q", got, want)
+	}
+
+	buf.Reset()
+	logger.jsonMode = true
+	logger.Info("Token renewed", "profile", "prod", "event", "renewal", "ttl", time.Hour, "renewable", true)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	wantFields := map[string]any{"level": "INFO", "message": "Token renewed", "profile": "prod", "event": "renewal", "ttl": "1h0m0s", "renewable": true}
+	for key, value := range wantFields {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+}
+
+func TestLogger_Handler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{writer: &buf, level: LogLevelInfo, jsonMode: true}
+
+	log := slog.New(logger.Handler()).With("profile", "prod").WithGroup("hook")
+	log.Debug("filtered out")
+	log.Info("Hook completed", "name", "notify")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse JSON output %q: %v", buf.String(), err)
+	}
+	if entry["message"] != "Hook completed" || entry["profile"] != "prod" || entry["hook.name"] != "notify" {
+		t.Errorf("entry = %v, want the attributes of the logger and the group", entry)
+	}
+}
+
+// rotatedFiles returns the rotated files of the log file at path.
+func rotatedFiles(t *testing.T, path string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestLogger_RotateBySize(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "patrol.log")
+	logger, err := NewLogger(LoggerConfig{Level: LogLevelInfo, FilePath: logFile, MaxSize: 100, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	for i := range 5 {
+		logger.Info("A message long enough to rotate the log file every time", "n", i)
+	}
+
+	if got := rotatedFiles(t, logFile); len(got) != 2 {
+		t.Errorf("rotated files = %v, want the last 2", got)
+	}
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "n=4") {
+		t.Errorf("log file = %q, want the last message", content)
+	}
+}
+
+func TestLogger_RotateCompress(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "patrol.log")
+	logger, err := NewLogger(LoggerConfig{Level: LogLevelInfo, FilePath: logFile, MaxSize: 10, Compress: true})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	logger.Info("first message")
+	logger.Info("second message")
+
+	rotated := rotatedFiles(t, logFile)
+	if len(rotated) != 1 || !strings.HasSuffix(rotated[0], ".gz") {
+		t.Fatalf("rotated files = %v, want one gzipped file", rotated)
+	}
+	f, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("rotated file is not gzipped: %v", err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "first message") {
+		t.Errorf("rotated file = %q, want the first message", content)
+	}
+}
+
+func TestLogger_RotateByInterval(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "patrol.log")
+	logger, err := NewLogger(LoggerConfig{Level: LogLevelInfo, FilePath: logFile, RotateInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	logger.Info("first message")
+	logger.Info("same hour")
+	if got := rotatedFiles(t, logFile); len(got) != 0 {
+		t.Fatalf("rotated files = %v, want none within the interval", got)
+	}
+
+	logger.mu.Lock()
+	logger.lastWrite = logger.lastWrite.Add(-time.Hour)
+	logger.mu.Unlock()
+	logger.Info("next hour")
+
+	if got := rotatedFiles(t, logFile); len(got) != 1 {
+		t.Errorf("rotated files = %v, want one after the interval ended", got)
+	}
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "first message") || !strings.Contains(string(content), "next hour") {
+		t.Errorf("log file = %q, want only the message of the new interval", content)
+	}
+}
+
+func TestLogger_RotateRenameFailure(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "patrol.log")
+	logger, err := NewLogger(LoggerConfig{Level: LogLevelInfo, FilePath: logFile, MaxSize: 100})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	logger.Info("A message long enough to rotate the log file every time", "n", 0)
+	// The rename of the next rotation fails with the file gone.
+	if err := os.Remove(logFile); err != nil {
+		t.Fatal(err)
+	}
+	logger.mu.Lock()
+	size := logger.currentSize
+	logger.mu.Unlock()
+	logger.Info("A message long enough to rotate the log file every time", "n", 1)
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if logger.currentSize <= size {
+		t.Errorf("size after a failed rotation = %d, want more than %d", logger.currentSize, size)
+	}
+	if got := rotatedFiles(t, logFile); len(got) != 0 {
+		t.Errorf("rotated files = %v, want none", got)
+	}
+}
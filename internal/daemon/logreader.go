@@ -0,0 +1,578 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLogPollInterval is how often a followed log file is checked for new
+// lines and rotation.
+const DefaultLogPollInterval = 250 * time.Millisecond
+
+// LogRecord is a line of the daemon log, parsed from the text or JSON
+// format written by Logger.
+type LogRecord struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+	Attrs   []slog.Attr
+	// Raw is the line as read. Lines that are not log records, such as the
+	// output of a crash, only have Raw.
+	Raw    string
+	parsed bool
+}
+
+// Parsed reports whether the line is a log record.
+func (r *LogRecord) Parsed() bool {
+	return r.parsed
+}
+
+// Attr returns the value of the attribute key, or "" if it is not set.
+func (r *LogRecord) Attr(key string) string {
+	for _, a := range r.Attrs {
+		if a.Key == key {
+			return formatValue(a.Value).String()
+		}
+	}
+	return ""
+}
+
+// Text formats the record in the text format of Logger, with a trailing
+// newline. Lines that are not log records are returned as read.
+func (r *LogRecord) Text() []byte {
+	if !r.parsed {
+		return []byte(r.Raw + "\n")
+	}
+	return r.format(false)
+}
+
+// JSON formats the record in the JSON format of Logger, with a trailing
+// newline. Lines that are not log records become a message without a level.
+func (r *LogRecord) JSON() []byte {
+	if !r.parsed {
+		data, _ := json.Marshal(map[string]string{messageKey: r.Raw}) //nolint:errcheck // strings always encode
+		return append(data, '\n')
+	}
+	return r.format(true)
+}
+
+// format formats the record with the handler of the log.
+func (r *LogRecord) format(jsonMode bool) []byte {
+	rec := slog.NewRecord(r.Time, r.Level.Level(), r.Message, 0)
+	rec.AddAttrs(r.Attrs...)
+	var b bytes.Buffer
+	newLogHandler(&b, jsonMode).Handle(context.Background(), rec) //nolint:errcheck // writing to a buffer does not fail
+	return b.Bytes()
+}
+
+// ParseLogLine parses a line of the daemon log in either format.
+func ParseLogLine(line string) LogRecord {
+	line = strings.TrimRight(line, "\r\n")
+	rec := LogRecord{Raw: line}
+	if strings.HasPrefix(line, "{") {
+		parseJSONLine(&rec, line)
+	} else {
+		parseTextLine(&rec, line)
+	}
+	return rec
+}
+
+// parseJSONLine parses a JSON log line, keeping the attributes in order.
+func parseJSONLine(rec *LogRecord, line string) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return
+	}
+
+	var attrs []slog.Attr
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return
+		}
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return
+		}
+
+		if n, ok := value.(json.Number); ok {
+			value = jsonNumber(n)
+		}
+		attrs = append(attrs, slog.Any(key, value))
+	}
+	rec.setAttrs(attrs)
+}
+
+// setAttrs sets the record from the attributes of a line, taking the time,
+// level and message out of them. The record is not parsed unless they hold
+// a time and a level.
+func (rec *LogRecord) setAttrs(attrs []slog.Attr) {
+	parsed := LogRecord{Raw: rec.Raw}
+	var hasTime, hasLevel bool
+	for _, a := range attrs {
+		isString := a.Value.Kind() == slog.KindString
+		switch {
+		case a.Key == slog.TimeKey && isString:
+			t, err := time.Parse(time.RFC3339, a.Value.String())
+			if err != nil {
+				return
+			}
+			parsed.Time, hasTime = t, true
+		case a.Key == slog.LevelKey && isString:
+			level, err := ParseLogLevel(a.Value.String())
+			if err != nil {
+				return
+			}
+			parsed.Level, hasLevel = level, true
+		case a.Key == messageKey:
+			// A message such as "42" is read from the text log as a number.
+			parsed.Message = a.Value.String()
+		default:
+			parsed.Attrs = append(parsed.Attrs, a)
+		}
+	}
+	if !hasTime || !hasLevel {
+		return
+	}
+
+	parsed.parsed = true
+	*rec = parsed
+}
+
+// jsonNumber returns n as an int64 if it is an integer, or a float64.
+func jsonNumber(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
+
+// parseTextLine parses a text log line of key=value pairs, or a line in
+// the text format of earlier versions.
+func parseTextLine(rec *LogRecord, line string) {
+	if attrs, ok := parseTextAttrs(line); ok {
+		rec.setAttrs(attrs)
+		if rec.parsed {
+			return
+		}
+	}
+	parseLegacyTextLine(rec, line)
+}
+
+// parseLegacyTextLine parses a line in the text format of earlier versions:
+// the time, the level in brackets, the message and the attributes as
+// key=value pairs. The attributes are the longest run of key=value pairs
+// ending the line.
+func parseLegacyTextLine(rec *LogRecord, line string) {
+	timestamp, rest, ok := strings.Cut(line, " [")
+	if !ok {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return
+	}
+	levelName, rest, ok := strings.Cut(rest, "] ")
+	if !ok {
+		levelName, ok = strings.CutSuffix(rest, "]")
+		if !ok {
+			return
+		}
+		rest = ""
+	}
+	level, err := ParseLogLevel(levelName)
+	if err != nil || levelName == "" {
+		return
+	}
+
+	rec.Time = t
+	rec.Level = level
+	rec.Message = rest
+	rec.parsed = true
+
+	for i := 0; i < len(rest); i++ {
+		if i > 0 && rest[i-1] != ' ' {
+			continue
+		}
+		if attrs, ok := parseTextAttrs(rest[i:]); ok {
+			rec.Message = strings.TrimSuffix(rest[:i], " ")
+			rec.Attrs = attrs
+			return
+		}
+	}
+}
+
+// parseTextAttrs parses s as space separated key=value pairs, with values
+// quoted as by slog.TextHandler. It fails unless all of s is made of pairs.
+func parseTextAttrs(s string) ([]slog.Attr, bool) {
+	var attrs []slog.Attr
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok || !isAttrKey(key) {
+			return nil, false
+		}
+
+		if !strings.HasPrefix(rest, `"`) {
+			value, next, _ := strings.Cut(rest, " ")
+			attrs = append(attrs, textAttr(key, value))
+			s = next
+			continue
+		}
+
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, false
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, false
+		}
+		attrs = append(attrs, textAttr(key, value))
+		rest = rest[len(quoted):]
+		if rest != "" && !strings.HasPrefix(rest, " ") {
+			return nil, false
+		}
+		s = strings.TrimPrefix(rest, " ")
+	}
+	return attrs, len(attrs) > 0
+}
+
+// isAttrKey reports whether s can be an attribute key: letters, digits,
+// underscores and the dots of flattened groups.
+func isAttrKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// textAttr returns an attribute for a value of the text format, keeping
+// integers and booleans as such for the JSON output.
+func textAttr(key, value string) slog.Attr {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return slog.Int64(key, i)
+	}
+	if value == "true" || value == "false" {
+		return slog.Bool(key, value == "true")
+	}
+	return slog.String(key, value)
+}
+
+// LogFilter selects log records.
+type LogFilter struct {
+	// Level is the lowest level shown.
+	Level LogLevel
+	// Profile shows only records about the profile, if set.
+	Profile string
+	// Since shows only records logged at or after it, if set.
+	Since time.Time
+}
+
+// active reports whether the filter drops any records.
+func (f *LogFilter) active() bool {
+	return f.Level > LogLevelDebug || f.Profile != "" || !f.Since.IsZero()
+}
+
+// Match reports whether rec passes the filter. Lines that are not log
+// records pass only a filter that drops nothing.
+func (f *LogFilter) Match(rec *LogRecord) bool {
+	if !rec.parsed {
+		return !f.active()
+	}
+	if rec.Level < f.Level {
+		return false
+	}
+	if f.Profile != "" && rec.Attr("profile") != f.Profile {
+		return false
+	}
+	return f.Since.IsZero() || !rec.Time.Before(f.Since)
+}
+
+// LogReader reads the daemon log file and the files rotated from it, and
+// follows it across rotations.
+type LogReader struct {
+	// Path is the log file.
+	Path string
+	// Since skips rotated files last written before it.
+	Since time.Time
+	// Follow keeps reading lines appended to the log until the context is
+	// canceled.
+	Follow bool
+	// PollInterval is how often a followed log is checked; it defaults to
+	// DefaultLogPollInterval.
+	PollInterval time.Duration
+}
+
+// RotatedLogFiles returns the files rotated from the log file at path,
+// oldest first.
+func RotatedLogFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	type rotated struct {
+		path    string
+		modTime time.Time
+	}
+	var files []rotated
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, rotated{m, info.ModTime()})
+	}
+	// Names only have second precision, so order by the time the files
+	// were last written.
+	slices.SortStableFunc(files, func(a, b rotated) int {
+		if c := a.modTime.Compare(b.modTime); c != 0 {
+			return c
+		}
+		return strings.Compare(a.path, b.path)
+	})
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
+}
+
+// Read calls fn with every line of the rotated files and the log file, in
+// order, then with the lines appended to the log file if following it. An
+// error of fn stops reading and is returned.
+func (r *LogReader) Read(ctx context.Context, fn func(line string) error) error {
+	rotated, err := RotatedLogFiles(r.Path)
+	if err != nil {
+		return err
+	}
+	for _, path := range rotated {
+		if !r.Since.IsZero() {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(r.Since) {
+				continue
+			}
+		}
+		if err := readLogFile(path, fn); err != nil {
+			return err
+		}
+	}
+
+	// #nosec G304 - the log file is taken from the configuration
+	f, err := os.Open(r.Path)
+	if err != nil {
+		if len(rotated) > 0 && errors.Is(err, os.ErrNotExist) && !r.Follow {
+			return nil
+		}
+		return err
+	}
+	lines := &lineReader{r: bufio.NewReader(f)}
+	if err := lines.each(fn); err != nil || !r.Follow {
+		f.Close() //nolint:errcheck // read-only file
+		return err
+	}
+	return r.follow(ctx, f, lines, fn)
+}
+
+// follow polls the open log file f for new lines, and closes it when done.
+// When the log is rotated, the rest of f is read and the new log file is
+// followed from its start.
+func (r *LogReader) follow(ctx context.Context, f *os.File, lines *lineReader, fn func(string) error) error {
+	defer func() { f.Close() }() //nolint:errcheck // read-only file
+
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = DefaultLogPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := lines.each(fn); err != nil {
+			return err
+		}
+
+		current, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(r.Path)
+		if err != nil {
+			// Between the rename and the creation of the new file.
+			continue
+		}
+		if !os.SameFile(current, info) {
+			if err := lines.flush(fn); err != nil {
+				return err
+			}
+			// #nosec G304 - the log file is taken from the configuration
+			next, err := os.Open(r.Path)
+			if err != nil {
+				continue
+			}
+			f.Close() //nolint:errcheck // read-only file
+			f = next
+			lines.r.Reset(f)
+			if err := lines.each(fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if offset, err := f.Seek(0, io.SeekCurrent); err == nil && info.Size() < offset-int64(lines.r.Buffered()) {
+			// Truncated in place: start over.
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			lines.r.Reset(f)
+			lines.partial = ""
+		}
+	}
+}
+
+// readLogFile calls fn with every line of a rotated log file, which may be
+// gzipped.
+func readLogFile(path string, fn func(string) error) error {
+	// #nosec G304 - path is a rotated log file next to the configured log file
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	lines := &lineReader{r: bufio.NewReader(r)}
+	if err := lines.each(fn); err != nil {
+		return err
+	}
+	return lines.flush(fn)
+}
+
+// lineReader splits a file being written into lines, holding back a last
+// line until it is complete.
+type lineReader struct {
+	r       *bufio.Reader
+	partial string
+}
+
+// each calls fn with every complete line read so far.
+func (lr *lineReader) each(fn func(string) error) error {
+	for {
+		s, err := lr.r.ReadString('\n')
+		lr.partial += s
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		line := lr.partial
+		lr.partial = ""
+		if err := fn(strings.TrimRight(line, "\r\n")); err != nil {
+			return err
+		}
+	}
+}
+
+// flush calls fn with a last line that has no newline.
+func (lr *lineReader) flush(fn func(string) error) error {
+	if err := lr.each(fn); err != nil {
+		return err
+	}
+	if lr.partial == "" {
+		return nil
+	}
+	line := lr.partial
+	lr.partial = ""
+	return fn(line)
+}
+
+// ReadLogCommand runs a command printing the daemon log, such as
+// journalctl, and calls fn with every line of its output until it exits or
+// the context is canceled.
+func ReadLogCommand(ctx context.Context, args []string, fn func(line string) error) error {
+	// #nosec G204 - the command is chosen by the service manager
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var fnErr error
+	for scanner.Scan() {
+		if fnErr = fn(scanner.Text()); fnErr != nil {
+			break
+		}
+	}
+	if fnErr != nil {
+		cmd.Cancel() //nolint:errcheck // the command is stopped on purpose
+		cmd.Wait()   //nolint:errcheck // the error of fn is returned
+		return fnErr
+	}
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// JournalCommand returns the journalctl command printing the output of a
+// systemd user unit, from since if set, and following it if follow is set.
+func JournalCommand(unit string, follow bool, since time.Time) []string {
+	args := []string{"journalctl", "--user", "--unit", unit, "--output", "cat", "--no-pager"}
+	if !since.IsZero() {
+		args = append(args, "--since", since.Local().Format(time.DateTime))
+	}
+	if follow {
+		args = append(args, "--follow", "--lines", "all")
+	}
+	return args
+}
@@ -0,0 +1,207 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	for _, jsonMode := range []bool{false, true} {
+		t.Run(fmt.Sprintf("json=%v", jsonMode), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := &Logger{writer: &buf, level: LogLevelDebug, jsonMode: jsonMode}
+			logger.Warn("Renewal failed, will retry", "profile", "prod", "attempt", 2,
+				"retry_in", time.Minute, "error", errors.New(`bad "token" = x`))
+
+			rec := ParseLogLine(buf.String())
+			if !rec.Parsed() {
+				t.Fatalf("ParseLogLine(%q) did not parse", buf.String())
+			}
+			if rec.Level != LogLevelWarn || rec.Message != "Renewal failed, will retry" {
+				t.Errorf("level, message = %s, %q", rec.Level, rec.Message)
+			}
+			if time.Since(rec.Time) > time.Minute {
+				t.Errorf("time = %s, want now", rec.Time)
+			}
+			want := map[string]string{"profile": "prod", "attempt": "2", "retry_in": "1m0s", "error": `bad "token" = x`}
+			for key, value := range want {
+				if got := rec.Attr(key); got != value {
+					t.Errorf("Attr(%q) = %q, want %q", key, got, value)
+				}
+			}
+
+			// Formatting in the format read gives the line back.
+			out := rec.Text()
+			if jsonMode {
+				out = rec.JSON()
+			}
+			if string(out) != buf.String() {
+				t.Errorf("formatted record = %q, want %q", out, buf.String())
+			}
+		})
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		parsed  bool
+		message string
+	}{
+		{"without attributes", "2026-01-15T10:04:05Z [INFO] Profile prod: token OK", true, "Profile prod: token OK"},
+		{"equals sign in the message", "2026-01-15T10:04:05Z [INFO] a=b c d=e", true, "a=b c"},
+		{"not a log line", "panic: runtime error", false, ""},
+		{"unknown level", "2026-01-15T10:04:05Z [TRACE] message", false, ""},
+		{"JSON without level", `{"time":"2026-01-15T10:04:05Z","message":"x"}`, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ParseLogLine(tt.line)
+			if rec.Parsed() != tt.parsed || rec.Message != tt.message {
+				t.Errorf("ParseLogLine(%q) = parsed %v, message %q; want %v, %q", tt.line, rec.Parsed(), rec.Message, tt.parsed, tt.message)
+			}
+			if !tt.parsed && string(rec.Text()) != tt.line+"\n" {
+				t.Errorf("Text() = %q, want the line as read", rec.Text())
+			}
+		})
+	}
+}
+
+func TestLogFilter(t *testing.T) {
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	rec := ParseLogLine(now.Format(time.RFC3339) + " [WARN] Token expired, login required profile=prod")
+	other := ParseLogLine("panic: runtime error")
+
+	tests := []struct {
+		name      string
+		filter    LogFilter
+		want      bool
+		wantOther bool
+	}{
+		{"no filter", LogFilter{}, true, true},
+		{"level below", LogFilter{Level: LogLevelWarn}, true, false},
+		{"level above", LogFilter{Level: LogLevelError}, false, false},
+		{"profile", LogFilter{Profile: "prod"}, true, false},
+		{"other profile", LogFilter{Profile: "dev"}, false, false},
+		{"since before", LogFilter{Since: now.Add(-time.Hour)}, true, false},
+		{"since after", LogFilter{Since: now.Add(time.Second)}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(&rec); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+			if got := tt.filter.Match(&other); got != tt.wantOther {
+				t.Errorf("Match() of a line that is not a record = %v, want %v", got, tt.wantOther)
+			}
+		})
+	}
+}
+
+// lineCollector collects the lines passed to a LogReader callback.
+type lineCollector struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *lineCollector) add(line string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec := ParseLogLine(line)
+	c.lines = append(c.lines, rec.Attr("n"))
+	return nil
+}
+
+func (c *lineCollector) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.lines)
+}
+
+// sequence returns the strings of 0 to n-1.
+func sequence(n int) []string {
+	s := make([]string, n)
+	for i := range n {
+		s[i] = fmt.Sprint(i)
+	}
+	return s
+}
+
+func TestLogReader_RotatedFiles(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "patrol.log")
+	logger, err := NewLogger(LoggerConfig{Level: LogLevelInfo, FilePath: logFile, MaxSize: 200, Compress: true})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	for i := range 10 {
+		logger.Info("A message long enough to fill the log file quickly", "n", i)
+		// Rotated files are ordered by their modification time.
+		time.Sleep(5 * time.Millisecond)
+	}
+	if rotated, _ := RotatedLogFiles(logFile); len(rotated) < 2 {
+		t.Fatalf("rotated files = %v, want several", rotated)
+	}
+
+	var c lineCollector
+	reader := &LogReader{Path: logFile}
+	if err := reader.Read(context.Background(), c.add); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got, want := c.get(), sequence(10); !slices.Equal(got, want) {
+		t.Errorf("lines = %v, want %v", got, want)
+	}
+}
+
+func TestLogReader_FollowRotation(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "patrol.log")
+	logger, err := NewLogger(LoggerConfig{Level: LogLevelInfo, FilePath: logFile, MaxSize: 200})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+	logger.Info("A message long enough to fill the log file quickly", "n", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var c lineCollector
+	done := make(chan error, 1)
+	go func() {
+		reader := &LogReader{Path: logFile, Follow: true, PollInterval: 5 * time.Millisecond}
+		done <- reader.Read(ctx, c.add)
+	}()
+
+	for i := 1; i < 10; i++ {
+		time.Sleep(10 * time.Millisecond)
+		logger.Info("A message long enough to fill the log file quickly", "n", i)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.get()) < 10 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got, want := c.get(), sequence(10); !slices.Equal(got, want) {
+		t.Errorf("lines = %v, want %v across rotations", got, want)
+	}
+}
+
+func TestJournalCommand(t *testing.T) {
+	since := time.Date(2026, 1, 15, 10, 0, 0, 0, time.Local)
+	got := strings.Join(JournalCommand("patrol.service", true, since), " ")
+	want := "journalctl --user --unit patrol.service --output cat --no-pager --since 2026-01-15 10:00:00 --follow --lines all"
+	if got != want {
+		t.Errorf("JournalCommand() = %q, want %q", got, want)
+	}
+}
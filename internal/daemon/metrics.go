@@ -0,0 +1,339 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+	"github.com/xabinapal/patrol/internal/vault"
+)
+
+// Operations timed by the latency histograms.
+const (
+	opRenewal = "renewal"
+	opLookup  = "lookup"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the latency histogram
+// buckets. Requests run the vault CLI, so they take tens of milliseconds at
+// best and are cut off by renew_timeout at worst.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// serverStatuses are the states reported by vault.HealthExecutor, in the
+// order they are exported.
+var serverStatuses = []string{"healthy", "standby", "sealed", "uninitialized", "unknown", "error"}
+
+// ProfileMetrics is the state of a profile exported by the health server.
+type ProfileMetrics struct {
+	Profile string
+	// HasToken is set while a token of the profile is tracked.
+	HasToken bool
+	// ExpiresAt is when the token expires; zero if it does not.
+	ExpiresAt time.Time
+	Renewable bool
+	// LastSuccess is the time of the last successful renewal, if any.
+	LastSuccess time.Time
+	// ConsecutiveFailures is the number of failed attempts since the last
+	// success.
+	ConsecutiveFailures int
+	// NextRetry is when a failing profile is retried, and LastError why it
+	// last failed.
+	NextRetry time.Time
+	LastError string
+	// NeedsLogin is set when the token expired and needs a new login.
+	NeedsLogin bool
+}
+
+// tokenInfo is what the daemon last learned about the token of a profile.
+type tokenInfo struct {
+	ExpiresAt time.Time
+	Renewable bool
+}
+
+// histogram is a Prometheus histogram over latencyBuckets.
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// observe records a value in seconds.
+func (hg *histogram) observe(v float64) {
+	if hg.counts == nil {
+		hg.counts = make([]uint64, len(latencyBuckets))
+	}
+	for i, bound := range latencyBuckets {
+		if v <= bound {
+			hg.counts[i]++
+			break
+		}
+	}
+	hg.count++
+	hg.sum += v
+}
+
+// latencyKey identifies a latency histogram.
+type latencyKey struct {
+	op      string
+	profile string
+	result  string
+}
+
+// SetProfileSource sets the function returning the per-profile state
+// exported on /metrics and /readyz. It is called on every request.
+func (h *HealthServer) SetProfileSource(source func() []ProfileMetrics) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.profiles = source
+}
+
+// ObserveLatency records how long an operation (renewal or lookup) of a
+// profile took, and whether it failed.
+func (h *HealthServer) ObserveLatency(op, profile string, d time.Duration, err error) {
+	key := latencyKey{op: op, profile: profile, result: "success"}
+	if err != nil {
+		key.result = "failure"
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.latencies == nil {
+		h.latencies = make(map[latencyKey]*histogram)
+	}
+	hg := h.latencies[key]
+	if hg == nil {
+		hg = &histogram{}
+		h.latencies[key] = hg
+	}
+	hg.observe(d.Seconds())
+}
+
+// SetServerHealth records the health of the server of a profile, as
+// reported by vault.HealthExecutor.
+func (h *HealthServer) SetServerHealth(profile, status string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.servers == nil {
+		h.servers = make(map[string]string)
+	}
+	h.servers[profile] = status
+}
+
+// ForgetProfile drops the latencies and server health of a profile that was
+// removed from the config.
+func (h *HealthServer) ForgetProfile(profile string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.servers, profile)
+	for key := range h.latencies {
+		if key.profile == profile {
+			delete(h.latencies, key)
+		}
+	}
+}
+
+// writeProfileMetrics writes the per-profile series. h.mu must be held for
+// reading; profiles is the result of the profile source, called without it.
+func (h *HealthServer) writeProfileMetrics(w io.Writer, profiles []ProfileMetrics, now time.Time) {
+	var expiring []ProfileMetrics
+	for _, p := range profiles {
+		if p.HasToken && !p.ExpiresAt.IsZero() {
+			expiring = append(expiring, p)
+		}
+	}
+
+	fmt.Fprintf(w, "\n# HELP patrol_token_ttl_seconds Remaining lifetime of the token in seconds\n")
+	fmt.Fprintf(w, "# TYPE patrol_token_ttl_seconds gauge\n")
+	for _, p := range expiring {
+		ttl := max(p.ExpiresAt.Sub(now), 0)
+		fmt.Fprintf(w, "patrol_token_ttl_seconds{profile=\"%s\"} %.0f\n", escapeLabel(p.Profile), ttl.Seconds())
+	}
+
+	fmt.Fprintf(w, "\n# HELP patrol_token_expiry_timestamp_seconds Unix timestamp the token expires at\n")
+	fmt.Fprintf(w, "# TYPE patrol_token_expiry_timestamp_seconds gauge\n")
+	for _, p := range expiring {
+		fmt.Fprintf(w, "patrol_token_expiry_timestamp_seconds{profile=\"%s\"} %d\n", escapeLabel(p.Profile), p.ExpiresAt.Unix())
+	}
+
+	fmt.Fprintf(w, "\n# HELP patrol_token_renewable Whether the token can be renewed (1) or not (0)\n")
+	fmt.Fprintf(w, "# TYPE patrol_token_renewable gauge\n")
+	for _, p := range profiles {
+		if p.HasToken {
+			fmt.Fprintf(w, "patrol_token_renewable{profile=\"%s\"} %d\n", escapeLabel(p.Profile), boolValue(p.Renewable))
+		}
+	}
+
+	fmt.Fprintf(w, "\n# HELP patrol_last_renewal_success_timestamp_seconds Unix timestamp of the last successful renewal\n")
+	fmt.Fprintf(w, "# TYPE patrol_last_renewal_success_timestamp_seconds gauge\n")
+	for _, p := range profiles {
+		if !p.LastSuccess.IsZero() {
+			fmt.Fprintf(w, "patrol_last_renewal_success_timestamp_seconds{profile=\"%s\"} %d\n", escapeLabel(p.Profile), p.LastSuccess.Unix())
+		}
+	}
+
+	fmt.Fprintf(w, "\n# HELP patrol_consecutive_failures Failed renewals or checks since the last success\n")
+	fmt.Fprintf(w, "# TYPE patrol_consecutive_failures gauge\n")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "patrol_consecutive_failures{profile=\"%s\"} %d\n", escapeLabel(p.Profile), p.ConsecutiveFailures)
+	}
+
+	fmt.Fprintf(w, "\n# HELP patrol_server_health Health of the server of the profile, one series per status\n")
+	fmt.Fprintf(w, "# TYPE patrol_server_health gauge\n")
+	for _, p := range profiles {
+		status, ok := h.servers[p.Profile]
+		if !ok {
+			continue
+		}
+		for _, s := range serverStatuses {
+			fmt.Fprintf(w, "patrol_server_health{profile=\"%s\",status=\"%s\"} %d\n", escapeLabel(p.Profile), s, boolValue(s == status))
+		}
+	}
+
+	h.writeHistograms(w, opRenewal, "patrol_renewal_duration_seconds", "Duration of token renewals in seconds")
+	h.writeHistograms(w, opLookup, "patrol_lookup_duration_seconds", "Duration of token lookups in seconds")
+}
+
+// writeHistograms writes the latency histograms of op. h.mu must be held
+// for reading.
+func (h *HealthServer) writeHistograms(w io.Writer, op, name, help string) {
+	var keys []latencyKey
+	for key := range h.latencies {
+		if key.op == op {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b latencyKey) int {
+		return strings.Compare(a.profile+"\x00"+a.result, b.profile+"\x00"+b.result)
+	})
+
+	fmt.Fprintf(w, "\n# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for _, key := range keys {
+		hg := h.latencies[key]
+		labels := fmt.Sprintf("profile=\"%s\",result=\"%s\"", escapeLabel(key.profile), key.result)
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += hg.counts[i]
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", name, labels, bound, cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, hg.count)
+		fmt.Fprintf(w, "%s_sum{%s} %g\n", name, labels, hg.sum)
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, hg.count)
+	}
+}
+
+// labelEscaper escapes label values as required by the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value.
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// boolValue returns 1 for true and 0 for false.
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// profileMetrics returns the state of every configured profile for the
+// health server's metrics and readiness.
+func (d *Daemon) profileMetrics() []ProfileMetrics {
+	cfg := d.currentConfig()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	metrics := make([]ProfileMetrics, 0, len(cfg.Connections))
+	for _, conn := range cfg.Connections {
+		m := ProfileMetrics{Profile: conn.Name}
+		if tok, ok := d.tokens[conn.Name]; ok {
+			m.HasToken = true
+			m.ExpiresAt = tok.ExpiresAt
+			m.Renewable = tok.Renewable
+		}
+		if ps := d.states[conn.Name]; ps != nil {
+			m.LastSuccess = ps.LastSuccess
+			m.ConsecutiveFailures = ps.FailureCount
+			m.NextRetry = ps.NextRetry
+			m.LastError = ps.LastError
+			m.NeedsLogin = ps.NeedsLogin
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// setTokenInfo records the token of profile name for the health server.
+func (d *Daemon) setTokenInfo(name string, tok *types.Token) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tokens[name] = tokenInfo{ExpiresAt: tok.ExpiresAt, Renewable: tok.Renewable}
+}
+
+// forgetTokenInfo records that profile name has no token anymore.
+func (d *Daemon) forgetTokenInfo(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.tokens, name)
+}
+
+// observeLatency records the duration of a renewal or lookup of profile
+// name that started at start, if the health server is enabled.
+func (d *Daemon) observeLatency(op, name string, start time.Time, err error) {
+	if hs := d.health(); hs != nil {
+		hs.ObserveLatency(op, name, time.Since(start), err)
+	}
+}
+
+// checkServers refreshes the server health exported by the health server
+// in the background. Servers shared by several profiles are checked once;
+// a check still running from the previous rescan is not started again.
+func (d *Daemon) checkServers(ctx context.Context, cfg *config.Config) {
+	hs := d.health()
+	if hs == nil {
+		return
+	}
+
+	d.mu.Lock()
+	if d.checkingServers {
+		d.mu.Unlock()
+		return
+	}
+	d.checkingServers = true
+	d.mu.Unlock()
+
+	d.workers.Add(1)
+	go func() {
+		defer d.workers.Done()
+		defer func() {
+			d.mu.Lock()
+			d.checkingServers = false
+			d.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(ctx, renewTimeout(cfg))
+		defer cancel()
+
+		checker := vault.NewHealthExecutor()
+		checked := make(map[string]string)
+		for _, conn := range cfg.Connections {
+			status, ok := checked[conn.Address]
+			if !ok {
+				status = checker.CheckHealth(ctx, types.FromConnection(&conn)).Status
+				if shuttingDown(ctx) {
+					return
+				}
+				checked[conn.Address] = status
+			}
+			hs.SetServerHealth(conn.Name, status)
+		}
+	}()
+}
@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Readiness statuses of the daemon and of its profiles.
+const (
+	// ReadyOK means every token is valid and renewing.
+	ReadyOK = "ok"
+	// ReadyDegraded means some profiles are failing or backing off.
+	ReadyDegraded = "degraded"
+	// ReadyFailing means no profile has a usable token.
+	ReadyFailing = "failing"
+	// ReadyStarting means the token store has not been scanned yet.
+	ReadyStarting = "starting"
+	// ReadyNoToken is the status of profiles without a token, which do not
+	// affect readiness.
+	ReadyNoToken = "no_token"
+)
+
+// ReadinessStatus represents the /readyz response.
+type ReadinessStatus struct {
+	Status   string             `json:"status"`
+	Profiles []ProfileReadiness `json:"profiles"`
+}
+
+// ProfileReadiness is the readiness of a single profile.
+type ProfileReadiness struct {
+	Profile             string    `json:"profile"`
+	Status              string    `json:"status"`
+	Reason              string    `json:"reason,omitempty"`
+	ExpiresAt           time.Time `json:"expires_at,omitzero"`
+	TTLSeconds          int64     `json:"ttl_seconds,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	NextRetry           time.Time `json:"next_retry,omitzero"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+// profileReadiness returns the readiness of p: failing once its token
+// expired, degraded while its renewals fail and back off.
+func profileReadiness(p ProfileMetrics, now time.Time) ProfileReadiness {
+	r := ProfileReadiness{Profile: p.Profile, Status: ReadyOK}
+	if !p.HasToken {
+		r.Status = ReadyNoToken
+		r.Reason = "no token stored"
+		return r
+	}
+
+	r.ExpiresAt = p.ExpiresAt
+	if !p.ExpiresAt.IsZero() {
+		r.TTLSeconds = int64(max(p.ExpiresAt.Sub(now), 0).Seconds())
+	}
+	if p.ConsecutiveFailures > 0 {
+		r.ConsecutiveFailures = p.ConsecutiveFailures
+		r.NextRetry = p.NextRetry
+		r.LastError = p.LastError
+	}
+
+	switch {
+	case p.NeedsLogin || !p.ExpiresAt.IsZero() && !p.ExpiresAt.After(now):
+		r.Status = ReadyFailing
+		r.Reason = "token expired, login required"
+	case p.ConsecutiveFailures > 0:
+		r.Status = ReadyDegraded
+		r.Reason = "renewal failing, backing off"
+	}
+	return r
+}
+
+// readiness returns the readiness of the daemon from the state of its
+// profiles: failing if every profile with a token is failing, degraded if
+// any is failing or degraded, and starting until the first rescan.
+func (h *HealthServer) readiness(profiles []ProfileMetrics, now time.Time) ReadinessStatus {
+	h.mu.RLock()
+	checked := !h.lastCheck.IsZero()
+	h.mu.RUnlock()
+
+	status := ReadinessStatus{Status: ReadyOK, Profiles: make([]ProfileReadiness, 0, len(profiles))}
+	var managed, failing, degraded int
+	for _, p := range profiles {
+		r := profileReadiness(p, now)
+		status.Profiles = append(status.Profiles, r)
+		switch r.Status {
+		case ReadyNoToken:
+			continue
+		case ReadyFailing:
+			failing++
+		case ReadyDegraded:
+			degraded++
+		}
+		managed++
+	}
+
+	switch {
+	case !checked:
+		status.Status = ReadyStarting
+	case managed > 0 && failing == managed:
+		status.Status = ReadyFailing
+	case failing > 0 || degraded > 0:
+		status.Status = ReadyDegraded
+	}
+	return status
+}
+
+// profileSnapshot returns the state of every profile from the profile
+// source. The source takes the daemon lock, so it is called without ours.
+func (h *HealthServer) profileSnapshot() []ProfileMetrics {
+	h.mu.RLock()
+	source := h.profiles
+	h.mu.RUnlock()
+	if source == nil {
+		return nil
+	}
+	return source()
+}
+
+// handleLive reports that the daemon is running. It does not depend on the
+// state of the tokens, so a supervisor does not restart the daemon for a
+// server outage.
+func (h *HealthServer) handleLive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": ReadyOK}); err != nil {
+		// Encoding error - response may be partially written
+		_ = err
+	}
+}
+
+// handleReady reports the readiness of the daemon with a per-profile
+// breakdown. It fails with 503 while starting and when every token is
+// failing; a degraded daemon is still ready.
+func (h *HealthServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	status := h.readiness(h.profileSnapshot(), time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	if status.Status == ReadyStarting || status.Status == ReadyFailing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		// Encoding error - response may be partially written
+		_ = err
+	}
+}
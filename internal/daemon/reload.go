@@ -0,0 +1,308 @@
+package daemon
+
+import (
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/notify"
+)
+
+// configPollInterval is how often the config file is checked for changes.
+const configPollInterval = 2 * time.Second
+
+// Overrides holds daemon settings given on the command line. They take
+// precedence over the configuration file and are kept across reloads.
+type Overrides struct {
+	LogLevel   string
+	LogFile    string
+	LogJSON    *bool
+	HealthAddr string
+}
+
+// SetOverrides sets the command line settings that take precedence over the
+// configuration file. It must be called before Run.
+func (d *Daemon) SetOverrides(o Overrides) {
+	d.overrides = o
+}
+
+// EffectiveLoggerConfig returns the logger settings from the current
+// configuration with the command line overrides applied.
+func (d *Daemon) EffectiveLoggerConfig() (LoggerConfig, error) {
+	return d.loggerConfigFor(d.currentConfig())
+}
+
+// EffectiveHealthAddr returns the health endpoint address from the current
+// configuration with the command line overrides applied, or "" if disabled.
+func (d *Daemon) EffectiveHealthAddr() string {
+	return d.healthAddrFor(d.currentConfig())
+}
+
+// loggerConfigFor returns the logger settings for cfg.
+func (d *Daemon) loggerConfigFor(cfg *config.Config) (LoggerConfig, error) {
+	levelName := cfg.Daemon.LogLevel
+	if d.overrides.LogLevel != "" {
+		levelName = d.overrides.LogLevel
+	}
+	level, err := ParseLogLevel(levelName)
+	if err != nil {
+		return LoggerConfig{}, err
+	}
+
+	lc := LoggerConfig{
+		Level:          level,
+		FilePath:       cfg.Daemon.LogFile,
+		JSONMode:       cfg.Daemon.LogJSON,
+		MaxSize:        int64(cfg.Daemon.LogMaxSize) * 1024 * 1024,
+		MaxFiles:       cfg.Daemon.GetLogMaxFiles(),
+		Compress:       cfg.Daemon.LogCompress,
+		RotateInterval: cfg.Daemon.LogRotateInterval,
+		Sink:           cfg.Daemon.LogSink,
+		Syslog:         cfg.Daemon.Syslog,
+	}
+	if d.overrides.LogFile != "" {
+		lc.FilePath = d.overrides.LogFile
+		lc.Sink = ""
+	}
+	if d.overrides.LogJSON != nil {
+		lc.JSONMode = *d.overrides.LogJSON
+	}
+	return lc, nil
+}
+
+// healthAddrFor returns the health endpoint address for cfg.
+func (d *Daemon) healthAddrFor(cfg *config.Config) string {
+	if d.overrides.HealthAddr != "" {
+		return d.overrides.HealthAddr
+	}
+	return cfg.Daemon.HealthEndpoint
+}
+
+// configWatcher detects changes to the config file by polling its identity
+// (inode), size and modification time. Editors that save by replacing the
+// file are detected even when size and time happen to match.
+type configWatcher struct {
+	path string
+	last os.FileInfo
+}
+
+// newConfigWatcher creates a watcher for the file at path, taking its
+// current state as unchanged.
+func newConfigWatcher(path string) *configWatcher {
+	w := &configWatcher{path: path}
+	w.last, _ = os.Stat(path) //nolint:errcheck // a missing file is detected when it appears
+	return w
+}
+
+// Changed reports whether the file was modified, replaced or created since
+// the last call. A missing file is not a change: it is usually being
+// replaced, and reloading would drop every profile.
+func (w *configWatcher) Changed() bool {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false
+	}
+
+	prev := w.last
+	w.last = info
+	if prev == nil {
+		return true
+	}
+	return !os.SameFile(prev, info) || !info.ModTime().Equal(prev.ModTime()) || info.Size() != prev.Size()
+}
+
+// reloadConfig loads the configuration file and applies the settings that
+// changed. It returns the previous and the new configuration; if loading
+// fails, the previous configuration stays in effect.
+func (d *Daemon) reloadConfig() (old, cur *config.Config, err error) {
+	old = d.currentConfig()
+	cur, err = config.LoadFrom(d.configPath)
+	if err != nil {
+		d.logger.Warn("Failed to reload config, using previous config", "error", err)
+		return old, old, err
+	}
+
+	d.mu.Lock()
+	d.config = cur
+	// Forget pause requests for profiles that no longer exist.
+	for name := range d.paused {
+		if _, err := cur.GetConnection(name); err != nil {
+			delete(d.paused, name)
+		}
+	}
+	d.mu.Unlock()
+
+	d.applyConfig(old, cur)
+	return old, cur, nil
+}
+
+// applyConfig applies the differences between old and cur to the logger,
+// health server, notifier and renewal schedule. Changes to the renewal loop
+// itself (rescan interval and concurrency) are applied by Run.
+func (d *Daemon) applyConfig(old, cur *config.Config) {
+	if oldLog, err := d.loggerConfigFor(old); err == nil {
+		newLog, err := d.loggerConfigFor(cur)
+		switch {
+		case err != nil:
+			d.logger.Warn("Ignoring logging settings", "error", err)
+		case newLog != oldLog:
+			if err := d.logger.Reconfigure(newLog); err != nil {
+				d.logger.Warn("Failed to apply logging settings", "error", err)
+			} else {
+				d.logger.Info("Logging settings updated")
+			}
+		}
+	}
+
+	if oldAddr, newAddr := d.healthAddrFor(old), d.healthAddrFor(cur); newAddr != oldAddr || cur.Daemon.Health != old.Daemon.Health {
+		d.restartHealthServer(newAddr, cur.Daemon.Health)
+	}
+
+	if !reflect.DeepEqual(cur.Daemon.Notifications, old.Daemon.Notifications) || cur.Daemon.Syslog != old.Daemon.Syslog {
+		d.mu.Lock()
+		prev := d.notifier
+		// Notifications held back by deduplication and the rate limit stay
+		// held back.
+		d.notifier = d.newNotifier(cur, notify.WithStateFrom(prev))
+		d.mu.Unlock()
+		d.logger.Info("Notification settings updated")
+
+		// The deliveries of the previous notifier finish in the background;
+		// Run waits for them on exit.
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			d.drainNotifier(prev)
+		}()
+	}
+
+	d.logProfileChanges(old, cur)
+
+	// auto_login decides between renewing and logging in again, so affected
+	// profiles are rescheduled.
+	for _, conn := range cur.Connections {
+		prev, err := old.GetConnection(conn.Name)
+		if err == nil && prev.AutoLogin != conn.AutoLogin && !d.isInFlight(conn.Name) && !d.isPaused(conn.Name) {
+			d.queue.Remove(conn.Name)
+		}
+	}
+
+	if cur.Daemon.RenewThreshold != old.Daemon.RenewThreshold || cur.Daemon.MinRenewTTL != old.Daemon.MinRenewTTL {
+		d.logger.Info("Renewal timing changed, rescheduling all profiles",
+			"renew_threshold", cur.Daemon.RenewThreshold, "min_renew_ttl", cur.Daemon.MinRenewTTL)
+		d.requeueAll()
+	}
+
+	if cur.Daemon.PIDFile != old.Daemon.PIDFile {
+		d.logger.Warn("The pid_file setting changes after the daemon restarts")
+	}
+	if cur.Daemon.ControlSocket != old.Daemon.ControlSocket {
+		d.logger.Warn("The control_socket setting changes after the daemon restarts")
+	}
+}
+
+// logProfileChanges logs profiles that were added, removed or modified.
+func (d *Daemon) logProfileChanges(old, cur *config.Config) {
+	previous := make(map[string]config.Connection, len(old.Connections))
+	for _, conn := range old.Connections {
+		previous[conn.Name] = conn
+	}
+
+	for _, conn := range cur.Connections {
+		prevConn, ok := previous[conn.Name]
+		switch {
+		case !ok:
+			d.logger.Info("Detected new profile", "profile", conn.Name)
+		case prevConn != conn:
+			d.logger.Info("Connection settings changed", "profile", conn.Name)
+		}
+		delete(previous, conn.Name)
+	}
+
+	// Remaining profiles were removed
+	for name := range previous {
+		d.logger.Info("Profile removed from config", "profile", name)
+	}
+}
+
+// requeueAll forgets the schedule of every profile that is neither being
+// renewed nor paused, so the next rescan schedules them with the current
+// settings.
+func (d *Daemon) requeueAll() {
+	for _, name := range d.queue.Profiles() {
+		if !d.isInFlight(name) && !d.isPaused(name) {
+			d.queue.Remove(name)
+		}
+	}
+}
+
+// restartHealthServer moves the health endpoint to addr with the TLS and
+// authentication settings of cfg, starting it if it was disabled and
+// stopping it if addr is empty. Counters are kept.
+func (d *Daemon) restartHealthServer(addr string, cfg config.HealthConfig) {
+	hs := d.health()
+
+	switch {
+	case addr == "" && hs == nil:
+		return
+	case addr == "":
+		if err := hs.Stop(); err != nil {
+			d.logger.Warn("Failed to stop health server", "error", err)
+		}
+		d.mu.Lock()
+		d.healthServer = nil
+		d.mu.Unlock()
+		d.logger.Info("Health server stopped")
+	case hs == nil:
+		hs = NewHealthServer(addr, WithHealthConfig(cfg))
+		hs.SetProfileSource(d.profileMetrics)
+		d.mu.Lock()
+		hs.SetTotals(d.totals.Renewals, d.totals.Errors)
+		d.mu.Unlock()
+		if err := hs.Start(); err != nil {
+			d.logger.Warn("Failed to start health server", "error", err)
+			return
+		}
+		d.mu.Lock()
+		d.healthServer = hs
+		d.mu.Unlock()
+		d.logger.Info("Health server started", "address", hs.Addr())
+	default:
+		moved := hs.Addr() != normalizeHealthAddr(addr)
+		if err := hs.Restart(addr, WithHealthConfig(cfg)); err != nil {
+			if hs.running() {
+				d.logger.Warn("Failed to update health server, keeping the previous settings", "address", hs.Addr(), "error", err)
+				return
+			}
+			// The address was released but could not be listened on
+			// again, so the server is down; the next reload starts a new one.
+			d.logger.Warn("Failed to restart health server", "error", err)
+			d.mu.Lock()
+			d.healthServer = nil
+			d.mu.Unlock()
+			return
+		}
+		if moved {
+			d.logger.Info("Health server moved", "address", hs.Addr())
+		} else {
+			d.logger.Info("Health server settings updated", "address", hs.Addr())
+		}
+	}
+}
+
+// health returns the health server, which may be replaced by a reload while
+// workers are running, or nil if it is disabled.
+func (d *Daemon) health() *HealthServer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.healthServer
+}
+
+// checkInterval returns the configured rescan interval.
+func checkInterval(cfg *config.Config) time.Duration {
+	if cfg.Daemon.CheckInterval <= 0 {
+		return time.Minute
+	}
+	return cfg.Daemon.CheckInterval
+}
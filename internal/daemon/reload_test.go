@@ -0,0 +1,199 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	w := newConfigWatcher(path)
+	if w.Changed() {
+		t.Error("missing file should not be reported as changed")
+	}
+
+	if err := os.WriteFile(path, []byte("current: a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Changed() {
+		t.Error("created file should be reported as changed")
+	}
+	if w.Changed() {
+		t.Error("unchanged file should not be reported as changed")
+	}
+
+	if err := os.WriteFile(path, []byte("current: bb\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Changed() {
+		t.Error("modified file should be reported as changed")
+	}
+
+	// Replace the file with one of the same size and modification time.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte("current: cc\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Changed() {
+		t.Error("replaced file should be reported as changed")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if w.Changed() {
+		t.Error("removed file should not be reported as changed")
+	}
+}
+
+// drainingNotifier records whether its deliveries were drained.
+type drainingNotifier struct {
+	recordingNotifier
+	drained atomic.Bool
+}
+
+func (n *drainingNotifier) Drain(ctx context.Context) error {
+	n.drained.Store(true)
+	return nil
+}
+
+func TestReloadConfig(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	dir := t.TempDir()
+	d.configPath = filepath.Join(dir, "config.yaml")
+	logPath := filepath.Join(dir, "daemon.log")
+
+	d.queue.Schedule("profile-0", time.Now().Add(time.Hour), time.Now().Add(time.Minute))
+	oldNotifier := &drainingNotifier{}
+	d.notifier = oldNotifier
+
+	content := `connections:
+  - name: profile-0
+    address: ` + cfg.Connections[0].Address + `
+  - name: added
+    address: http://127.0.0.1:2
+daemon:
+  renew_threshold: 0.5
+  log_file: ` + logPath + `
+  log_level: debug
+  health_endpoint: localhost:0
+  notifications:
+    enabled: true
+    on_failure: true
+`
+	if err := os.WriteFile(d.configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	old, cur, err := d.reloadConfig()
+	if err != nil {
+		t.Fatalf("reloadConfig() failed: %v", err)
+	}
+	if old != cfg || d.currentConfig() != cur {
+		t.Fatal("reloadConfig() should replace the current config")
+	}
+	t.Cleanup(func() {
+		if hs := d.health(); hs != nil {
+			hs.Stop()
+		}
+		d.logger.Close()
+	})
+
+	if len(cur.Connections) != 2 {
+		t.Errorf("got %d connections, want 2", len(cur.Connections))
+	}
+	if _, ok := d.queue.Tracked("profile-0"); ok {
+		t.Error("changed renewal threshold should clear the schedule")
+	}
+	if d.currentNotifier() == oldNotifier {
+		t.Error("changed notification settings should rebuild the notifier")
+	}
+	d.workers.Wait()
+	if !oldNotifier.drained.Load() {
+		t.Error("the replaced notifier should be drained")
+	}
+	if d.health() == nil {
+		t.Error("health endpoint in config should start the health server")
+	}
+	if d.logger.GetLevel() != LogLevelDebug {
+		t.Errorf("log level = %v, want debug", d.logger.GetLevel())
+	}
+	d.logger.Info("written to the new log file")
+	if data, err := os.ReadFile(logPath); err != nil || len(data) == 0 {
+		t.Errorf("log file not written after reload: %v", err)
+	}
+
+	// Command line overrides win over the reloaded config.
+	d.SetOverrides(Overrides{LogLevel: "error"})
+	if lc, err := d.EffectiveLoggerConfig(); err != nil || lc.Level != LogLevelError {
+		t.Errorf("EffectiveLoggerConfig() level = %v (%v), want error", lc.Level, err)
+	}
+
+	// A broken file keeps the previous config.
+	if err := os.WriteFile(d.configPath, []byte("connections: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := d.reloadConfig(); err == nil {
+		t.Error("reloadConfig() should fail for an invalid file")
+	}
+	if d.currentConfig() != cur {
+		t.Error("failed reload should keep the previous config")
+	}
+}
+
+func TestRestartHealthServerFailure(t *testing.T) {
+	d, _ := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	path := filepath.Join(t.TempDir(), "health.sock")
+	addr := "unix:" + path
+
+	d.restartHealthServer(addr, config.HealthConfig{})
+	if d.health() == nil {
+		t.Fatal("health server should be started")
+	}
+
+	// Settings that cannot be loaded leave the server running as it was.
+	hs := d.health()
+	broken := config.HealthConfig{TLSCert: "/nonexistent/cert.pem", TLSKey: "/nonexistent/key.pem"}
+	d.restartHealthServer(addr, broken)
+	if d.health() != hs {
+		t.Fatal("a health server with broken settings should be kept")
+	}
+	if hs.URL() != addr {
+		t.Errorf("URL() = %q, want the previous plain settings kept", hs.URL())
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://patrol/livez")
+	if err != nil {
+		t.Fatalf("health server should still answer: %v", err)
+	}
+	resp.Body.Close()
+	client.CloseIdleConnections()
+
+	if err := hs.Stop(); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package daemon
+
+import (
+	"container/heap"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+const (
+	// renewJitterFraction is the largest share of the time until a renewal
+	// that jitter may move it earlier.
+	renewJitterFraction = 0.1
+	// maxRenewJitter caps the jitter applied to a single renewal.
+	maxRenewJitter = 5 * time.Minute
+)
+
+// nextRenewalTime returns when tok should be renewed: the earlier of the
+// moment threshold of its lease has elapsed and the moment its remaining TTL
+// drops below minTTL. The minTTL rule is ignored for leases shorter than
+// minTTL, which would otherwise be renewed continuously. It returns false if
+// the token cannot or need not be renewed.
+func nextRenewalTime(tok *types.Token, threshold float64, minTTL time.Duration, now time.Time) (time.Time, bool) {
+	if !tok.Renewable || tok.ExpiresAt.IsZero() || !tok.ExpiresAt.After(now) {
+		return time.Time{}, false
+	}
+
+	at := tok.ExpiresAt.Add(-minTTL)
+	lease := time.Duration(tok.LeaseDuration) * time.Second
+	if lease > 0 {
+		if minTTL >= lease {
+			at = tok.ExpiresAt
+		}
+		byThreshold := tok.ExpiresAt.Add(-time.Duration(float64(lease) * (1 - threshold)))
+		if byThreshold.Before(at) {
+			at = byThreshold
+		}
+	}
+
+	if at.Before(now) {
+		at = now
+	}
+	return at, true
+}
+
+// withJitter moves at earlier by a random amount of up to renewJitterFraction
+// of the time left until it, capped at maxRenewJitter. Renewals are never
+// delayed, only spread out so profiles sharing a server do not renew at once.
+func withJitter(at, now time.Time) time.Time {
+	lead := at.Sub(now)
+	if lead <= 0 {
+		return at
+	}
+
+	maxJitter := time.Duration(float64(lead) * renewJitterFraction)
+	if maxJitter > maxRenewJitter {
+		maxJitter = maxRenewJitter
+	}
+	if maxJitter <= 0 {
+		return at
+	}
+
+	// #nosec G404 - jitter does not need a cryptographically secure source
+	return at.Add(-rand.N(maxJitter))
+}
+
+// scheduleItem is a profile tracked by the renewal queue.
+type scheduleItem struct {
+	profile string
+	// expiresAt is the token expiry the schedule was computed from, used to
+	// detect tokens replaced outside the daemon.
+	expiresAt time.Time
+	// at is when the profile is due; zero if it is tracked but not scheduled.
+	at time.Time
+	// renewAt is when the renewal or login scheduled for the token expiring
+	// at expiresAt is due, jitter included; zero if none was scheduled.
+	renewAt time.Time
+	// index is the position in the heap, or -1 if not scheduled.
+	index int
+}
+
+// scheduleHeap orders scheduled items by due time.
+type scheduleHeap []*scheduleItem
+
+func (h scheduleHeap) Len() int           { return len(h) }
+func (h scheduleHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x any) {
+	item := x.(*scheduleItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *scheduleHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// renewalQueue is a priority queue of profiles keyed by their next renewal
+// time. Profiles can be tracked without being scheduled, for tokens that do
+// not need renewal, so unchanged tokens are not re-evaluated on every rescan.
+type renewalQueue struct {
+	mu    sync.Mutex
+	heap  scheduleHeap
+	items map[string]*scheduleItem
+}
+
+// newRenewalQueue creates an empty renewal queue.
+func newRenewalQueue() *renewalQueue {
+	return &renewalQueue{items: make(map[string]*scheduleItem)}
+}
+
+// Schedule tracks profile with the given token expiry and makes it due at at.
+// A zero at tracks the profile without scheduling it. Due times are kept as
+// wall clock times, so renewals that became due while the system was
+// suspended (when the monotonic clock stops) are found right after resume.
+func (q *renewalQueue) Schedule(profile string, expiresAt, at time.Time) {
+	q.schedule(profile, expiresAt, at, false)
+}
+
+// ScheduleRenewal is like Schedule, and also records at as the time the
+// renewal (or login) of this token is due, so RenewalDue reports it due
+// from then on even if jitter brought it before the renewal threshold.
+func (q *renewalQueue) ScheduleRenewal(profile string, expiresAt, at time.Time) {
+	q.schedule(profile, expiresAt, at, true)
+}
+
+func (q *renewalQueue) schedule(profile string, expiresAt, at time.Time, renewal bool) {
+	at = at.Round(0)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[profile]
+	if !ok {
+		item = &scheduleItem{profile: profile, index: -1}
+		q.items[profile] = item
+	}
+	switch {
+	case renewal:
+		item.renewAt = at
+	case !item.expiresAt.Equal(expiresAt):
+		// The renewal time belonged to the replaced token.
+		item.renewAt = time.Time{}
+	}
+	item.expiresAt = expiresAt
+	item.at = at
+
+	switch {
+	case at.IsZero() && item.index >= 0:
+		heap.Remove(&q.heap, item.index)
+	case at.IsZero():
+	case item.index >= 0:
+		heap.Fix(&q.heap, item.index)
+	default:
+		heap.Push(&q.heap, item)
+	}
+}
+
+// Reschedule makes a tracked profile due at at, keeping its token expiry.
+// Untracked profiles are tracked with an unknown expiry.
+func (q *renewalQueue) Reschedule(profile string, at time.Time) {
+	expiresAt, _ := q.Tracked(profile)
+	q.Schedule(profile, expiresAt, at)
+}
+
+// Tracked returns the token expiry profile was scheduled with, and whether
+// the profile is tracked at all.
+func (q *renewalQueue) Tracked(profile string) (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[profile]
+	if !ok {
+		return time.Time{}, false
+	}
+	return item.expiresAt, true
+}
+
+// Entry returns the token expiry profile was scheduled with and when it is
+// due (zero if it is not scheduled), and whether the profile is tracked.
+func (q *renewalQueue) Entry(profile string) (expiresAt, at time.Time, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[profile]
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return item.expiresAt, item.at, true
+}
+
+// RenewalDue reports whether a renewal or login was scheduled for profile
+// with the token expiring at expiresAt, and its time has come by now.
+func (q *renewalQueue) RenewalDue(profile string, expiresAt, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[profile]
+	if !ok || item.renewAt.IsZero() || expiresAt.IsZero() {
+		return false
+	}
+	return item.expiresAt.Equal(expiresAt) && !now.Before(item.renewAt)
+}
+
+// Remove stops tracking profile.
+func (q *renewalQueue) Remove(profile string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[profile]
+	if !ok {
+		return
+	}
+	if item.index >= 0 {
+		heap.Remove(&q.heap, item.index)
+	}
+	delete(q.items, profile)
+}
+
+// Profiles returns the names of all tracked profiles.
+func (q *renewalQueue) Profiles() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	names := make([]string, 0, len(q.items))
+	for name := range q.items {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Next returns the profile due first and when it is due.
+func (q *renewalQueue) Next() (string, time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.heap) == 0 {
+		return "", time.Time{}, false
+	}
+	return q.heap[0].profile, q.heap[0].at, true
+}
+
+// PopDue unschedules and returns all profiles due at or before now, earliest
+// first. They stay tracked until they are scheduled again or removed.
+func (q *renewalQueue) PopDue(now time.Time) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due []string
+	for len(q.heap) > 0 && !q.heap[0].at.After(now) {
+		item := heap.Pop(&q.heap).(*scheduleItem)
+		item.at = time.Time{}
+		due = append(due, item.profile)
+	}
+	return due
+}
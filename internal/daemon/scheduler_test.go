@@ -0,0 +1,223 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestNextRenewalTime(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		tok       types.Token
+		threshold float64
+		minTTL    time.Duration
+		want      time.Duration // offset from now
+		wantOK    bool
+	}{
+		{
+			name:      "threshold before min TTL",
+			tok:       types.Token{LeaseDuration: 3600, Renewable: true, ExpiresAt: now.Add(time.Hour)},
+			threshold: 0.75,
+			minTTL:    5 * time.Minute,
+			want:      45 * time.Minute,
+			wantOK:    true,
+		},
+		{
+			name:      "min TTL before threshold",
+			tok:       types.Token{LeaseDuration: 3600, Renewable: true, ExpiresAt: now.Add(time.Hour)},
+			threshold: 0.95,
+			minTTL:    10 * time.Minute,
+			want:      50 * time.Minute,
+			wantOK:    true,
+		},
+		{
+			name:      "short lease ignores min TTL",
+			tok:       types.Token{LeaseDuration: 10, Renewable: true, ExpiresAt: now.Add(10 * time.Second)},
+			threshold: 0.75,
+			minTTL:    5 * time.Minute,
+			want:      7500 * time.Millisecond,
+			wantOK:    true,
+		},
+		{
+			name:      "long lease",
+			tok:       types.Token{LeaseDuration: 30 * 24 * 3600, Renewable: true, ExpiresAt: now.Add(30 * 24 * time.Hour)},
+			threshold: 0.75,
+			minTTL:    5 * time.Minute,
+			want:      time.Duration(0.75 * float64(30*24*time.Hour)),
+			wantOK:    true,
+		},
+		{
+			name:      "partially elapsed lease",
+			tok:       types.Token{LeaseDuration: 3600, Renewable: true, ExpiresAt: now.Add(30 * time.Minute)},
+			threshold: 0.75,
+			minTTL:    5 * time.Minute,
+			want:      15 * time.Minute,
+			wantOK:    true,
+		},
+		{
+			name:      "overdue renews now",
+			tok:       types.Token{LeaseDuration: 3600, Renewable: true, ExpiresAt: now.Add(time.Minute)},
+			threshold: 0.75,
+			minTTL:    5 * time.Minute,
+			want:      0,
+			wantOK:    true,
+		},
+		{
+			name:      "unknown lease uses min TTL",
+			tok:       types.Token{Renewable: true, ExpiresAt: now.Add(time.Hour)},
+			threshold: 0.75,
+			minTTL:    5 * time.Minute,
+			want:      55 * time.Minute,
+			wantOK:    true,
+		},
+		{
+			name:   "not renewable",
+			tok:    types.Token{LeaseDuration: 3600, ExpiresAt: now.Add(time.Hour)},
+			wantOK: false,
+		},
+		{
+			name:   "no expiry",
+			tok:    types.Token{Renewable: true},
+			wantOK: false,
+		},
+		{
+			name:   "expired",
+			tok:    types.Token{LeaseDuration: 3600, Renewable: true, ExpiresAt: now.Add(-time.Second)},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextRenewalTime(&tt.tok, tt.threshold, tt.minTTL, now)
+			if ok != tt.wantOK {
+				t.Fatalf("nextRenewalTime() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !got.Equal(now.Add(tt.want)) {
+				t.Errorf("nextRenewalTime() = now+%s, want now+%s", got.Sub(now), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		lead      time.Duration
+		maxJitter time.Duration
+	}{
+		{"short", 10 * time.Second, time.Second},
+		{"capped", 30 * 24 * time.Hour, maxRenewJitter},
+		{"due", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := now.Add(tt.lead)
+			for range 100 {
+				got := withJitter(at, now)
+				if got.After(at) {
+					t.Fatalf("withJitter() delayed the renewal by %s", got.Sub(at))
+				}
+				if at.Sub(got) > tt.maxJitter {
+					t.Fatalf("withJitter() moved the renewal by %s, max %s", at.Sub(got), tt.maxJitter)
+				}
+			}
+		})
+	}
+}
+
+func TestRenewalQueue(t *testing.T) {
+	now := time.Now()
+	q := newRenewalQueue()
+
+	if _, _, ok := q.Next(); ok {
+		t.Fatal("Next() on empty queue should return false")
+	}
+
+	expiry := now.Add(time.Hour)
+	q.Schedule("slow", expiry, now.Add(30*time.Minute))
+	q.Schedule("fast", expiry, now.Add(time.Second))
+	q.Schedule("idle", expiry, time.Time{})
+	q.Schedule("soon", expiry, now.Add(time.Minute))
+
+	name, at, ok := q.Next()
+	if !ok || name != "fast" || !at.Equal(now.Add(time.Second)) {
+		t.Fatalf("Next() = %s at %v, want fast", name, at)
+	}
+
+	// Moving a profile earlier reorders the queue.
+	q.Reschedule("slow", now.Add(-time.Second))
+	if name, _, _ := q.Next(); name != "slow" {
+		t.Fatalf("Next() after Reschedule() = %s, want slow", name)
+	}
+	if exp, ok := q.Tracked("slow"); !ok || !exp.Equal(expiry) {
+		t.Errorf("Reschedule() changed the tracked expiry to %v", exp)
+	}
+
+	due := q.PopDue(now.Add(2 * time.Second))
+	if len(due) != 2 || due[0] != "slow" || due[1] != "fast" {
+		t.Fatalf("PopDue() = %v, want [slow fast]", due)
+	}
+	if _, ok := q.Tracked("fast"); !ok {
+		t.Error("PopDue() should keep profiles tracked")
+	}
+	if name, _, _ := q.Next(); name != "soon" {
+		t.Errorf("Next() after PopDue() = %s, want soon", name)
+	}
+
+	if _, ok := q.Tracked("idle"); !ok {
+		t.Error("profile scheduled with zero time should be tracked")
+	}
+	if len(q.Profiles()) != 4 {
+		t.Errorf("Profiles() = %v, want 4 profiles", q.Profiles())
+	}
+
+	// Unscheduling and removing.
+	q.Schedule("soon", expiry, time.Time{})
+	if _, _, ok := q.Next(); ok {
+		t.Error("Next() should be empty after unscheduling the last profile")
+	}
+	q.Remove("soon")
+	q.Remove("missing")
+	if _, ok := q.Tracked("soon"); ok {
+		t.Error("Remove() should stop tracking the profile")
+	}
+}
+
+func TestRenewalQueueRenewalDue(t *testing.T) {
+	q := newRenewalQueue()
+	now := time.Now()
+	expiry := now.Add(time.Hour)
+
+	q.ScheduleRenewal("p", expiry, now.Add(time.Minute))
+	if q.RenewalDue("p", expiry, now) {
+		t.Error("RenewalDue() before the scheduled time should be false")
+	}
+	if !q.RenewalDue("p", expiry, now.Add(time.Minute)) {
+		t.Error("RenewalDue() at the scheduled time should be true")
+	}
+
+	// A retry keeps the renewal due.
+	q.PopDue(now.Add(time.Minute))
+	q.Reschedule("p", now.Add(2*time.Minute))
+	if !q.RenewalDue("p", expiry, now.Add(2*time.Minute)) {
+		t.Error("RenewalDue() after Reschedule() should still be true")
+	}
+
+	// A replaced token is not due until its own renewal is scheduled.
+	newExpiry := expiry.Add(time.Hour)
+	if q.RenewalDue("p", newExpiry, now.Add(2*time.Minute)) {
+		t.Error("RenewalDue() for another token should be false")
+	}
+	q.Schedule("p", newExpiry, now)
+	if q.RenewalDue("p", newExpiry, now.Add(time.Hour)) {
+		t.Error("Schedule() with a new token should clear the renewal time")
+	}
+}
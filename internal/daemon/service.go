@@ -24,6 +24,17 @@ type ServiceManager interface {
 	Status() (ServiceStatus, error)
 	// ServiceFilePath returns the path to the service definition file.
 	ServiceFilePath() string
+	// Logs returns where the output of the service goes.
+	Logs() ServiceLogs
+}
+
+// ServiceLogs describes where the output of the service goes: a file, or
+// the systemd journal. Both are empty if the output is discarded.
+type ServiceLogs struct {
+	// Path is the file the output is written to.
+	Path string
+	// Unit is the systemd user unit whose output the journal keeps.
+	Unit string
 }
 
 // ServiceStatus represents the current status of the service.
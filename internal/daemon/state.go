@@ -0,0 +1,253 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/utils"
+)
+
+const (
+	// StateFileName is the name of the daemon state file in the data directory.
+	StateFileName = "daemon-state.json"
+
+	// stateVersion is the current state file format version.
+	stateVersion = 1
+)
+
+// profileState is the renewal state and history of a profile. It is
+// persisted so a restarted daemon keeps backing off a failing server.
+type profileState struct {
+	// FailureCount is the number of consecutive failed checks or renewals.
+	FailureCount int `json:"failure_count,omitempty"`
+	// NextRetry is when the next attempt may be made after a failure.
+	NextRetry   time.Time `json:"next_retry,omitzero"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+	// Renewals and Failures count all successful and failed attempts.
+	Renewals int `json:"renewals,omitempty"`
+	Failures int `json:"failures,omitempty"`
+	// NeedsLogin is set when the token expired and can only be replaced by
+	// logging in again.
+	NeedsLogin bool `json:"needs_login,omitempty"`
+}
+
+// stateTotals holds the daemon-wide counters reported by the health server.
+type stateTotals struct {
+	Renewals int `json:"renewals"`
+	Errors   int `json:"errors"`
+}
+
+// stateFile is the on-disk format of the daemon state.
+type stateFile struct {
+	Version  int                      `json:"version"`
+	SavedAt  time.Time                `json:"saved_at"`
+	Totals   stateTotals              `json:"totals"`
+	Profiles map[string]*profileState `json:"profiles"`
+}
+
+// stateStore reads and atomically writes the daemon state file.
+type stateStore struct {
+	mu   sync.Mutex // serializes writes from concurrent workers
+	path string
+}
+
+// defaultStatePath returns the path of the state file in the data directory.
+func defaultStatePath() string {
+	return filepath.Join(config.GetPaths().DataDir, StateFileName)
+}
+
+// load reads the state file. A missing file is an empty state.
+func (s *stateStore) load() (*stateFile, error) {
+	// #nosec G304 - path is in the data directory (controlled)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &stateFile{Version: stateVersion}, nil
+		}
+		return nil, fmt.Errorf("failed to read daemon state: %w", err)
+	}
+
+	var f stateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse daemon state %s: %w", s.path, err)
+	}
+	if f.Version != stateVersion {
+		return nil, fmt.Errorf("unsupported daemon state version %d", f.Version)
+	}
+	return &f, nil
+}
+
+// save atomically writes f to the state file.
+func (s *stateStore) save(f *stateFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode daemon state: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create daemon state directory: %w", err)
+	}
+	return utils.WriteFileAtomic(s.path, data, 0600)
+}
+
+// restoreState loads the state persisted by a previous run, keeping only
+// profiles that are still configured. A state file that cannot be read is
+// logged and ignored.
+func (d *Daemon) restoreState() {
+	f, err := d.state.load()
+	if err != nil {
+		d.logger.Warn("Ignoring saved daemon state", "error", err)
+		return
+	}
+
+	cfg := d.currentConfig()
+	restored := 0
+
+	d.mu.Lock()
+	d.totals = f.Totals
+	for name, ps := range f.Profiles {
+		if ps == nil {
+			continue
+		}
+		if _, err := cfg.GetConnection(name); err != nil {
+			continue
+		}
+		d.states[name] = ps
+		restored++
+	}
+	totals := d.totals
+	d.mu.Unlock()
+
+	if hs := d.health(); hs != nil {
+		hs.SetTotals(totals.Renewals, totals.Errors)
+	}
+	if restored > 0 {
+		d.logger.Info("Restored renewal state", "count", restored)
+	}
+}
+
+// saveState persists the renewal state. Failures are logged, as losing the
+// state only affects backoff and history after a restart.
+func (d *Daemon) saveState() {
+	d.mu.Lock()
+	f := &stateFile{
+		Version:  stateVersion,
+		SavedAt:  time.Now().UTC(),
+		Totals:   d.totals,
+		Profiles: make(map[string]*profileState, len(d.states)),
+	}
+	for name, ps := range d.states {
+		snapshot := *ps
+		f.Profiles[name] = &snapshot
+	}
+	d.mu.Unlock()
+
+	if err := d.state.save(f); err != nil {
+		d.logger.Warn("Failed to save daemon state", "error", err)
+	}
+}
+
+// profileStateLocked returns the state of profile name, creating it if
+// needed. d.mu must be held.
+func (d *Daemon) profileStateLocked(name string) *profileState {
+	ps := d.states[name]
+	if ps == nil {
+		ps = &profileState{}
+		d.states[name] = ps
+	}
+	return ps
+}
+
+// recordRenewalSuccess records a successful renewal of profile name and
+// clears its backoff.
+func (d *Daemon) recordRenewalSuccess(name string) {
+	d.mu.Lock()
+	ps := d.profileStateLocked(name)
+	ps.FailureCount = 0
+	ps.NextRetry = time.Time{}
+	ps.NeedsLogin = false
+	ps.LastSuccess = time.Now().UTC()
+	ps.Renewals++
+	d.totals.Renewals++
+	d.mu.Unlock()
+
+	if hs := d.health(); hs != nil {
+		hs.RecordRenewal()
+	}
+	d.saveState()
+}
+
+// resetBackoff clears the backoff of a profile, keeping its history.
+func (d *Daemon) resetBackoff(name string) {
+	d.mu.Lock()
+	ps := d.states[name]
+	changed := ps != nil && (ps.FailureCount != 0 || !ps.NextRetry.IsZero())
+	if changed {
+		ps.FailureCount = 0
+		ps.NextRetry = time.Time{}
+	}
+	d.mu.Unlock()
+
+	if changed {
+		d.saveState()
+	}
+}
+
+// setNeedsLogin records whether the token of profile name expired and needs
+// a new login. It reports whether the profile newly needs one.
+func (d *Daemon) setNeedsLogin(name string, needsLogin bool) bool {
+	d.mu.Lock()
+	ps := d.states[name]
+	changed := (ps == nil && needsLogin) || (ps != nil && ps.NeedsLogin != needsLogin)
+	if changed {
+		d.profileStateLocked(name).NeedsLogin = needsLogin
+	}
+	d.mu.Unlock()
+
+	if changed {
+		d.saveState()
+	}
+	return changed && needsLogin
+}
+
+// stateProfiles returns the names of all profiles with recorded state.
+func (d *Daemon) stateProfiles() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := make([]string, 0, len(d.states))
+	for name := range d.states {
+		names = append(names, name)
+	}
+	return names
+}
+
+// forgetProfile drops all state of a profile that was removed from the config.
+func (d *Daemon) forgetProfile(name string) {
+	d.mu.Lock()
+	_, ok := d.states[name]
+	delete(d.states, name)
+	delete(d.loginDue, name)
+	delete(d.warned, name)
+	delete(d.idleWarned, name)
+	delete(d.tokens, name)
+	d.mu.Unlock()
+
+	if hs := d.health(); hs != nil {
+		hs.ForgetProfile(name)
+	}
+	if ok {
+		d.saveState()
+	}
+}
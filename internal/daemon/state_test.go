@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestStatePersistence(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1", "http://127.0.0.1:2", "http://127.0.0.1:3"})
+
+	nextRetry := d.recordRenewalFailure(cfg, "profile-0", errors.New("connection refused"))
+	d.recordRenewalFailure(cfg, "profile-1", errors.New("permission denied"))
+	d.recordRenewalSuccess("profile-1")
+	d.recordRenewalFailure(cfg, "profile-2", errors.New("connection refused"))
+
+	info, err := os.Stat(d.state.path)
+	if err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("state file permissions = %o, want 600", perm)
+	}
+
+	// A restarted daemon, with profile-2 removed from the config.
+	restarted, restartedCfg := newTestDaemon(t, []string{"http://127.0.0.1:1", "http://127.0.0.1:2"})
+	restarted.state = d.state
+	hs := NewHealthServer("localhost:0")
+	restarted.SetHealthServer(hs)
+	restarted.restoreState()
+
+	retry, ok := restarted.backoffUntil("profile-0")
+	if !ok || !retry.Equal(nextRetry) {
+		t.Errorf("backoffUntil(profile-0) = %v, %v; want %v, true", retry, ok, nextRetry)
+	}
+	// The next failure continues the backoff instead of starting over.
+	restarted.recordRenewalFailure(restartedCfg, "profile-0", errors.New("connection refused"))
+	if ps := restarted.states["profile-0"]; ps.FailureCount != 2 || ps.Failures != 2 {
+		t.Errorf("profile-0 failures = %d consecutive, %d total; want 2, 2", ps.FailureCount, ps.Failures)
+	}
+
+	st := restarted.profileStatus("profile-1")
+	if st.Failures != 0 || st.LastSuccess.IsZero() || st.LastError != "permission denied" {
+		t.Errorf("profile-1 status = %+v, want restored history without backoff", st)
+	}
+	if _, ok := restarted.states["profile-2"]; ok {
+		t.Error("state of a profile removed from the config should not be restored")
+	}
+
+	if hs.renewalsTotal != 1 || hs.errorsTotal != 4 {
+		t.Errorf("health totals = %d renewals, %d errors; want 1, 4", hs.renewalsTotal, hs.errorsTotal)
+	}
+}
+
+func TestRestoreStateIgnoresInvalidFile(t *testing.T) {
+	d, _ := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+	if err := os.WriteFile(d.state.path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d.restoreState()
+	if len(d.states) != 0 {
+		t.Errorf("got %d profile states from an invalid file, want 0", len(d.states))
+	}
+}
+
+func TestRescanHonorsRestoredBackoff(t *testing.T) {
+	d, cfg := newTestDaemon(t, []string{"http://127.0.0.1:1"})
+
+	// Without metadata the token would be looked up right away.
+	prof := types.FromConnection(&cfg.Connections[0])
+	if err := d.store.DeleteMetadata(prof); err != nil {
+		t.Fatal(err)
+	}
+	nextRetry := d.recordRenewalFailure(cfg, prof.Name, errors.New("connection refused"))
+
+	d.rescanProfiles(context.Background())
+
+	_, at, ok := d.queue.Entry(prof.Name)
+	if !ok || !at.Equal(nextRetry) {
+		t.Errorf("profile scheduled at %v, want the retry time %v", at, nextRetry)
+	}
+	if due := d.queue.PopDue(time.Now()); len(due) != 0 {
+		t.Errorf("profiles %v due before their retry time", due)
+	}
+}
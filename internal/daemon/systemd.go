@@ -222,3 +222,8 @@ func (m *SystemdManager) Status() (ServiceStatus, error) {
 func (m *SystemdManager) ServiceFilePath() string {
 	return m.servicePath
 }
+
+// Logs returns the unit whose output the journal keeps.
+func (m *SystemdManager) Logs() ServiceLogs {
+	return ServiceLogs{Unit: "patrol.service"}
+}
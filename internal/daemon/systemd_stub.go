@@ -35,3 +35,6 @@ func (m *SystemdManager) Status() (ServiceStatus, error) {
 
 // ServiceFilePath is not supported on this platform.
 func (m *SystemdManager) ServiceFilePath() string { return "" }
+
+// Logs is not supported on this platform.
+func (m *SystemdManager) Logs() ServiceLogs { return ServiceLogs{} }
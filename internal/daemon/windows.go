@@ -155,6 +155,11 @@ func (m *WindowsManager) ServiceFilePath() string {
 	return fmt.Sprintf("Task Scheduler: %s", taskName)
 }
 
+// Logs returns no location: the output of the task is discarded.
+func (m *WindowsManager) Logs() ServiceLogs {
+	return ServiceLogs{}
+}
+
 // enable enables the scheduled task.
 func (m *WindowsManager) enable() error {
 	// #nosec G204 - schtasks.exe is a Windows system utility, args are controlled
@@ -35,3 +35,6 @@ func (m *WindowsManager) Status() (ServiceStatus, error) {
 
 // ServiceFilePath is not supported on this platform.
 func (m *WindowsManager) ServiceFilePath() string { return "" }
+
+// Logs is not supported on this platform.
+func (m *WindowsManager) Logs() ServiceLogs { return ServiceLogs{} }
@@ -0,0 +1,151 @@
+// Package hooks runs user-configured commands on token lifecycle events.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// maxLoggedOutput is how much of a hook's output is kept for the log.
+const maxLoggedOutput = 4096
+
+// Environment variables passed to hook commands.
+const (
+	EnvEvent   = "PATROL_EVENT"
+	EnvProfile = "PATROL_PROFILE"
+	EnvAddress = "PATROL_ADDRESS"
+	EnvTTL     = "PATROL_TTL"
+	EnvError   = "PATROL_ERROR"
+	// EnvToken and VAULT_TOKEN are only set for hooks with pass_token.
+	EnvToken = "PATROL_TOKEN"
+)
+
+// Logger is the logging interface used by the runner, taking key/value
+// attributes as log/slog does. The daemon Logger implements it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// Event describes what happened to a profile's token.
+type Event struct {
+	// Type is the hook event (login, renewal, failure, logout).
+	Type string
+	// Profile is the profile the event is about.
+	Profile *types.Profile
+	// TTL is the remaining lifetime of the token, if known.
+	TTL time.Duration
+	// Err is the error of a failure event.
+	Err error
+	// Token is the token, passed only to hooks with pass_token. Logout
+	// events carry no token, since it was revoked or removed.
+	Token string
+}
+
+// Runner runs the configured hooks matching an event.
+type Runner struct {
+	hooks  []config.HookConfig
+	logger Logger
+}
+
+// NewRunner creates a runner for hooks logging to logger.
+func NewRunner(hooks []config.HookConfig, logger Logger) *Runner {
+	return &Runner{hooks: hooks, logger: logger}
+}
+
+// Matching returns the hooks that run for ev.
+func (r *Runner) Matching(ev *Event) []config.HookConfig {
+	var matching []config.HookConfig
+	for _, hook := range r.hooks {
+		if hook.Matches(ev.Type, ev.Profile.Name) {
+			matching = append(matching, hook)
+		}
+	}
+	return matching
+}
+
+// NeedsToken reports whether a hook matching ev is passed the token, so
+// callers only read it from the token store when needed.
+func (r *Runner) NeedsToken(ev *Event) bool {
+	for _, hook := range r.Matching(ev) {
+		if hook.PassToken {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs every hook matching ev, one after another, and waits for them.
+// Failures are logged and do not stop the remaining hooks.
+func (r *Runner) Run(ctx context.Context, ev *Event) {
+	for _, hook := range r.Matching(ev) {
+		attrs := []any{"profile", ev.Profile.Name, "event", ev.Type, "hook", hook.GetName()}
+		r.logger.Debug("Running hook", attrs...)
+
+		output, err := run(ctx, &hook, ev)
+		if output != "" {
+			attrs = append(attrs, "output", output)
+		}
+		if err != nil {
+			r.logger.Warn("Hook failed", append(attrs, "error", err)...)
+			continue
+		}
+		r.logger.Info("Hook completed", attrs...)
+	}
+}
+
+// run runs a single hook and returns its trimmed combined output.
+func run(ctx context.Context, hook *config.HookConfig, ev *Event) (string, error) {
+	timeout := hook.GetTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// #nosec G204 - the hook command comes from the user's configuration
+	cmd := exec.CommandContext(ctx, hook.Command, hook.Args...)
+	cmd.Env = append(os.Environ(), hookEnv(hook, ev)...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Do not wait on children that keep the output open after a timeout.
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	out := strings.TrimSpace(output.String())
+	if len(out) > maxLoggedOutput {
+		out = out[:maxLoggedOutput] + "..."
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return out, fmt.Errorf("timed out after %s", timeout)
+	}
+	return out, err
+}
+
+// hookEnv returns the environment variables describing ev to hook.
+func hookEnv(hook *config.HookConfig, ev *Event) []string {
+	env := []string{
+		EnvEvent + "=" + ev.Type,
+		EnvProfile + "=" + ev.Profile.Name,
+		EnvAddress + "=" + ev.Profile.Address,
+	}
+	if ev.TTL > 0 {
+		env = append(env, EnvTTL+"="+strconv.Itoa(int(ev.TTL.Seconds())))
+	}
+	if ev.Err != nil {
+		env = append(env, EnvError+"="+ev.Err.Error())
+	}
+	if hook.PassToken && ev.Token != "" {
+		env = append(env, EnvToken+"="+ev.Token, "VAULT_TOKEN="+ev.Token)
+	}
+	return env
+}
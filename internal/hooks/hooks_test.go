@@ -0,0 +1,191 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// testLogger records log messages and their attributes by level.
+type testLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *testLogger) log(level, msg string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := level + " " + msg
+	for i := 0; i+1 < len(args); i += 2 {
+		entry += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+	l.logs = append(l.logs, entry)
+}
+
+func (l *testLogger) Debug(msg string, args ...any) { l.log("DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...any)  { l.log("INFO", msg, args) }
+func (l *testLogger) Warn(msg string, args ...any)  { l.log("WARN", msg, args) }
+
+// contains reports whether a message at level contains substr.
+func (l *testLogger) contains(level, substr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, entry := range l.logs {
+		if strings.HasPrefix(entry, level+" ") && strings.Contains(entry, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellHook returns a hook running script with sh.
+func shellHook(t *testing.T, script string) config.HookConfig {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use sh")
+	}
+	return config.HookConfig{Command: "sh", Args: []string{"-c", script}}
+}
+
+// readEnv parses the output of env written to path.
+func readEnv(t *testing.T, path string) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(path) // #nosec G304 - test file
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	env := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, value, ok := strings.Cut(line, "="); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+func TestRunPassesEventEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "env")
+
+	hook := shellHook(t, "env > "+out)
+	hook.Events = []string{config.HookFailure}
+	logger := &testLogger{}
+	runner := NewRunner([]config.HookConfig{hook}, logger)
+
+	t.Setenv("VAULT_TOKEN", "")
+	prof := &types.Profile{Name: "prod", Address: "https://vault.example.com"}
+	runner.Run(context.Background(), &Event{
+		Type:    config.HookFailure,
+		Profile: prof,
+		TTL:     90 * time.Second,
+		Err:     errors.New("permission denied"),
+		Token:   "hvs.secret",
+	})
+
+	env := readEnv(t, out)
+	want := map[string]string{
+		EnvEvent:   "failure",
+		EnvProfile: "prod",
+		EnvAddress: "https://vault.example.com",
+		EnvTTL:     "90",
+		EnvError:   "permission denied",
+	}
+	for name, value := range want {
+		if env[name] != value {
+			t.Errorf("%s = %q, want %q", name, env[name], value)
+		}
+	}
+	if _, ok := env[EnvToken]; ok {
+		t.Errorf("%s was passed to a hook without pass_token", EnvToken)
+	}
+	if env["VAULT_TOKEN"] == "hvs.secret" {
+		t.Error("VAULT_TOKEN was passed to a hook without pass_token")
+	}
+	if !logger.contains("INFO", "completed") {
+		t.Errorf("completion not logged: %v", logger.logs)
+	}
+}
+
+func TestRunPassesTokenOnOptIn(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "env")
+
+	hook := shellHook(t, "env > "+out)
+	hook.Events = []string{config.HookLogin}
+	hook.PassToken = true
+	runner := NewRunner([]config.HookConfig{hook}, &testLogger{})
+
+	ev := &Event{Type: config.HookLogin, Profile: &types.Profile{Name: "dev"}, Token: "hvs.secret"}
+	if !runner.NeedsToken(ev) {
+		t.Error("NeedsToken() = false for a pass_token hook")
+	}
+	runner.Run(context.Background(), ev)
+
+	env := readEnv(t, out)
+	if env[EnvToken] != "hvs.secret" || env["VAULT_TOKEN"] != "hvs.secret" {
+		t.Errorf("token not passed: %s=%q VAULT_TOKEN=%q", EnvToken, env[EnvToken], env["VAULT_TOKEN"])
+	}
+}
+
+func TestRunMatchesEventsAndProfiles(t *testing.T) {
+	dir := t.TempDir()
+	var hooks []config.HookConfig
+	for _, name := range []string{"renewal-prod", "renewal-all", "login-all"} {
+		hook := shellHook(t, "touch "+filepath.Join(dir, name))
+		hook.Name = name
+		hooks = append(hooks, hook)
+	}
+	hooks[0].Events = []string{config.HookRenewal}
+	hooks[0].Profiles = []string{"prod-*"}
+	hooks[1].Events = []string{config.HookRenewal}
+	hooks[2].Events = []string{config.HookLogin}
+
+	runner := NewRunner(hooks, &testLogger{})
+	runner.Run(context.Background(), &Event{Type: config.HookRenewal, Profile: &types.Profile{Name: "dev"}})
+
+	for name, wantRun := range map[string]bool{"renewal-prod": false, "renewal-all": true, "login-all": false} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if ran := err == nil; ran != wantRun {
+			t.Errorf("hook %s ran = %v, want %v", name, ran, wantRun)
+		}
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	hook := shellHook(t, "sleep 10")
+	hook.Events = []string{config.HookLogout}
+	hook.Timeout = 100 * time.Millisecond
+	logger := &testLogger{}
+	runner := NewRunner([]config.HookConfig{hook}, logger)
+
+	start := time.Now()
+	runner.Run(context.Background(), &Event{Type: config.HookLogout, Profile: &types.Profile{Name: "dev"}})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("hook ran for %s despite its timeout", elapsed)
+	}
+	if !logger.contains("WARN", fmt.Sprintf("timed out after %s", hook.Timeout)) {
+		t.Errorf("timeout not logged: %v", logger.logs)
+	}
+}
+
+func TestRunLogsFailureOutput(t *testing.T) {
+	hook := shellHook(t, "echo something broke >&2; exit 3")
+	hook.Events = []string{config.HookRenewal}
+	logger := &testLogger{}
+	runner := NewRunner([]config.HookConfig{hook}, logger)
+
+	runner.Run(context.Background(), &Event{Type: config.HookRenewal, Profile: &types.Profile{Name: "dev"}})
+	if !logger.contains("WARN", "something broke") {
+		t.Errorf("failure output not logged: %v", logger.logs)
+	}
+}
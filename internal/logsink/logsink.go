@@ -0,0 +1,85 @@
+// Package logsink sends messages to the system log: a syslog daemon, using
+// RFC 5424, or the systemd journal, using its native protocol. Sinks serve
+// both the daemon log and notifications.
+package logsink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xabinapal/patrol/internal/config"
+)
+
+// Priority is a syslog severity, from PriorityEmerg (most urgent) to
+// PriorityDebug.
+type Priority int
+
+// Syslog severities, as defined by RFC 5424.
+const (
+	PriorityEmerg Priority = iota
+	PriorityAlert
+	PriorityCrit
+	PriorityErr
+	PriorityWarning
+	PriorityNotice
+	PriorityInfo
+	PriorityDebug
+)
+
+// Field is a key/value pair sent with a message, such as the profile it is
+// about. Keys are lowercase words separated by underscores.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Sink sends messages to the system log.
+type Sink interface {
+	// Send sends msg with priority p and fields describing it.
+	Send(p Priority, msg string, fields ...Field) error
+	// Close releases the connection to the system log.
+	Close() error
+}
+
+// New opens the sink named by kind (config.LogSinkSyslog or
+// config.LogSinkJournald) with the syslog settings in cfg.
+func New(kind string, cfg config.SyslogConfig) (Sink, error) {
+	switch kind {
+	case config.LogSinkSyslog:
+		return NewSyslog(cfg)
+	case config.LogSinkJournald:
+		return NewJournal(cfg.GetTag())
+	default:
+		return nil, fmt.Errorf("unknown log sink %q", kind)
+	}
+}
+
+// PriorityForSeverity returns the priority of a notification severity.
+func PriorityForSeverity(severity string) Priority {
+	switch severity {
+	case config.SeverityCritical:
+		return PriorityCrit
+	case config.SeverityWarning:
+		return PriorityWarning
+	default:
+		return PriorityInfo
+	}
+}
+
+// formatFields appends fields to msg as key=value pairs, quoting values
+// with spaces, for sinks that only take text.
+func formatFields(msg string, fields []Field) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, f := range fields {
+		value := f.Value
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %s=%s", f.Key, value)
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package logsink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+)
+
+// listenUDP starts a UDP syslog server and returns its address and a
+// function reading the next message.
+func listenUDP(t *testing.T) (string, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func() string {
+		t.Helper()
+		buf := make([]byte, 8192)
+		if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("no syslog message received: %v", err)
+		}
+		return string(buf[:n])
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	addr, read := listenUDP(t)
+	s, err := NewSyslog(config.SyslogConfig{Network: config.SyslogUDP, Address: addr, Facility: "local3", Tag: "patrol-test"})
+	if err != nil {
+		t.Fatalf("NewSyslog() failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Send(PriorityWarning, "Renewal failed\nretrying", Field{"profile", "prod"}, Field{"error", "connection refused"}); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	// local3 (19) * 8 + warning (4) = 156
+	pattern := regexp.MustCompile(`^<156>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) \S+ patrol-test \d+ - - ` +
+		`Renewal failed retrying profile=prod error="connection refused"$`)
+	if msg := read(); !pattern.MatchString(msg) {
+		t.Errorf("message %q is not the expected RFC 5424 message", msg)
+	}
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets are not available on Windows")
+	}
+	// Socket paths are limited in length, so avoid the long t.TempDir().
+	dir, err := os.MkdirTemp("", "patrol-syslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatalf("ListenPacket() failed: %v", err)
+	}
+	defer conn.Close()
+
+	s, err := NewSyslog(config.SyslogConfig{Network: config.SyslogUnixgram, Address: path})
+	if err != nil {
+		t.Fatalf("NewSyslog() failed: %v", err)
+	}
+	defer s.Close()
+	if err := s.Send(PriorityErr, "boom"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	buf := make([]byte, 8192)
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+	// daemon (3) * 8 + err (3) = 27, with the default tag.
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<27>1 ") || !strings.Contains(msg, " patrol ") || !strings.HasSuffix(msg, " boom") {
+		t.Errorf("message = %q", msg)
+	}
+}
+
+func TestValidateSyslog(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.SyslogConfig
+		wantErr bool
+	}{
+		{"local socket", config.SyslogConfig{}, false},
+		{"udp", config.SyslogConfig{Network: "udp", Address: "logs.example.com:514", Facility: "local0"}, false},
+		{"udp without port", config.SyslogConfig{Network: "udp", Address: "logs.example.com"}, true},
+		{"address without network", config.SyslogConfig{Address: "/dev/log"}, true},
+		{"tcp", config.SyslogConfig{Network: "tcp", Address: "logs.example.com:514"}, true},
+		{"unknown facility", config.SyslogConfig{Facility: "local9"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSyslog(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSyslog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJournalEntry(t *testing.T) {
+	j := &Journal{tag: "patrol"}
+	entry := j.entry(PriorityCrit, "Login required\nRun patrol login", []Field{{"profile", "prod-eu"}, {"event", "login_required"}})
+
+	var want bytes.Buffer
+	want.WriteString("MESSAGE\n")
+	binary.Write(&want, binary.LittleEndian, uint64(len("Login required\nRun patrol login"))) //nolint:errcheck // bytes.Buffer does not fail
+	want.WriteString("Login required\nRun patrol login\n")
+	want.WriteString("PRIORITY=2\nSYSLOG_IDENTIFIER=patrol\nPATROL_PROFILE=prod-eu\nPATROL_EVENT=login_required\n")
+
+	if !bytes.Equal(entry, want.Bytes()) {
+		t.Errorf("entry = %q, want %q", entry, want.Bytes())
+	}
+}
+
+func TestPriorityForSeverity(t *testing.T) {
+	tests := map[string]Priority{
+		config.SeverityCritical: PriorityCrit,
+		config.SeverityWarning:  PriorityWarning,
+		config.SeverityInfo:     PriorityInfo,
+		"":                      PriorityInfo,
+	}
+	for severity, want := range tests {
+		if got := PriorityForSeverity(severity); got != want {
+			t.Errorf("PriorityForSeverity(%q) = %d, want %d", severity, got, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package logsink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+)
+
+// localSyslogSockets are where syslog daemons listen on Linux, macOS and
+// the BSDs.
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// facilities maps syslog facility names to their codes.
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// rfc5424Time is the RFC 5424 timestamp format, which allows at most six
+// fractional digits.
+const rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+// ValidateSyslog checks the syslog settings, including the facility.
+func ValidateSyslog(cfg config.SyslogConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if _, ok := facilities[cfg.GetFacility()]; !ok {
+		return fmt.Errorf("syslog: unknown facility %q", cfg.Facility)
+	}
+	return nil
+}
+
+// Syslog sends RFC 5424 messages to a syslog daemon, over a local socket or
+// UDP. Fields are appended to the message as key=value pairs.
+type Syslog struct {
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslog connects to the syslog daemon configured by cfg, or to the local
+// syslog socket if no network is set.
+func NewSyslog(cfg config.SyslogConfig) (*Syslog, error) {
+	if err := ValidateSyslog(cfg); err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	s := &Syslog{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		facility: facilities[cfg.GetFacility()],
+		tag:      cfg.GetTag(),
+		hostname: hostname,
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect dials the syslog daemon. Without a configured network, the
+// local sockets are tried in turn, as datagram and then stream sockets.
+func (s *Syslog) connect() error {
+	if s.network != "" {
+		conn, err := net.Dial(s.network, s.address)
+		if err != nil {
+			return fmt.Errorf("syslog: %w", err)
+		}
+		s.conn = conn
+		return nil
+	}
+
+	for _, path := range localSyslogSockets {
+		for _, network := range []string{config.SyslogUnixgram, config.SyslogUnix} {
+			if conn, err := net.Dial(network, path); err == nil {
+				s.network, s.address, s.conn = network, path, conn
+				return nil
+			}
+		}
+	}
+	return errors.New("syslog: no local syslog socket found")
+}
+
+// Send implements Sink. A failed write is retried once on a new connection,
+// since local syslog daemons drop their clients when they restart.
+func (s *Syslog) Send(p Priority, msg string, fields ...Field) error {
+	line := s.format(p, time.Now(), formatFields(msg, fields))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		if _, err := s.conn.Write(line); err == nil {
+			return nil
+		}
+		s.conn.Close() //nolint:errcheck // replaced below
+		s.conn = nil
+	}
+	if err := s.connect(); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(line); err != nil {
+		return fmt.Errorf("syslog: %w", err)
+	}
+	return nil
+}
+
+// format renders an RFC 5424 message without structured data. Stream
+// sockets get a trailing newline to delimit messages.
+func (s *Syslog) format(p Priority, t time.Time, msg string) []byte {
+	// Each message must be a single line for line-based collectors.
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	line := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		s.facility*8+int(p), t.Format(rfc5424Time), s.hostname, s.tag, os.Getpid(), msg)
+	if s.network == config.SyslogUnix {
+		line += "\n"
+	}
+	return []byte(line)
+}
+
+// Close implements Sink.
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -1,6 +1,10 @@
 package notify
 
-import "github.com/gen2brain/beeep"
+import (
+	"time"
+
+	"github.com/gen2brain/beeep"
+)
 
 // Backend defines the interface for the notification backend.
 type Backend interface {
@@ -10,6 +14,54 @@ type Backend interface {
 	Alert(title, message, iconPath string) error
 }
 
+// EventType identifies what a notification event is about.
+type EventType string
+
+// Notification event types.
+const (
+	// EventRenewal is sent after a token was renewed or replaced by a new login.
+	EventRenewal EventType = "renewal"
+	// EventFailure is sent when a renewal or automatic login failed.
+	EventFailure EventType = "failure"
+	// EventExpiring is sent when a token approaches its hard expiry.
+	EventExpiring EventType = "expiring"
+	// EventLoginRequired is sent when a token expired and needs a new login.
+	EventLoginRequired EventType = "login_required"
+	// EventIdleWarning is sent before an unused token is revoked.
+	EventIdleWarning EventType = "idle_warning"
+	// EventIdleRevoked is sent after an unused token was revoked.
+	EventIdleRevoked EventType = "idle_revoked"
+	// EventSealed is sent when a renewal failed because the server is sealed.
+	EventSealed EventType = "sealed"
+)
+
+// Event is a notification about a profile.
+type Event struct {
+	// Type is what the event is about.
+	Type EventType `json:"type"`
+	// Profile is the profile the event is about.
+	Profile string `json:"profile"`
+	// Title and Message are the human-readable notification.
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	// Severity is how urgent the event is: info, warning or critical.
+	Severity string `json:"severity"`
+	// Alert marks events that need the user's attention, those above info.
+	Alert bool `json:"alert"`
+	// TTL is the new TTL after a renewal, or the time left in seconds.
+	TTL int `json:"ttl,omitempty"`
+	// Error is the error that caused a failure.
+	Error string `json:"error,omitempty"`
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+}
+
+// EventSender is implemented by backends that take the whole event instead
+// of a title and message.
+type EventSender interface {
+	Send(ev *Event) error
+}
+
 // desktopBackend implements Backend by calling beeep functions directly.
 type desktopBackend struct{}
 
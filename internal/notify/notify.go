@@ -1,74 +1,282 @@
-// Package notify provides desktop notification support for Patrol.
+// Package notify provides desktop, webhook and system log notifications for
+// Patrol.
 package notify
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/xabinapal/patrol/internal/config"
 	"github.com/xabinapal/patrol/internal/utils"
 )
 
-// Notifier defines the interface for sending desktop notifications.
+// Notifier defines the interface for sending notifications.
 type Notifier interface {
 	// NotifyRenewal sends a notification about successful token renewal.
 	NotifyRenewal(profile string, newTTL time.Duration) error
 	// NotifyFailure sends a notification about renewal failure.
 	NotifyFailure(profile string, err error) error
+	// NotifyExpiring sends a notification about a token that can no longer be
+	// renewed past the given remaining time.
+	NotifyExpiring(profile string, remaining time.Duration) error
+	// NotifyIdle sends a notification about an unused token that will be
+	// revoked after revokeIn, or was revoked if revokeIn is zero.
+	NotifyIdle(profile string, revokeIn time.Duration) error
+	// NotifyLoginRequired sends a notification about a token that expired
+	// and can only be replaced by logging in again.
+	NotifyLoginRequired(profile string) error
+	// NotifySealed sends a notification about a renewal that failed because
+	// the server at address is sealed.
+	NotifySealed(profile, address string) error
+}
+
+// Drainer is implemented by notifiers and backends whose deliveries
+// complete in the background.
+type Drainer interface {
+	// Drain waits for the pending deliveries until ctx is done.
+	Drain(ctx context.Context) error
+}
+
+// Drain waits for the pending deliveries of n until ctx is done. Notifiers
+// that deliver right away have nothing to wait for.
+func Drain(ctx context.Context, n Notifier) error {
+	if d, ok := n.(Drainer); ok {
+		return d.Drain(ctx)
+	}
+	return nil
 }
 
 // Option configures a Notifier.
 type Option func(*notifier)
 
-// WithBackend sets a custom notification backend (for testing).
+// WithBackend sets a custom desktop notification backend (for testing).
 func WithBackend(backend Backend) Option {
 	return func(n *notifier) {
-		n.backend = backend
+		n.desktop = backend
+	}
+}
+
+// WithErrorHandler sets a function called with the errors of deliveries
+// that complete in the background, such as webhook posts.
+func WithErrorHandler(handler func(error)) Option {
+	return func(n *notifier) {
+		n.onError = handler
+	}
+}
+
+// WithStateFrom carries the deduplication and rate limit state of prev over,
+// so a notifier rebuilt for new settings does not repeat the notifications
+// prev held back.
+func WithStateFrom(prev Notifier) Option {
+	return func(n *notifier) {
+		if p, ok := prev.(*notifier); ok {
+			n.throttle.copyState(p.throttle)
+		}
 	}
 }
 
-// notifier sends desktop notifications using the system notification service.
+// namedBackend is a backend with the name rules refer to it by.
+type namedBackend struct {
+	name string
+	Backend
+}
+
+// WithSyslog sets the syslog settings used by the syslog backend.
+func WithSyslog(cfg config.SyslogConfig) Option {
+	return func(n *notifier) {
+		n.syslog = cfg
+	}
+}
+
+// notifier routes events to the desktop, the webhook and the system log
+// according to the notification rules.
 type notifier struct {
-	onRenewal bool
-	onFailure bool
-	backend   Backend
+	rules    []config.NotificationRule
+	throttle *throttle
+	desktop  Backend
+	onError  func(error)
+	syslog   config.SyslogConfig
+	backends []namedBackend
+	now      func() time.Time
 }
 
 // NotifyRenewal sends a notification about successful token renewal.
 func (n *notifier) NotifyRenewal(profile string, newTTL time.Duration) error {
-	if !n.onRenewal {
-		return nil
+	return n.send(&Event{
+		Type:    EventRenewal,
+		Profile: profile,
+		Title:   "Patrol: Token Renewed",
+		Message: fmt.Sprintf("Token for '%s' renewed successfully.\nNew TTL: %s", profile, utils.FormatDuration(newTTL)),
+		TTL:     int(newTTL.Seconds()),
+	})
+}
+
+// NotifyFailure sends a notification about renewal failure.
+func (n *notifier) NotifyFailure(profile string, err error) error {
+	return n.send(&Event{
+		Type:    EventFailure,
+		Profile: profile,
+		Title:   "Patrol: Renewal Failed",
+		Message: fmt.Sprintf("Failed to renew token for '%s'.\nError: %v", profile, err),
+		Error:   err.Error(),
+	})
+}
+
+// NotifyExpiring sends a notification about a token approaching its hard expiry.
+func (n *notifier) NotifyExpiring(profile string, remaining time.Duration) error {
+	return n.send(&Event{
+		Type:    EventExpiring,
+		Profile: profile,
+		Title:   "Patrol: Session Ending",
+		Message: fmt.Sprintf("The token for '%s' cannot be renewed further and expires in %s.\nRun 'patrol login' to keep access.",
+			profile, utils.FormatDuration(remaining)),
+		TTL: int(remaining.Seconds()),
+	})
+}
+
+// NotifyIdle sends a notification about a token revoked for being unused.
+func (n *notifier) NotifyIdle(profile string, revokeIn time.Duration) error {
+	if revokeIn > 0 {
+		return n.send(&Event{
+			Type:    EventIdleWarning,
+			Profile: profile,
+			Title:   "Patrol: Idle Session",
+			Message: fmt.Sprintf("The token for '%s' has not been used for a while and will be revoked in %s.\nUse the profile to keep it.",
+				profile, utils.FormatDuration(revokeIn)),
+			TTL: int(revokeIn.Seconds()),
+		})
 	}
 
-	title := "Patrol: Token Renewed"
-	message := fmt.Sprintf("Token for '%s' renewed successfully.\nNew TTL: %s", profile, utils.FormatDuration(newTTL))
+	return n.send(&Event{
+		Type:    EventIdleRevoked,
+		Profile: profile,
+		Title:   "Patrol: Token Revoked",
+		Message: fmt.Sprintf("The token for '%s' was revoked because it was not used.\nRun 'patrol login' to authenticate again.", profile),
+	})
+}
 
-	return n.backend.Notify(title, message, "")
+// NotifyLoginRequired sends a notification about an expired token.
+func (n *notifier) NotifyLoginRequired(profile string) error {
+	return n.send(&Event{
+		Type:    EventLoginRequired,
+		Profile: profile,
+		Title:   "Patrol: Login Required",
+		Message: fmt.Sprintf("The token for '%s' has expired.\nRun 'patrol login' to authenticate again.", profile),
+	})
 }
 
-// NotifyFailure sends a notification about renewal failure.
-func (n *notifier) NotifyFailure(profile string, err error) error {
-	if !n.onFailure {
-		return nil
+// NotifySealed sends a notification about a sealed server.
+func (n *notifier) NotifySealed(profile, address string) error {
+	return n.send(&Event{
+		Type:    EventSealed,
+		Profile: profile,
+		Title:   "Patrol: Server Sealed",
+		Message: fmt.Sprintf("The server of '%s' (%s) is sealed.\nTokens cannot be renewed until it is unsealed.", profile, address),
+	})
+}
+
+// Drain implements Drainer.
+func (n *notifier) Drain(ctx context.Context) error {
+	var errs []error
+	for _, backend := range n.backends {
+		if d, ok := backend.Backend.(Drainer); ok {
+			if err := d.Drain(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	title := "Patrol: Renewal Failed"
-	message := fmt.Sprintf("Failed to renew token for '%s'.\nError: %v", profile, err)
+// send delivers ev to the backends chosen by the first rule matching it,
+// unless the rule mutes it or the throttle holds it back. Backends that
+// accept structured events get the event itself; others get its title and
+// message, as an alert above info severity.
+func (n *notifier) send(ev *Event) error {
+	if ev.Time.IsZero() {
+		ev.Time = n.now().UTC()
+	}
 
-	return n.backend.Alert(title, message, "")
+	rule := matchRule(n.rules, ev)
+	if rule == nil || rule.Mute {
+		return nil
+	}
+	ev.Severity = rule.Severity
+	if ev.Severity == "" {
+		ev.Severity = defaultSeverity(ev.Type)
+	}
+	ev.Alert = ev.Severity != config.SeverityInfo
+	if !n.throttle.allow(ev) {
+		return nil
+	}
+
+	var errs []error
+	for _, backend := range n.backends {
+		if len(rule.Backends) > 0 && !slices.Contains(rule.Backends, backend.name) {
+			continue
+		}
+		var err error
+		switch b := backend.Backend.(type) {
+		case EventSender:
+			err = b.Send(ev)
+		default:
+			if ev.Alert {
+				err = b.Alert(ev.Title, ev.Message, "")
+			} else {
+				err = b.Notify(ev.Title, ev.Message, "")
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
 }
 
-// New creates a new Notifier based on the configuration.
+// New creates a new Notifier based on the configuration. Desktop
+// notifications are sent if enabled, events are posted to the webhook if one
+// is configured, and sent to syslog and the journal if enabled; a webhook
+// that cannot be set up is reported to the error handler and left out.
+// Without rules, the on_* settings choose the events sent to every backend.
 func New(cfg config.NotificationConfig, opts ...Option) Notifier {
 	n := &notifier{
-		onRenewal: cfg.Enabled && cfg.OnRenewal,
-		onFailure: cfg.Enabled && cfg.OnFailure,
-		backend:   newDesktopBackend(),
+		rules:    cfg.Rules,
+		throttle: newThrottle(cfg),
+		desktop:  newDesktopBackend(),
+		onError:  func(error) {},
+		now:      time.Now,
+	}
+	if len(n.rules) == 0 {
+		n.rules = legacyRules(cfg)
 	}
 
 	for _, opt := range opts {
 		opt(n)
 	}
 
+	if cfg.Enabled {
+		n.backends = append(n.backends, namedBackend{config.NotifyBackendDesktop, n.desktop})
+	}
+	if cfg.Webhook.Enabled() {
+		webhook, err := newWebhookBackend(cfg.Webhook, n.onError)
+		if err != nil {
+			n.onError(err)
+		} else {
+			n.backends = append(n.backends, namedBackend{config.NotifyBackendWebhook, webhook})
+		}
+	}
+	if cfg.Syslog {
+		n.backends = append(n.backends, namedBackend{config.NotifyBackendSyslog, &sinkBackend{config.LogSinkSyslog, n.syslog}})
+	}
+	if cfg.Journald {
+		n.backends = append(n.backends, namedBackend{config.NotifyBackendJournald, &sinkBackend{config.LogSinkJournald, n.syslog}})
+	}
+
 	return n
 }
@@ -135,6 +135,67 @@ func TestNotifyFailure(t *testing.T) {
 	}
 }
 
+func TestNotifyExpiring(t *testing.T) {
+	mock := &mockBackend{}
+	cfg := config.NotificationConfig{
+		Enabled:    true,
+		OnExpiring: true,
+	}
+
+	n := New(cfg, WithBackend(mock))
+	if err := n.NotifyExpiring("test-profile", 10*time.Minute); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if len(mock.alertCalls) != 1 {
+		t.Fatalf("expected 1 alert call, got %d", len(mock.alertCalls))
+	}
+	call := mock.alertCalls[0]
+	if call.title != "Patrol: Session Ending" {
+		t.Errorf("expected title %q, got %q", "Patrol: Session Ending", call.title)
+	}
+	expectedMessage := fmt.Sprintf("The token for 'test-profile' cannot be renewed further and expires in %s.\nRun 'patrol login' to keep access.",
+		utils.FormatDuration(10*time.Minute))
+	if call.message != expectedMessage {
+		t.Errorf("expected message %q, got %q", expectedMessage, call.message)
+	}
+
+	disabled := New(config.NotificationConfig{Enabled: true}, WithBackend(mock))
+	if err := disabled.NotifyExpiring("test-profile", time.Hour); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if len(mock.alertCalls) != 1 {
+		t.Errorf("expected no alert with on_expiring disabled, got %d calls", len(mock.alertCalls))
+	}
+}
+
+func TestNotifyIdle(t *testing.T) {
+	mock := &mockBackend{}
+	cfg := config.NotificationConfig{
+		Enabled: true,
+		OnIdle:  true,
+	}
+
+	n := New(cfg, WithBackend(mock))
+	if err := n.NotifyIdle("test-profile", 10*time.Minute); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if len(mock.alertCalls) != 1 || mock.alertCalls[0].title != "Patrol: Idle Session" {
+		t.Fatalf("expected an idle session alert, got %+v", mock.alertCalls)
+	}
+
+	if err := n.NotifyIdle("test-profile", 0); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if len(mock.notifyCalls) != 1 || mock.notifyCalls[0].title != "Patrol: Token Revoked" {
+		t.Fatalf("expected a token revoked notification, got %+v", mock.notifyCalls)
+	}
+	expectedMessage := "The token for 'test-profile' was revoked because it was not used.\nRun 'patrol login' to authenticate again."
+	if mock.notifyCalls[0].message != expectedMessage {
+		t.Errorf("expected message %q, got %q", expectedMessage, mock.notifyCalls[0].message)
+	}
+}
+
 func TestNotifyFailureWithDisabledGlobal(t *testing.T) {
 	mock := &mockBackend{}
 	cfg := config.NotificationConfig{
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/xabinapal/patrol/internal/config"
+)
+
+// eventTypes are the event types rules can match.
+var eventTypes = []EventType{
+	EventRenewal, EventFailure, EventExpiring, EventLoginRequired,
+	EventIdleWarning, EventIdleRevoked, EventSealed,
+}
+
+// backendNames are the backends rules can send to.
+var backendNames = []string{
+	config.NotifyBackendDesktop, config.NotifyBackendWebhook,
+	config.NotifyBackendSyslog, config.NotifyBackendJournald,
+}
+
+// defaultSeverity returns the severity of events of type t unless a rule
+// sets another.
+func defaultSeverity(t EventType) string {
+	switch t {
+	case EventLoginRequired, EventSealed:
+		return config.SeverityCritical
+	case EventFailure, EventExpiring, EventIdleWarning:
+		return config.SeverityWarning
+	default:
+		return config.SeverityInfo
+	}
+}
+
+// legacyRules returns the rules equivalent to the on_* settings, which apply
+// when no rules are configured.
+func legacyRules(cfg config.NotificationConfig) []config.NotificationRule {
+	var rules []config.NotificationRule
+	add := func(enabled bool, events ...EventType) {
+		if !enabled {
+			return
+		}
+		rule := config.NotificationRule{}
+		for _, ev := range events {
+			rule.Events = append(rule.Events, string(ev))
+		}
+		rules = append(rules, rule)
+	}
+	add(cfg.OnRenewal, EventRenewal)
+	add(cfg.OnFailure, EventFailure, EventLoginRequired, EventSealed)
+	add(cfg.OnExpiring, EventExpiring)
+	add(cfg.OnIdle, EventIdleWarning, EventIdleRevoked)
+	return rules
+}
+
+// ValidateRules checks the notification rules, throttling and quiet hours.
+// The webhook is checked by ValidateWebhook.
+func ValidateRules(cfg config.NotificationConfig) error {
+	for i, rule := range cfg.Rules {
+		if err := validateRule(&rule); err != nil {
+			return fmt.Errorf("notifications: rule %d: %w", i+1, err)
+		}
+	}
+	if cfg.RateLimit < -1 {
+		return errors.New("notifications: rate_limit must be -1 (no limit) or more")
+	}
+	if err := cfg.QuietHours.Validate(); err != nil {
+		return fmt.Errorf("notifications: %w", err)
+	}
+	return nil
+}
+
+// validateRule checks a rule, including its event types and backends.
+func validateRule(rule *config.NotificationRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	for _, event := range rule.Events {
+		if !slices.Contains(eventTypes, EventType(event)) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	for _, backend := range rule.Backends {
+		if !slices.Contains(backendNames, backend) {
+			return fmt.Errorf("unknown backend %q (use desktop, webhook, syslog or journald)", backend)
+		}
+	}
+	return nil
+}
+
+// matchRule returns the first rule matching ev, or nil if none does.
+func matchRule(rules []config.NotificationRule, ev *Event) *config.NotificationRule {
+	for i := range rules {
+		if rules[i].Matches(string(ev.Type), ev.Profile) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package notify
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+)
+
+// recordingSender records the events sent to it.
+type recordingSender struct {
+	mockBackend
+	events []*Event
+}
+
+// Send implements EventSender.
+func (r *recordingSender) Send(ev *Event) error {
+	r.events = append(r.events, ev)
+	return nil
+}
+
+// noErr fails the test if sending a notification failed.
+func noErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("notification failed: %v", err)
+	}
+}
+
+// newRuleNotifier creates a notifier sending to a recording desktop backend
+// at a fixed time.
+func newRuleNotifier(cfg config.NotificationConfig, now time.Time) (*notifier, *recordingSender) {
+	desktop := &recordingSender{}
+	cfg.Enabled = true
+	n := New(cfg, WithBackend(desktop)).(*notifier)
+	n.now = func() time.Time { return now }
+	return n, desktop
+}
+
+func TestRulesRouteEvents(t *testing.T) {
+	cfg := config.NotificationConfig{
+		Rules: []config.NotificationRule{
+			{Profiles: []string{"dev-*"}, Mute: true},
+			{Events: []string{"failure"}, Profiles: []string{"prod-*"}, Severity: config.SeverityCritical},
+			{Events: []string{"failure", "sealed"}},
+			{Events: []string{"renewal"}, Backends: []string{config.NotifyBackendWebhook}},
+		},
+	}
+	n, desktop := newRuleNotifier(cfg, time.Now())
+
+	noErr(t, n.NotifyFailure("dev-1", errors.New("boom")))
+	noErr(t, n.NotifyFailure("prod-eu", errors.New("boom")))
+	noErr(t, n.NotifyFailure("staging", errors.New("boom")))
+	noErr(t, n.NotifySealed("staging", "https://vault.example"))
+	noErr(t, n.NotifyRenewal("staging", time.Hour))
+	noErr(t, n.NotifyExpiring("staging", time.Minute))
+
+	want := []struct {
+		typ      EventType
+		profile  string
+		severity string
+	}{
+		{EventFailure, "prod-eu", config.SeverityCritical},
+		{EventFailure, "staging", config.SeverityWarning},
+		{EventSealed, "staging", config.SeverityCritical},
+	}
+	if len(desktop.events) != len(want) {
+		t.Fatalf("sent %d events, want %d", len(desktop.events), len(want))
+	}
+	for i, w := range want {
+		ev := desktop.events[i]
+		if ev.Type != w.typ || ev.Profile != w.profile || ev.Severity != w.severity || !ev.Alert {
+			t.Errorf("event %d = %s/%s/%s (alert %v), want %s/%s/%s", i,
+				ev.Type, ev.Profile, ev.Severity, ev.Alert, w.typ, w.profile, w.severity)
+		}
+	}
+}
+
+func TestLegacyRules(t *testing.T) {
+	cfg := config.NotificationConfig{OnFailure: true}
+	n, desktop := newRuleNotifier(cfg, time.Now())
+
+	noErr(t, n.NotifyRenewal("prod", time.Hour))
+	noErr(t, n.NotifyLoginRequired("prod"))
+	noErr(t, n.NotifySealed("prod", "https://vault.test"))
+
+	if len(desktop.events) != 2 || desktop.events[0].Type != EventLoginRequired || desktop.events[1].Type != EventSealed {
+		t.Errorf("on_failure sent %d events, want login required and sealed", len(desktop.events))
+	}
+}
+
+func TestThrottleDedupsFailures(t *testing.T) {
+	now := time.Now()
+	n, desktop := newRuleNotifier(config.NotificationConfig{OnRenewal: true, OnFailure: true}, now)
+
+	for range 3 {
+		noErr(t, n.NotifyFailure("prod", errors.New("connection refused")))
+	}
+	if len(desktop.events) != 1 {
+		t.Fatalf("repeated failure sent %d times, want once", len(desktop.events))
+	}
+
+	noErr(t, n.NotifyFailure("prod", errors.New("permission denied")))
+	if len(desktop.events) != 2 {
+		t.Errorf("a different failure should be sent")
+	}
+
+	n.now = func() time.Time { return now.Add(config.DefaultNotificationDedupWindow) }
+	noErr(t, n.NotifyFailure("prod", errors.New("connection refused")))
+	if len(desktop.events) != 3 {
+		t.Errorf("a failure should be sent again after the dedup window")
+	}
+
+	noErr(t, n.NotifyRenewal("prod", time.Hour))
+	noErr(t, n.NotifyFailure("prod", errors.New("connection refused")))
+	if len(desktop.events) != 5 {
+		t.Errorf("a failure after a renewal should be sent right away, got %d events", len(desktop.events))
+	}
+}
+
+func TestWithStateFrom(t *testing.T) {
+	now := time.Now()
+	prev, _ := newRuleNotifier(config.NotificationConfig{OnFailure: true, RateLimit: 1}, now)
+	noErr(t, prev.NotifyFailure("prod", errors.New("connection refused")))
+
+	desktop := &recordingSender{}
+	cfg := config.NotificationConfig{Enabled: true, OnRenewal: true, OnFailure: true, RateLimit: 1}
+	n := New(cfg, WithBackend(desktop), WithStateFrom(prev)).(*notifier)
+	n.now = func() time.Time { return now }
+
+	noErr(t, n.NotifyFailure("prod", errors.New("connection refused")))
+	noErr(t, n.NotifyRenewal("prod", time.Hour))
+	if len(desktop.events) != 0 {
+		t.Errorf("sent %d events, want the failure deduplicated and the renewal rate limited", len(desktop.events))
+	}
+}
+
+func TestThrottleRateLimit(t *testing.T) {
+	now := time.Now()
+	cfg := config.NotificationConfig{OnRenewal: true, OnFailure: true, RateLimit: 2}
+	n, desktop := newRuleNotifier(cfg, now)
+
+	for range 3 {
+		noErr(t, n.NotifyRenewal("prod", time.Hour))
+	}
+	noErr(t, n.NotifyRenewal("dev", time.Hour))
+	noErr(t, n.NotifyLoginRequired("prod"))
+	if len(desktop.events) != 4 {
+		t.Fatalf("sent %d events, want 2 for prod, 1 for dev and the critical one", len(desktop.events))
+	}
+
+	n.now = func() time.Time { return now.Add(time.Hour) }
+	noErr(t, n.NotifyRenewal("prod", time.Hour))
+	if len(desktop.events) != 5 {
+		t.Errorf("the rate limit should reset after an hour")
+	}
+}
+
+func TestThrottleQuietHours(t *testing.T) {
+	night := time.Date(2026, 1, 10, 23, 30, 0, 0, time.Local)
+	cfg := config.NotificationConfig{
+		OnRenewal:  true,
+		OnFailure:  true,
+		QuietHours: config.QuietHoursConfig{Start: "22:00", End: "07:00"},
+	}
+	n, desktop := newRuleNotifier(cfg, night)
+
+	noErr(t, n.NotifyRenewal("prod", time.Hour))
+	noErr(t, n.NotifyLoginRequired("prod"))
+	if len(desktop.events) != 1 || desktop.events[0].Type != EventLoginRequired {
+		t.Fatalf("quiet hours sent %d events, want only the critical one", len(desktop.events))
+	}
+
+	n.now = func() time.Time { return night.Add(8 * time.Hour) }
+	noErr(t, n.NotifyRenewal("prod", time.Hour))
+	if len(desktop.events) != 2 {
+		t.Errorf("events should be sent after quiet hours")
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.NotificationConfig
+		wantErr bool
+	}{
+		{"defaults", config.NotificationConfig{}, false},
+		{"valid rules", config.NotificationConfig{Rules: []config.NotificationRule{
+			{Events: []string{"sealed", "login_required"}, Backends: []string{"webhook"}, Severity: "critical"},
+		}}, false},
+		{"unknown event", config.NotificationConfig{Rules: []config.NotificationRule{{Events: []string{"renewed"}}}}, true},
+		{"unknown backend", config.NotificationConfig{Rules: []config.NotificationRule{{Backends: []string{"email"}}}}, true},
+		{"unknown severity", config.NotificationConfig{Rules: []config.NotificationRule{{Severity: "urgent"}}}, true},
+		{"bad quiet hours", config.NotificationConfig{QuietHours: config.QuietHoursConfig{Start: "10pm", End: "07:00"}}, true},
+		{"bad rate limit", config.NotificationConfig{RateLimit: -2}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateRules(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSyslogBackend(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() failed: %v", err)
+	}
+	defer conn.Close()
+
+	cfg := config.NotificationConfig{
+		OnFailure: true,
+		Syslog:    true,
+		Rules:     []config.NotificationRule{{Events: []string{"failure"}, Backends: []string{config.NotifyBackendSyslog}}},
+	}
+	n := New(cfg, WithSyslog(config.SyslogConfig{Network: config.SyslogUDP, Address: conn.LocalAddr().String()}))
+	noErr(t, n.NotifyFailure("prod", errors.New("permission denied")))
+
+	buf := make([]byte, 8192)
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	size, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no syslog message received: %v", err)
+	}
+	// daemon (3) * 8 + warning (4) = 28
+	msg := string(buf[:size])
+	if !strings.HasPrefix(msg, "<28>1 ") || !strings.Contains(msg, "Patrol: Renewal Failed") ||
+		!strings.HasSuffix(msg, `severity=warning event=failure profile=prod error="permission denied"`) {
+		t.Errorf("syslog message = %q", msg)
+	}
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"strconv"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/logsink"
+)
+
+// sinkBackend sends events to syslog or the systemd journal, with the event
+// type, profile and severity as fields so log-based alerting can match them.
+// It connects for every event: notifications are rare, and a notifier
+// replaced on reload leaves no connection behind.
+type sinkBackend struct {
+	kind   string
+	syslog config.SyslogConfig
+}
+
+// Notify implements Backend.
+func (b *sinkBackend) Notify(title, message, iconPath string) error {
+	return b.Send(&Event{Title: title, Message: message, Severity: config.SeverityInfo})
+}
+
+// Alert implements Backend.
+func (b *sinkBackend) Alert(title, message, iconPath string) error {
+	return b.Send(&Event{Title: title, Message: message, Severity: config.SeverityWarning, Alert: true})
+}
+
+// Send implements EventSender.
+func (b *sinkBackend) Send(ev *Event) error {
+	sink, err := logsink.New(b.kind, b.syslog)
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	fields := []logsink.Field{{Key: "severity", Value: ev.Severity}}
+	if ev.Type != "" {
+		fields = append(fields, logsink.Field{Key: "event", Value: string(ev.Type)})
+	}
+	if ev.Profile != "" {
+		fields = append(fields, logsink.Field{Key: "profile", Value: ev.Profile})
+	}
+	if ev.TTL > 0 {
+		fields = append(fields, logsink.Field{Key: "ttl", Value: strconv.Itoa(ev.TTL)})
+	}
+	if ev.Error != "" {
+		fields = append(fields, logsink.Field{Key: "error", Value: ev.Error})
+	}
+	return sink.Send(logsink.PriorityForSeverity(ev.Severity), ev.Title+": "+ev.Message, fields...)
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+)
+
+// rateWindow is the period the rate limit applies to.
+const rateWindow = time.Hour
+
+// dedupKey identifies a repeated event.
+type dedupKey struct {
+	typ     EventType
+	profile string
+	err     string
+}
+
+// throttle decides which events are held back by quiet hours,
+// deduplication and the rate limit.
+type throttle struct {
+	dedupWindow time.Duration
+	rateLimit   int
+	quietHours  config.QuietHoursConfig
+
+	mu   sync.Mutex
+	seen map[dedupKey]time.Time
+	sent map[string][]time.Time
+}
+
+// newThrottle creates a throttle for the notification settings in cfg.
+func newThrottle(cfg config.NotificationConfig) *throttle {
+	return &throttle{
+		dedupWindow: cfg.GetDedupWindow(),
+		rateLimit:   cfg.GetRateLimit(),
+		quietHours:  cfg.QuietHours,
+		seen:        make(map[dedupKey]time.Time),
+		sent:        make(map[string][]time.Time),
+	}
+}
+
+// copyState copies the events recorded by prev, keeping the settings of t.
+func (t *throttle) copyState(prev *throttle) {
+	prev.mu.Lock()
+	defer prev.mu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	maps.Copy(t.seen, prev.seen)
+	for profile, sent := range prev.sent {
+		t.sent[profile] = slices.Clone(sent)
+	}
+}
+
+// repeating reports whether events of type t report an ongoing condition,
+// which is retried and would otherwise be notified on every attempt.
+func repeating(t EventType) bool {
+	return t == EventFailure || t == EventLoginRequired || t == EventSealed
+}
+
+// allow reports whether ev is sent, and records it if so. Non-critical events
+// are held back during quiet hours; a repeating event is only sent again
+// after the dedup window or a successful renewal of its profile; and
+// non-critical events beyond the rate limit of their profile are dropped.
+func (t *throttle) allow(ev *Event) bool {
+	now := ev.Time
+	critical := ev.Severity == config.SeverityCritical
+	if !critical && t.quietHours.Enabled() && t.quietHours.Contains(now.Local()) {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ev.Type == EventRenewal {
+		// The profile recovered: report the next failure right away.
+		for key := range t.seen {
+			if key.profile == ev.Profile {
+				delete(t.seen, key)
+			}
+		}
+	}
+
+	key := dedupKey{typ: ev.Type, profile: ev.Profile, err: ev.Error}
+	if t.dedupWindow > 0 && repeating(ev.Type) {
+		if last, ok := t.seen[key]; ok && now.Sub(last) < t.dedupWindow {
+			return false
+		}
+	}
+
+	if t.rateLimit > 0 && !critical {
+		sent := t.sent[ev.Profile]
+		for len(sent) > 0 && now.Sub(sent[0]) >= rateWindow {
+			sent = sent[1:]
+		}
+		if len(sent) >= t.rateLimit {
+			t.sent[ev.Profile] = sent
+			return false
+		}
+		t.sent[ev.Profile] = append(sent, now)
+	}
+
+	if t.dedupWindow > 0 && repeating(ev.Type) {
+		t.seen[key] = now
+	}
+	return true
+}
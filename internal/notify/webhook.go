@@ -0,0 +1,254 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/version"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of the request body when a
+	// signing key is configured, as "sha256=<hex>".
+	SignatureHeader = "X-Patrol-Signature-256"
+
+	// webhookMaxInFlight bounds the deliveries running at the same time, so a
+	// slow endpoint cannot pile up goroutines.
+	webhookMaxInFlight = 8
+
+	// webhookRetryDelay is the wait before the first retry; it doubles
+	// after every failed attempt.
+	webhookRetryDelay = time.Second
+)
+
+// templateFuncs are the functions available to webhook body templates.
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {"text": {{json .Message}}}.
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// ValidateWebhook checks the webhook settings, including its body template.
+func ValidateWebhook(cfg config.WebhookConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	_, err := parseBodyTemplate(cfg.BodyTemplate)
+	return err
+}
+
+// parseBodyTemplate parses a webhook body template. An empty template
+// returns nil, which posts the event as JSON.
+func parseBodyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("body").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: invalid body_template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// webhookBackend posts events to an HTTP endpoint. Deliveries run in the
+// background with a timeout and retries, so callers never wait on the
+// endpoint; their errors go to onError.
+type webhookBackend struct {
+	url        string
+	headers    map[string]string
+	tmpl       *template.Template
+	secret     []byte
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+	client     *http.Client
+	onError    func(error)
+
+	inFlight chan struct{}
+	wg       sync.WaitGroup
+}
+
+// newWebhookBackend creates a webhook backend from cfg.
+func newWebhookBackend(cfg config.WebhookConfig, onError func(error)) (*webhookBackend, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	tmpl, err := parseBodyTemplate(cfg.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret []byte
+	switch {
+	case cfg.HMACSecretFile != "":
+		// #nosec G304 - path comes from the user's notification configuration
+		data, err := os.ReadFile(cfg.HMACSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: failed to read hmac_secret_file: %w", err)
+		}
+		secret = bytes.TrimSpace(data)
+	case cfg.HMACSecretEnv != "":
+		secret = []byte(os.Getenv(cfg.HMACSecretEnv))
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("webhook: environment variable %s is not set", cfg.HMACSecretEnv)
+		}
+	}
+
+	headers := make(map[string]string, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		headers[name] = os.ExpandEnv(value)
+	}
+
+	return &webhookBackend{
+		url:        cfg.URL,
+		headers:    headers,
+		tmpl:       tmpl,
+		secret:     secret,
+		timeout:    cfg.GetTimeout(),
+		retries:    cfg.GetRetries(),
+		retryDelay: webhookRetryDelay,
+		client:     &http.Client{},
+		onError:    onError,
+		inFlight:   make(chan struct{}, webhookMaxInFlight),
+	}, nil
+}
+
+// Notify implements Backend.
+func (w *webhookBackend) Notify(title, message, iconPath string) error {
+	return w.Send(&Event{Title: title, Message: message, Time: time.Now().UTC()})
+}
+
+// Alert implements Backend.
+func (w *webhookBackend) Alert(title, message, iconPath string) error {
+	return w.Send(&Event{Title: title, Message: message, Alert: true, Time: time.Now().UTC()})
+}
+
+// Send implements EventSender. The body is rendered right away; the
+// delivery runs in the background and is dropped if too many are pending.
+func (w *webhookBackend) Send(ev *Event) error {
+	body, err := w.render(ev)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case w.inFlight <- struct{}{}:
+	default:
+		return fmt.Errorf("webhook: too many deliveries pending, dropping %s event for %s", ev.Type, ev.Profile)
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() { <-w.inFlight }()
+
+		if err := w.deliver(body); err != nil {
+			w.onError(fmt.Errorf("webhook: failed to deliver %s event for %s: %w", ev.Type, ev.Profile, err))
+		}
+	}()
+	return nil
+}
+
+// render returns the request body for ev.
+func (w *webhookBackend) render(ev *Event) ([]byte, error) {
+	if w.tmpl == nil {
+		return json.Marshal(ev)
+	}
+	var buf bytes.Buffer
+	if err := w.tmpl.Execute(&buf, ev); err != nil {
+		return nil, fmt.Errorf("webhook: failed to render body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// deliver posts body, retrying network errors and server-side failures.
+func (w *webhookBackend) deliver(body []byte) error {
+	delay := w.retryDelay
+	var err error
+	for attempt := 0; attempt <= w.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		var retry bool
+		retry, err = w.post(body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post makes a single delivery attempt. It reports whether a failed attempt
+// is worth retrying.
+func (w *webhookBackend) post(body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "patrol/"+version.Version)
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return true, fmt.Errorf("timed out after %s", w.timeout)
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("endpoint returned %s", strings.TrimSpace(resp.Status))
+}
+
+// wait blocks until all pending deliveries have finished.
+func (w *webhookBackend) wait() {
+	w.wg.Wait()
+}
+
+// Drain implements Drainer.
+func (w *webhookBackend) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("webhook: %d deliveries still pending: %w", len(w.inFlight), ctx.Err())
+	}
+}
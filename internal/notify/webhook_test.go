@@ -0,0 +1,251 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+)
+
+// webhookRequest is a request received by a test webhook endpoint.
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newWebhookNotifier creates a notifier posting to url with fast retries,
+// and returns it with its webhook backend.
+func newWebhookNotifier(t *testing.T, cfg config.NotificationConfig, onError func(error)) (Notifier, *webhookBackend) {
+	t.Helper()
+	nt := New(cfg, WithErrorHandler(onError))
+	n := nt.(*notifier)
+	if len(n.backends) != 1 {
+		t.Fatalf("expected only the webhook backend, got %d backends", len(n.backends))
+	}
+	webhook := n.backends[0].Backend.(*webhookBackend)
+	webhook.retryDelay = time.Millisecond
+	return nt, webhook
+}
+
+func TestWebhookPostsEvents(t *testing.T) {
+	var mu sync.Mutex
+	var requests []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, webhookRequest{header: r.Header, body: body})
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	t.Setenv("PATROL_TEST_WEBHOOK_TOKEN", "s3cret")
+	t.Setenv("PATROL_TEST_WEBHOOK_KEY", "signing-key")
+	cfg := config.NotificationConfig{
+		OnFailure: true,
+		Webhook: config.WebhookConfig{
+			URL:           server.URL,
+			Headers:       map[string]string{"Authorization": "Bearer ${PATROL_TEST_WEBHOOK_TOKEN}"},
+			HMACSecretEnv: "PATROL_TEST_WEBHOOK_KEY",
+		},
+	}
+	n, webhook := newWebhookNotifier(t, cfg, func(err error) { t.Errorf("unexpected delivery error: %v", err) })
+
+	if err := n.NotifyFailure("prod", errors.New("permission denied")); err != nil {
+		t.Fatalf("NotifyFailure() failed: %v", err)
+	}
+	webhook.wait()
+
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if got := req.header.Get("Authorization"); got != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want the expanded header", got)
+	}
+	mac := hmac.New(sha256.New, []byte("signing-key"))
+	mac.Write(req.body)
+	if got, want := req.header.Get(SignatureHeader), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(req.body, &ev); err != nil {
+		t.Fatalf("body is not a JSON event: %v", err)
+	}
+	if ev.Type != EventFailure || ev.Profile != "prod" || ev.Error != "permission denied" || !ev.Alert {
+		t.Errorf("posted event = %+v", ev)
+	}
+}
+
+func TestWebhookBodyTemplate(t *testing.T) {
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	defer server.Close()
+
+	cfg := config.NotificationConfig{
+		OnExpiring: true,
+		Webhook: config.WebhookConfig{
+			URL:          server.URL,
+			BodyTemplate: `{"text": {{json .Message}}, "event": "{{.Type}}", "ttl": {{.TTL}}}`,
+		},
+	}
+	n, webhook := newWebhookNotifier(t, cfg, func(err error) { t.Errorf("unexpected delivery error: %v", err) })
+
+	if err := n.NotifyExpiring("prod", 10*time.Minute); err != nil {
+		t.Fatalf("NotifyExpiring() failed: %v", err)
+	}
+	webhook.wait()
+
+	var body struct {
+		Text  string `json:"text"`
+		Event string `json:"event"`
+		TTL   int    `json:"ttl"`
+	}
+	if err := json.Unmarshal([]byte(<-bodies), &body); err != nil {
+		t.Fatalf("rendered body is not valid JSON: %v", err)
+	}
+	if body.Event != "expiring" || body.TTL != 600 || body.Text == "" {
+		t.Errorf("rendered body = %+v", body)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		retries      int
+		wantRequests int32
+		wantErr      bool
+	}{
+		{"server error then success", []int{500, 503, 200}, 0, 3, false},
+		{"client error is not retried", []int{400}, 0, 1, true},
+		{"retries exhausted", []int{500, 500, 500}, 2, 3, true},
+		{"retries disabled", []int{500}, -1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tt.statuses[min(int(n), len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			var deliveryErr error
+			cfg := config.NotificationConfig{
+				OnRenewal: true,
+				Webhook:   config.WebhookConfig{URL: server.URL, Retries: tt.retries},
+			}
+			n, webhook := newWebhookNotifier(t, cfg, func(err error) { deliveryErr = err })
+
+			if err := n.NotifyRenewal("dev", time.Hour); err != nil {
+				t.Fatalf("NotifyRenewal() failed: %v", err)
+			}
+			webhook.wait()
+
+			if got := atomic.LoadInt32(&calls); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+			if (deliveryErr != nil) != tt.wantErr {
+				t.Errorf("delivery error = %v, wantErr %v", deliveryErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := config.NotificationConfig{
+		OnFailure:   true,
+		DedupWindow: -1,
+		RateLimit:   -1,
+		Webhook:     config.WebhookConfig{URL: server.URL, Retries: -1},
+	}
+	n, _ := newWebhookNotifier(t, cfg, func(error) {})
+
+	start := time.Now()
+	var dropped int
+	for range webhookMaxInFlight + 2 {
+		if err := n.NotifyFailure("prod", errors.New("boom")); err != nil {
+			dropped++
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("notifying took %s with a stalled endpoint", elapsed)
+	}
+	if dropped != 2 {
+		t.Errorf("dropped %d events, want the 2 beyond the in-flight limit", dropped)
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.WebhookConfig
+		wantErr bool
+	}{
+		{"disabled", config.WebhookConfig{}, false},
+		{"valid", config.WebhookConfig{URL: "https://hooks.example.com/patrol", BodyTemplate: `{"text": {{json .Message}}}`}, false},
+		{"not http", config.WebhookConfig{URL: "ftp://hooks.example.com"}, true},
+		{"bad template", config.WebhookConfig{URL: "https://hooks.example.com", BodyTemplate: "{{.Message"}, true},
+		{"two secrets", config.WebhookConfig{URL: "https://hooks.example.com", HMACSecretFile: "/key", HMACSecretEnv: "KEY"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateWebhook(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookDrain(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	cfg := config.NotificationConfig{OnFailure: true, Webhook: config.WebhookConfig{URL: server.URL}}
+	n, _ := newWebhookNotifier(t, cfg, func(err error) { t.Errorf("unexpected delivery error: %v", err) })
+
+	if err := n.NotifyFailure("prod", errors.New("permission denied")); err != nil {
+		t.Fatalf("NotifyFailure() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Drain(ctx, n); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain() with a pending delivery = %v, want the deadline error", err)
+	}
+
+	close(release)
+	if err := Drain(context.Background(), n); err != nil {
+		t.Errorf("Drain() = %v, want nil once the delivery finished", err)
+	}
+}
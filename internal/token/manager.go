@@ -31,8 +31,41 @@ func (tm *TokenManager) Get(prof *types.Profile) (string, error) {
 	return tm.store.Get(prof)
 }
 
+// Set stores a token without metadata, discarding any metadata left over
+// from a previous token.
 func (tm *TokenManager) Set(prof *types.Profile, tokenStr string) error {
-	return tm.store.Set(prof, tokenStr)
+	if err := tm.store.Set(prof, tokenStr); err != nil {
+		return err
+	}
+	return tm.store.DeleteMetadata(prof)
+}
+
+// SetFromResponse stores the token from a login response along with its metadata.
+func (tm *TokenManager) SetFromResponse(prof *types.Profile, resp *vault.VaultTokenResponse) error {
+	if err := tm.store.Set(prof, resp.ClientToken); err != nil {
+		return err
+	}
+	return tm.store.SetMetadata(prof, resp.TokenMetadata())
+}
+
+// SetFromLogin stores the token from a login response and looks it up to
+// record what login responses lack, such as the creation time and explicit
+// max TTL needed to forecast when the token stops being renewable. Tokens
+// with limited uses are not looked up, since that would spend one. The lookup
+// is informational; a failure does not fail the call.
+func (tm *TokenManager) SetFromLogin(prof *types.Profile, resp *vault.VaultTokenResponse) error {
+	if err := tm.SetFromResponse(prof, resp); err != nil {
+		return err
+	}
+	if resp.NumUses == 0 {
+		_, _ = tm.Lookup(prof) //nolint:errcheck // the login metadata is already stored
+	}
+	return nil
+}
+
+// GetMetadata returns the stored metadata for the profile's token.
+func (tm *TokenManager) GetMetadata(prof *types.Profile) (*types.TokenMetadata, error) {
+	return tm.store.GetMetadata(prof)
 }
 
 func (tm *TokenManager) Delete(prof *types.Profile) error {
@@ -63,6 +96,11 @@ func (tm *TokenManager) Renew(prof *types.Profile, increment string, opts ...pro
 		ExpiresAt:     now.Add(time.Duration(status.TTL) * time.Second),
 	}
 
+	// Metadata is a cache; failing to update it must not fail the renewal.
+	meta := tm.loadMetadata(prof)
+	meta.RecordRenewal(status.TTL, status.Renewable, now)
+	_ = tm.store.SetMetadata(prof, meta)
+
 	return tok, nil
 }
 
@@ -93,5 +131,49 @@ func (tm *TokenManager) Lookup(prof *types.Profile, opts ...proxy.Option) (*type
 		ExpiresAt:     now.Add(time.Duration(status.TTL) * time.Second),
 	}
 
+	// Metadata is a cache; failing to update it must not fail the lookup.
+	_ = tm.store.SetMetadata(prof, tm.metadataFromLookup(prof, status, now))
+
 	return tok, nil
 }
+
+// loadMetadata returns the stored metadata for the profile, or an empty record.
+func (tm *TokenManager) loadMetadata(prof *types.Profile) *types.TokenMetadata {
+	meta, err := tm.store.GetMetadata(prof)
+	if err != nil {
+		return &types.TokenMetadata{Version: types.TokenMetadataVersion}
+	}
+	return meta
+}
+
+// metadataFromLookup merges a lookup result into the stored metadata.
+func (tm *TokenManager) metadataFromLookup(prof *types.Profile, status *vault.TokenStatus, now time.Time) *types.TokenMetadata {
+	meta := tm.loadMetadata(prof)
+	meta.Accessor = status.Accessor
+	meta.Policies = status.Policies
+	meta.TokenType = status.TokenType
+	meta.IssueTime = status.CreationTime
+	meta.CreationTTL = status.CreationTTL
+	meta.ExplicitMaxTTL = status.ExplicitMaxTTL
+	meta.Renewable = status.Renewable
+	if !status.LastRenewal.IsZero() {
+		meta.LastRenewal = status.LastRenewal
+	}
+
+	// The lease duration is the TTL remaining plus the time elapsed since the
+	// lease started, so renewal thresholds are computed against the full lease.
+	meta.LeaseDuration = status.TTL
+	meta.ExpiresAt = time.Time{}
+	if status.TTL > 0 {
+		meta.ExpiresAt = now.Add(time.Duration(status.TTL) * time.Second)
+		leaseStart := status.LastRenewal
+		if leaseStart.IsZero() {
+			leaseStart = status.CreationTime
+		}
+		if !leaseStart.IsZero() && leaseStart.Before(now) {
+			meta.LeaseDuration += int(now.Sub(leaseStart).Seconds())
+		}
+	}
+
+	return meta
+}
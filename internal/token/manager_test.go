@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/xabinapal/patrol/internal/config"
 	"github.com/xabinapal/patrol/internal/proxy"
@@ -13,12 +14,14 @@ import (
 )
 
 type mockStore struct {
-	tokens map[string]string
+	tokens   map[string]string
+	metadata map[string]*types.TokenMetadata
 }
 
 func newMockStore() *mockStore {
 	return &mockStore{
-		tokens: make(map[string]string),
+		tokens:   make(map[string]string),
+		metadata: make(map[string]*types.TokenMetadata),
 	}
 }
 
@@ -42,6 +45,24 @@ func (m *mockStore) Delete(prof *types.Profile) error {
 		return tokenstore.ErrTokenNotFound
 	}
 	delete(m.tokens, key)
+	delete(m.metadata, key)
+	return nil
+}
+
+func (m *mockStore) GetMetadata(prof *types.Profile) (*types.TokenMetadata, error) {
+	if meta, ok := m.metadata[prof.Name]; ok {
+		return meta, nil
+	}
+	return nil, tokenstore.ErrMetadataNotFound
+}
+
+func (m *mockStore) SetMetadata(prof *types.Profile, meta *types.TokenMetadata) error {
+	m.metadata[prof.Name] = meta
+	return nil
+}
+
+func (m *mockStore) DeleteMetadata(prof *types.Profile) error {
+	delete(m.metadata, prof.Name)
 	return nil
 }
 
@@ -441,3 +462,203 @@ func TestTokenManager_Lookup(t *testing.T) {
 		t.Error("Lookup() should return error when vault fails")
 	}
 }
+
+func TestTokenManager_SetClearsMetadata(t *testing.T) {
+	ctx := context.Background()
+	mockStore := newMockStore()
+	tm := NewTokenManager(ctx, mockStore, &mockVaultExecutor{})
+
+	prof := types.FromConnection(&config.Connection{
+		Name: "test",
+	})
+
+	if err := mockStore.SetMetadata(prof, &types.TokenMetadata{Accessor: "old-accessor"}); err != nil {
+		t.Fatalf("failed to set metadata: %v", err)
+	}
+
+	if err := tm.Set(prof, "hvs.new-token"); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+
+	if _, err := tm.GetMetadata(prof); !errors.Is(err, tokenstore.ErrMetadataNotFound) {
+		t.Errorf("GetMetadata() error = %v, want ErrMetadataNotFound", err)
+	}
+}
+
+func TestTokenManager_SetFromResponse(t *testing.T) {
+	ctx := context.Background()
+	mockStore := newMockStore()
+	tm := NewTokenManager(ctx, mockStore, &mockVaultExecutor{})
+
+	prof := types.FromConnection(&config.Connection{
+		Name: "test",
+	})
+
+	now := time.Now()
+	resp := &vault.VaultTokenResponse{
+		ClientToken:   "hvs.test-token-12345",
+		Accessor:      "accessor-123",
+		Policies:      []string{"default", "admin"},
+		LeaseDuration: 3600,
+		Renewable:     true,
+		TokenType:     "service",
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(time.Hour),
+	}
+
+	if err := tm.SetFromResponse(prof, resp); err != nil {
+		t.Fatalf("SetFromResponse() error = %v, want nil", err)
+	}
+
+	stored, err := tm.Get(prof)
+	if err != nil {
+		t.Fatalf("Get() error = %v, want nil", err)
+	}
+	if stored != resp.ClientToken {
+		t.Errorf("stored token = %q, want %q", stored, resp.ClientToken)
+	}
+
+	meta, err := tm.GetMetadata(prof)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v, want nil", err)
+	}
+	if meta.Accessor != "accessor-123" {
+		t.Errorf("Accessor = %q, want %q", meta.Accessor, "accessor-123")
+	}
+	if meta.LeaseDuration != 3600 {
+		t.Errorf("LeaseDuration = %d, want 3600", meta.LeaseDuration)
+	}
+	if meta.TokenType != "service" {
+		t.Errorf("TokenType = %q, want %q", meta.TokenType, "service")
+	}
+}
+
+func TestTokenManager_SetFromLogin(t *testing.T) {
+	created := time.Now().Add(-time.Minute).Truncate(time.Second)
+	lookups := 0
+	executor := &mockVaultExecutor{
+		lookupTokenFunc: func(ctx context.Context, prof *types.Profile, tokenStr string, opts ...proxy.Option) (*vault.TokenStatus, error) {
+			lookups++
+			return &vault.TokenStatus{
+				TTL:            3540,
+				Renewable:      true,
+				CreationTime:   created,
+				CreationTTL:    3600,
+				ExplicitMaxTTL: 86400,
+			}, nil
+		},
+	}
+	tm := NewTokenManager(context.Background(), newMockStore(), executor)
+	prof := types.FromConnection(&config.Connection{Name: "test"})
+
+	resp := &vault.VaultTokenResponse{ClientToken: "hvs.login", LeaseDuration: 3600, Renewable: true}
+	if err := tm.SetFromLogin(prof, resp); err != nil {
+		t.Fatalf("SetFromLogin() error = %v, want nil", err)
+	}
+	meta, err := tm.GetMetadata(prof)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v, want nil", err)
+	}
+	if meta.ExplicitMaxTTL != 86400 || !meta.IssueTime.Equal(created) {
+		t.Errorf("metadata = %+v, want the max TTL and creation time from the lookup", meta)
+	}
+
+	// A lookup would spend one of the token's uses.
+	limited := &vault.VaultTokenResponse{ClientToken: "hvs.limited", LeaseDuration: 3600, NumUses: 5}
+	if err := tm.SetFromLogin(prof, limited); err != nil {
+		t.Fatalf("SetFromLogin() error = %v, want nil", err)
+	}
+	if lookups != 1 {
+		t.Errorf("lookups = %d, want 1 (none for a use-limited token)", lookups)
+	}
+}
+
+func TestTokenManager_RenewUpdatesMetadata(t *testing.T) {
+	ctx := context.Background()
+	mockStore := newMockStore()
+	tm := NewTokenManager(ctx, mockStore, &mockVaultExecutor{})
+
+	prof := types.FromConnection(&config.Connection{
+		Name: "test",
+	})
+
+	if err := mockStore.Set(prof, "hvs.test-token-12345"); err != nil {
+		t.Fatalf("failed to set token: %v", err)
+	}
+	if err := mockStore.SetMetadata(prof, &types.TokenMetadata{Accessor: "accessor-123", LeaseDuration: 60}); err != nil {
+		t.Fatalf("failed to set metadata: %v", err)
+	}
+
+	if _, err := tm.Renew(prof, ""); err != nil {
+		t.Fatalf("Renew() error = %v, want nil", err)
+	}
+
+	meta, err := tm.GetMetadata(prof)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v, want nil", err)
+	}
+	if meta.Accessor != "accessor-123" {
+		t.Errorf("Accessor = %q, want %q", meta.Accessor, "accessor-123")
+	}
+	if meta.LeaseDuration != 3600 {
+		t.Errorf("LeaseDuration = %d, want 3600", meta.LeaseDuration)
+	}
+	if meta.LastRenewal.IsZero() {
+		t.Error("LastRenewal is zero, want renewal time")
+	}
+	if meta.ExpiresAt.IsZero() {
+		t.Error("ExpiresAt is zero, want expiration time")
+	}
+}
+
+func TestTokenManager_LookupUpdatesMetadata(t *testing.T) {
+	ctx := context.Background()
+	mockStore := newMockStore()
+	prof := types.FromConnection(&config.Connection{
+		Name: "test",
+	})
+
+	if err := mockStore.Set(prof, "hvs.test-token-12345"); err != nil {
+		t.Fatalf("failed to set token: %v", err)
+	}
+
+	created := time.Now().Add(-30 * time.Minute)
+	mockVault := &mockVaultExecutor{
+		lookupTokenFunc: func(ctx context.Context, prof *types.Profile, tokenStr string, opts ...proxy.Option) (*vault.TokenStatus, error) {
+			return &vault.TokenStatus{
+				TTL:            1800,
+				Renewable:      true,
+				Accessor:       "accessor-123",
+				Policies:       []string{"default"},
+				TokenType:      "service",
+				CreationTime:   created,
+				CreationTTL:    3600,
+				ExplicitMaxTTL: 86400,
+			}, nil
+		},
+	}
+	tm := NewTokenManager(ctx, mockStore, mockVault)
+
+	if _, err := tm.Lookup(prof); err != nil {
+		t.Fatalf("Lookup() error = %v, want nil", err)
+	}
+
+	meta, err := tm.GetMetadata(prof)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v, want nil", err)
+	}
+	if meta.Accessor != "accessor-123" {
+		t.Errorf("Accessor = %q, want %q", meta.Accessor, "accessor-123")
+	}
+	if meta.ExplicitMaxTTL != 86400 {
+		t.Errorf("ExplicitMaxTTL = %d, want 86400", meta.ExplicitMaxTTL)
+	}
+	if !meta.IssueTime.Equal(created) {
+		t.Errorf("IssueTime = %v, want %v", meta.IssueTime, created)
+	}
+	// The lease started at creation, so the full lease is the remaining
+	// TTL plus the time already elapsed.
+	if meta.LeaseDuration < 3599 || meta.LeaseDuration > 3601 {
+		t.Errorf("LeaseDuration = %d, want ~3600", meta.LeaseDuration)
+	}
+}
@@ -0,0 +1,117 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/tokenstore"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// UsageDirName is the name of the directory in the data directory holding
+// the last-use records of tokens.
+const UsageDirName = "usage"
+
+// UsageLog records when the token of each profile was last handed out.
+// Every profile has an empty marker file whose modification time is the last
+// use, so concurrent commands never read and rewrite a shared file.
+type UsageLog struct {
+	dir string
+}
+
+// NewUsageLog creates a usage log backed by the directory dir.
+func NewUsageLog(dir string) *UsageLog {
+	return &UsageLog{dir: dir}
+}
+
+// DefaultUsageDir returns the path of the usage directory in the data directory.
+func DefaultUsageDir() string {
+	return filepath.Join(config.GetPaths().DataDir, UsageDirName)
+}
+
+// Touch records that the token of prof was used at now.
+func (u *UsageLog) Touch(prof *types.Profile, now time.Time) error {
+	path, err := u.path(prof)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(u.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	// #nosec G304 - path is derived from the hashed profile key
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to record token use: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to record token use: %w", err)
+	}
+	if err := os.Chtimes(path, now, now); err != nil {
+		return fmt.Errorf("failed to record token use: %w", err)
+	}
+	return nil
+}
+
+// LastUsed returns when the token of prof was last used, or the zero time
+// if no use was recorded.
+func (u *UsageLog) LastUsed(prof *types.Profile) (time.Time, error) {
+	path, err := u.path(prof)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read token use: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
+// Forget removes the last-use record of prof. Forgetting a profile without
+// a record is not an error.
+func (u *UsageLog) Forget(prof *types.Profile) error {
+	path, err := u.path(prof)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove token use record: %w", err)
+	}
+	return nil
+}
+
+// Move moves the last-use record of from to to, for a renamed profile.
+// Moving a profile without a record is not an error.
+func (u *UsageLog) Move(from, to *types.Profile) error {
+	fromPath, err := u.path(from)
+	if err != nil {
+		return err
+	}
+	toPath, err := u.path(to)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(fromPath, toPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to move token use record: %w", err)
+	}
+	return nil
+}
+
+// path returns the marker file of prof.
+func (u *UsageLog) path(prof *types.Profile) (string, error) {
+	if prof == nil {
+		return "", tokenstore.ErrProfileNil
+	}
+	key := tokenstore.KeyFromProfile(prof)
+	if key == "" {
+		return "", tokenstore.ErrProfileNameEmpty
+	}
+	return filepath.Join(u.dir, key), nil
+}
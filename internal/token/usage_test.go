@@ -0,0 +1,65 @@
+package token
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestUsageLog(t *testing.T) {
+	usage := NewUsageLog(filepath.Join(t.TempDir(), UsageDirName))
+	prof := &types.Profile{Name: "test"}
+
+	lastUsed, err := usage.LastUsed(prof)
+	if err != nil || !lastUsed.IsZero() {
+		t.Fatalf("LastUsed() before any use = %v, %v; want zero time", lastUsed, err)
+	}
+
+	used := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, at := range []time.Time{used.Add(-time.Hour), used} {
+		if err := usage.Touch(prof, at); err != nil {
+			t.Fatalf("Touch() failed: %v", err)
+		}
+	}
+	if lastUsed, err := usage.LastUsed(prof); err != nil || !lastUsed.Equal(used) {
+		t.Errorf("LastUsed() = %v, %v; want %v", lastUsed, err, used)
+	}
+	if lastUsed, _ := usage.LastUsed(&types.Profile{Name: "other"}); !lastUsed.IsZero() {
+		t.Errorf("LastUsed() of another profile = %v, want zero time", lastUsed)
+	}
+
+	if err := usage.Forget(prof); err != nil {
+		t.Fatalf("Forget() failed: %v", err)
+	}
+	if lastUsed, _ := usage.LastUsed(prof); !lastUsed.IsZero() {
+		t.Errorf("LastUsed() after Forget() = %v, want zero time", lastUsed)
+	}
+	if err := usage.Forget(prof); err != nil {
+		t.Errorf("Forget() without a record failed: %v", err)
+	}
+}
+
+func TestUsageLogMove(t *testing.T) {
+	usage := NewUsageLog(filepath.Join(t.TempDir(), UsageDirName))
+	from := &types.Profile{Name: "dev"}
+	to := &types.Profile{Name: "development"}
+
+	used := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := usage.Touch(from, used); err != nil {
+		t.Fatalf("Touch() failed: %v", err)
+	}
+	if err := usage.Move(from, to); err != nil {
+		t.Fatalf("Move() failed: %v", err)
+	}
+	if lastUsed, err := usage.LastUsed(to); err != nil || !lastUsed.Equal(used) {
+		t.Errorf("LastUsed() of the new name = %v, %v; want %v", lastUsed, err, used)
+	}
+	if lastUsed, _ := usage.LastUsed(from); !lastUsed.IsZero() {
+		t.Errorf("LastUsed() of the old name = %v, want zero time", lastUsed)
+	}
+	if err := usage.Move(from, to); err != nil {
+		t.Errorf("Move() without a record failed: %v", err)
+	}
+}
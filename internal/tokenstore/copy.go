@@ -0,0 +1,69 @@
+package tokenstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// ErrCopyVerify is returned when a copied entry does not read back intact.
+var ErrCopyVerify = errors.New("copied token failed read-back verification")
+
+// CopyEntry copies the token and metadata stored for srcProf in src to dstProf
+// in dst and reads the copy back to verify it. It reports whether metadata was
+// copied. If the copy fails after writing, the destination entry is removed,
+// so callers must not copy over an entry they want to keep.
+func CopyEntry(src TokenStore, srcProf *types.Profile, dst TokenStore, dstProf *types.Profile) (bool, error) {
+	tokenStr, err := src.Get(srcProf)
+	if err != nil {
+		return false, err
+	}
+
+	meta, err := src.GetMetadata(srcProf)
+	if err != nil && !errors.Is(err, ErrMetadataNotFound) {
+		return false, fmt.Errorf("failed to read token metadata: %w", err)
+	}
+
+	if err := copyEntry(dst, dstProf, tokenStr, meta); err != nil {
+		_ = dst.Delete(dstProf)
+		return false, err
+	}
+
+	return meta != nil, nil
+}
+
+// copyEntry writes tokenStr and meta to dst and verifies them.
+func copyEntry(dst TokenStore, prof *types.Profile, tokenStr string, meta *types.TokenMetadata) error {
+	if err := dst.Set(prof, tokenStr); err != nil {
+		return err
+	}
+
+	if meta != nil {
+		if err := dst.SetMetadata(prof, meta); err != nil {
+			return fmt.Errorf("failed to store token metadata: %w", err)
+		}
+	} else if err := dst.DeleteMetadata(prof); err != nil {
+		return fmt.Errorf("failed to clear token metadata: %w", err)
+	}
+
+	got, err := dst.Get(prof)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCopyVerify, err)
+	}
+	if got != tokenStr {
+		return fmt.Errorf("%w: token mismatch", ErrCopyVerify)
+	}
+
+	if meta != nil {
+		gotMeta, err := dst.GetMetadata(prof)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCopyVerify, err)
+		}
+		if gotMeta.Accessor != meta.Accessor || !gotMeta.ExpiresAt.Equal(meta.ExpiresAt) {
+			return fmt.Errorf("%w: metadata mismatch", ErrCopyVerify)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package tokenstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestCopyEntry(t *testing.T) {
+	src, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+	dst, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+
+	prof := &types.Profile{Name: "dev"}
+
+	// Test missing source token
+	if _, err := CopyEntry(src, prof, dst, prof); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("CopyEntry() without source token error = %v, want ErrTokenNotFound", err)
+	}
+
+	// Test token without metadata
+	if err := src.Set(prof, "hvs.token"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	copied, err := CopyEntry(src, prof, dst, prof)
+	if err != nil {
+		t.Fatalf("CopyEntry() failed: %v", err)
+	}
+	if copied {
+		t.Error("CopyEntry() reported metadata copied, want false")
+	}
+	if got, _ := dst.Get(prof); got != "hvs.token" {
+		t.Errorf("destination token = %q, want %q", got, "hvs.token")
+	}
+
+	// Test token with metadata to a different profile
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := src.SetMetadata(prof, &types.TokenMetadata{Accessor: "acc", ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("SetMetadata() failed: %v", err)
+	}
+	renamed := &types.Profile{Name: "development"}
+	copied, err = CopyEntry(src, prof, dst, renamed)
+	if err != nil {
+		t.Fatalf("CopyEntry() failed: %v", err)
+	}
+	if !copied {
+		t.Error("CopyEntry() reported metadata not copied, want true")
+	}
+	meta, err := dst.GetMetadata(renamed)
+	if err != nil {
+		t.Fatalf("GetMetadata() failed: %v", err)
+	}
+	if meta.Accessor != "acc" || !meta.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("destination metadata = %+v, want accessor %q expiring %v", meta, "acc", expiresAt)
+	}
+
+	// Source is left untouched
+	if got, _ := src.Get(prof); got != "hvs.token" {
+		t.Errorf("source token = %q, want %q", got, "hvs.token")
+	}
+}
@@ -0,0 +1,521 @@
+package tokenstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/xabinapal/patrol/internal/types"
+	"github.com/xabinapal/patrol/internal/utils"
+)
+
+const (
+	// encryptedStoreHeader is the file holding the key derivation parameters.
+	encryptedStoreHeader = "store.json"
+	// encryptedStoreVersion is the current header and entry format version.
+	encryptedStoreVersion = 1
+	// encryptedStoreKDF identifies the key derivation function in the header.
+	encryptedStoreKDF = "pbkdf2-sha256"
+	// encryptedStoreCheck is encrypted into the header to verify the key.
+	encryptedStoreCheck = "patrol-token-store"
+)
+
+// pbkdf2Iterations is the iteration count used for new stores.
+// Existing stores keep the count recorded in their header.
+var pbkdf2Iterations = 600000
+
+// EncryptedFileStoreOptions configures an EncryptedFileStore.
+type EncryptedFileStoreOptions struct {
+	// Dir is the directory holding the encrypted entries.
+	Dir string
+	// KeyFile is the path to a file whose contents are used as key material.
+	KeyFile string
+	// Passphrase is used as key material when KeyFile is not set.
+	Passphrase string
+	// PassphraseEnv names the environment variable the passphrase came from.
+	// It is only used in diagnostics.
+	PassphraseEnv string
+}
+
+// encryptedStoreHeaderFile is the on-disk header of an encrypted store.
+type encryptedStoreHeaderFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Check      []byte `json:"check"`
+}
+
+// EncryptedFileStore stores tokens in files encrypted with AES-256-GCM.
+// The key is derived with PBKDF2-SHA256 from a passphrase or key file and a
+// random per-store salt. Entries are written atomically with 0600 permissions.
+type EncryptedFileStore struct {
+	mu   sync.Mutex
+	opts EncryptedFileStoreOptions
+
+	keyMu sync.Mutex
+	aead  cipher.AEAD
+}
+
+// NewEncryptedFileStore creates a new encrypted file-based token store.
+// Key material is only read and verified on first use; call IsAvailable to
+// check the configuration.
+func NewEncryptedFileStore(opts EncryptedFileStoreOptions) (*EncryptedFileStore, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("directory path is required")
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	return &EncryptedFileStore{opts: opts}, nil
+}
+
+func (e *EncryptedFileStore) IsAvailable() error {
+	info, err := os.Stat(e.opts.Dir)
+	if err != nil {
+		return fmt.Errorf("%w: directory %s not accessible: %v", ErrStoreUnavailable, e.opts.Dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrStoreUnavailable, e.opts.Dir)
+	}
+
+	_, err = e.cipher()
+	return err
+}
+
+func (e *EncryptedFileStore) Get(prof *types.Profile) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.IsAvailable(); err != nil {
+		return "", err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := e.readEntry(key)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrTokenNotFound
+		}
+		return "", fmt.Errorf("%w: %v", ErrTokenRetrieve, err)
+	}
+
+	return string(data), nil
+}
+
+func (e *EncryptedFileStore) Set(prof *types.Profile, token string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.IsAvailable(); err != nil {
+		return err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return ErrTokenEmpty
+	}
+
+	if err := e.writeEntry(key, []byte(token)); err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenStore, err)
+	}
+
+	return nil
+}
+
+func (e *EncryptedFileStore) Delete(prof *types.Profile) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.IsAvailable(); err != nil {
+		return err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+
+	if err := e.removeEntry(key); err != nil {
+		return ErrTokenDelete
+	}
+	if err := e.removeEntry(key + metadataSuffix); err != nil {
+		return ErrTokenDelete
+	}
+
+	return nil
+}
+
+func (e *EncryptedFileStore) GetMetadata(prof *types.Profile) (*types.TokenMetadata, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.IsAvailable(); err != nil {
+		return nil, err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := e.readEntry(key + metadataSuffix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrMetadataNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", ErrTokenRetrieve, err)
+	}
+
+	return decodeMetadata(string(data))
+}
+
+func (e *EncryptedFileStore) SetMetadata(prof *types.Profile, meta *types.TokenMetadata) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.IsAvailable(); err != nil {
+		return err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeMetadata(meta)
+	if err != nil {
+		return err
+	}
+
+	if err := e.writeEntry(key+metadataSuffix, []byte(data)); err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenStore, err)
+	}
+
+	return nil
+}
+
+func (e *EncryptedFileStore) DeleteMetadata(prof *types.Profile) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.IsAvailable(); err != nil {
+		return err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+
+	if err := e.removeEntry(key + metadataSuffix); err != nil {
+		return ErrTokenDelete
+	}
+
+	return nil
+}
+
+// ListKeys returns the keys of all entries in the store directory.
+// Entries are listed without decrypting them, so no key material is needed.
+func (e *EncryptedFileStore) ListKeys() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return listDirKeys(e.opts.Dir)
+}
+
+// DeleteKey removes the token and metadata entries stored under key.
+func (e *EncryptedFileStore) DeleteKey(key string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return deleteDirKey(e.opts.Dir, key)
+}
+
+// profileKey returns the storage key for prof.
+func profileKey(prof *types.Profile) (string, error) {
+	if prof == nil {
+		return "", ErrProfileNil
+	}
+	key := KeyFromProfile(prof)
+	if key == "" {
+		return "", ErrProfileNameEmpty
+	}
+	return key, nil
+}
+
+// readEntry reads and decrypts the named entry.
+// The entry name is authenticated so entries cannot be swapped on disk.
+func (e *EncryptedFileStore) readEntry(name string) ([]byte, error) {
+	aead, err := e.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	// #nosec G304 - path is constructed from the store directory and a hashed key
+	data, err := os.ReadFile(filepath.Join(e.opts.Dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(data) < 1+nonceSize || data[0] != encryptedStoreVersion {
+		return nil, errors.New("entry is corrupt or has an unsupported format")
+	}
+
+	plaintext, err := aead.Open(nil, data[1:1+nonceSize], data[1+nonceSize:], []byte(name))
+	if err != nil {
+		return nil, errors.New("entry cannot be decrypted (corrupt or tampered)")
+	}
+
+	return plaintext, nil
+}
+
+// writeEntry encrypts and atomically writes the named entry.
+func (e *EncryptedFileStore) writeEntry(name string, plaintext []byte) error {
+	aead, err := e.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	data := append([]byte{encryptedStoreVersion}, nonce...)
+	data = aead.Seal(data, nonce, plaintext, []byte(name))
+
+	return utils.WriteFileAtomic(filepath.Join(e.opts.Dir, name), data, 0600)
+}
+
+// removeEntry removes the named entry, ignoring missing files.
+func (e *EncryptedFileStore) removeEntry(name string) error {
+	err := os.Remove(filepath.Join(e.opts.Dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// cipher returns the store cipher, deriving and verifying the key on first use.
+func (e *EncryptedFileStore) cipher() (cipher.AEAD, error) {
+	e.keyMu.Lock()
+	defer e.keyMu.Unlock()
+
+	if e.aead != nil {
+		return e.aead, nil
+	}
+
+	secret, err := e.keyMaterial()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := e.loadOrCreateHeader(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := deriveCipher(secret, header.Salt, header.Iterations)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(header.Check) < nonceSize {
+		return nil, fmt.Errorf("%w: store header %s is corrupt", ErrStoreUnavailable, e.headerPath())
+	}
+	check, err := aead.Open(nil, header.Check[:nonceSize], header.Check[nonceSize:], []byte(encryptedStoreHeader))
+	if err != nil || string(check) != encryptedStoreCheck {
+		return nil, fmt.Errorf("%w: %s does not match the key this store was created with", ErrStoreAccessDenied, e.keySource())
+	}
+
+	e.aead = aead
+	return aead, nil
+}
+
+// keyMaterial returns the passphrase or key file contents.
+func (e *EncryptedFileStore) keyMaterial() ([]byte, error) {
+	if e.opts.KeyFile == "" {
+		if e.opts.Passphrase == "" {
+			hint := "a passphrase"
+			if e.opts.PassphraseEnv != "" {
+				hint = "$" + e.opts.PassphraseEnv
+			}
+			return nil, fmt.Errorf("%w: encrypted file store has no key; set %s or configure token_store.key_file",
+				ErrStoreUnavailable, hint)
+		}
+		return []byte(e.opts.Passphrase), nil
+	}
+
+	info, err := os.Stat(e.opts.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key file not accessible: %v", ErrStoreUnavailable, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%w: key file %s is not a regular file", ErrStoreUnavailable, e.opts.KeyFile)
+	}
+	// Windows does not use Unix permission bits.
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%w: key file %s is accessible by other users (mode %04o); run 'chmod 600 %s'",
+			ErrStoreAccessDenied, e.opts.KeyFile, info.Mode().Perm(), e.opts.KeyFile)
+	}
+
+	// #nosec G304 - key file path comes from the user's configuration
+	data, err := os.ReadFile(e.opts.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read key file: %v", ErrStoreAccessDenied, err)
+	}
+
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: key file %s is empty", ErrStoreUnavailable, e.opts.KeyFile)
+	}
+
+	return data, nil
+}
+
+// loadOrCreateHeader reads the store header, creating it for a new store.
+func (e *EncryptedFileStore) loadOrCreateHeader(secret []byte) (*encryptedStoreHeaderFile, error) {
+	header, err := e.readHeader()
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return header, err
+	}
+
+	header = &encryptedStoreHeaderFile{
+		Version:    encryptedStoreVersion,
+		KDF:        encryptedStoreKDF,
+		Iterations: pbkdf2Iterations,
+		Salt:       make([]byte, 32),
+	}
+	if _, err := rand.Read(header.Salt); err != nil {
+		return nil, fmt.Errorf("%w: failed to generate salt: %v", ErrStoreUnavailable, err)
+	}
+
+	aead, err := deriveCipher(secret, header.Salt, header.Iterations)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("%w: failed to generate nonce: %v", ErrStoreUnavailable, err)
+	}
+	header.Check = aead.Seal(nonce, nonce, []byte(encryptedStoreCheck), []byte(encryptedStoreHeader))
+
+	data, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to encode store header: %v", ErrStoreUnavailable, err)
+	}
+
+	// Link the header into place so a concurrent process creating the same
+	// store cannot overwrite it; the loser reads the winner's header.
+	tmpPath, err := writeTempFile(e.opts.Dir, encryptedStoreHeader, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to write store header: %v", ErrStoreUnavailable, err)
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if err := os.Link(tmpPath, e.headerPath()); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return e.readHeader()
+		}
+		return nil, fmt.Errorf("%w: failed to write store header: %v", ErrStoreUnavailable, err)
+	}
+
+	return header, nil
+}
+
+// writeTempFile writes data to a new 0600 temporary file in dir and returns its path.
+func writeTempFile(dir, name string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if err := tmp.Chmod(0600); err == nil {
+		_, err = tmp.Write(data)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// readHeader reads and validates the store header.
+func (e *EncryptedFileStore) readHeader() (*encryptedStoreHeaderFile, error) {
+	// #nosec G304 - path is constructed from the store directory
+	data, err := os.ReadFile(e.headerPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: failed to read store header: %v", ErrStoreUnavailable, err)
+	}
+
+	var header encryptedStoreHeaderFile
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("%w: store header %s is corrupt: %v", ErrStoreUnavailable, e.headerPath(), err)
+	}
+	if header.Version != encryptedStoreVersion || header.KDF != encryptedStoreKDF {
+		return nil, fmt.Errorf("%w: store header %s has unsupported version %d (%s)",
+			ErrStoreUnavailable, e.headerPath(), header.Version, header.KDF)
+	}
+	if len(header.Salt) == 0 || header.Iterations <= 0 {
+		return nil, fmt.Errorf("%w: store header %s is corrupt", ErrStoreUnavailable, e.headerPath())
+	}
+
+	return &header, nil
+}
+
+func (e *EncryptedFileStore) headerPath() string {
+	return filepath.Join(e.opts.Dir, encryptedStoreHeader)
+}
+
+// keySource describes where the key material came from, for diagnostics.
+func (e *EncryptedFileStore) keySource() string {
+	if e.opts.KeyFile != "" {
+		return "key file " + e.opts.KeyFile
+	}
+	if e.opts.PassphraseEnv != "" {
+		return "passphrase from $" + e.opts.PassphraseEnv
+	}
+	return "passphrase"
+}
+
+// deriveCipher derives an AES-256-GCM cipher from secret and salt.
+func deriveCipher(secret, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(secret), salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
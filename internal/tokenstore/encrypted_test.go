@@ -0,0 +1,262 @@
+package tokenstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// newTestEncryptedStore creates an encrypted store with a cheap key derivation.
+func newTestEncryptedStore(t *testing.T, opts EncryptedFileStoreOptions) *EncryptedFileStore {
+	t.Helper()
+
+	iterations := pbkdf2Iterations
+	pbkdf2Iterations = 1000
+	t.Cleanup(func() { pbkdf2Iterations = iterations })
+
+	store, err := NewEncryptedFileStore(opts)
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() failed: %v", err)
+	}
+	return store
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	store := newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: tmpDir, Passphrase: "correct horse"})
+
+	if err := store.IsAvailable(); err != nil {
+		t.Fatalf("IsAvailable() should not error: %v", err)
+	}
+
+	// Test Get non-existent
+	prof := &types.Profile{Name: "test-key"}
+	if _, err := store.Get(prof); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Get(non-existent) should return ErrTokenNotFound, got %v", err)
+	}
+
+	// Test Set and Get
+	if err := store.Set(prof, "hvs.secret-token"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	token, err := store.Get(prof)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if token != "hvs.secret-token" {
+		t.Errorf("Get() = %q, want %q", token, "hvs.secret-token")
+	}
+
+	// Test the token is not stored in plaintext and has secure permissions
+	path := filepath.Join(tmpDir, KeyFromProfile(prof))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read entry: %v", err)
+	}
+	if strings.Contains(string(data), "hvs.secret-token") {
+		t.Error("entry contains the plaintext token")
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat entry: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("entry permissions = %o, want 600", perm)
+		}
+	}
+
+	// Test metadata round trip
+	if err := store.SetMetadata(prof, &types.TokenMetadata{Accessor: "accessor-123"}); err != nil {
+		t.Fatalf("SetMetadata() failed: %v", err)
+	}
+	meta, err := store.GetMetadata(prof)
+	if err != nil {
+		t.Fatalf("GetMetadata() failed: %v", err)
+	}
+	if meta.Accessor != "accessor-123" {
+		t.Errorf("GetMetadata() Accessor = %q, want %q", meta.Accessor, "accessor-123")
+	}
+
+	// Test Delete removes token and metadata
+	if err := store.Delete(prof); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := store.Get(prof); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Get() after Delete() should return ErrTokenNotFound, got %v", err)
+	}
+	if _, err := store.GetMetadata(prof); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("GetMetadata() after Delete() should return ErrMetadataNotFound, got %v", err)
+	}
+
+	// Test Delete non-existent (should not error)
+	if err := store.Delete(prof); err != nil {
+		t.Errorf("Delete(non-existent) should not error: %v", err)
+	}
+}
+
+func TestEncryptedFileStorePersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+	prof := &types.Profile{Name: "persistent"}
+
+	store1 := newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: tmpDir, Passphrase: "passphrase"})
+	if err := store1.Set(prof, "persistent-token"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	store2 := newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: tmpDir, Passphrase: "passphrase"})
+	token, err := store2.Get(prof)
+	if err != nil {
+		t.Fatalf("Get() from new store failed: %v", err)
+	}
+	if token != "persistent-token" {
+		t.Errorf("Get() = %q, want %q", token, "persistent-token")
+	}
+}
+
+func TestEncryptedFileStoreWrongPassphrase(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	store1 := newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: tmpDir, Passphrase: "right"})
+	if err := store1.Set(&types.Profile{Name: "test"}, "token"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	store2 := newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: tmpDir, Passphrase: "wrong", PassphraseEnv: "MY_PASSPHRASE"})
+	err := store2.IsAvailable()
+	if !errors.Is(err, ErrStoreAccessDenied) {
+		t.Fatalf("IsAvailable() with wrong passphrase should return ErrStoreAccessDenied, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "$MY_PASSPHRASE") {
+		t.Errorf("IsAvailable() error should name the passphrase source, got %v", err)
+	}
+}
+
+func TestEncryptedFileStoreTampered(t *testing.T) {
+	tmpDir := t.TempDir()
+	store := newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: tmpDir, Passphrase: "passphrase"})
+
+	prof := &types.Profile{Name: "test"}
+	other := &types.Profile{Name: "other"}
+	if err := store.Set(prof, "token"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	// An entry copied to another profile's key must not decrypt.
+	data, err := os.ReadFile(filepath.Join(tmpDir, KeyFromProfile(prof)))
+	if err != nil {
+		t.Fatalf("failed to read entry: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, KeyFromProfile(other)), data, 0600); err != nil {
+		t.Fatalf("failed to write entry: %v", err)
+	}
+
+	if _, err := store.Get(other); !errors.Is(err, ErrTokenRetrieve) {
+		t.Errorf("Get() of swapped entry should return ErrTokenRetrieve, got %v", err)
+	}
+}
+
+func TestEncryptedFileStoreNoKey(t *testing.T) {
+	store := newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: t.TempDir(), PassphraseEnv: "MY_PASSPHRASE"})
+
+	err := store.IsAvailable()
+	if !errors.Is(err, ErrStoreUnavailable) {
+		t.Fatalf("IsAvailable() without key should return ErrStoreUnavailable, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "$MY_PASSPHRASE") {
+		t.Errorf("IsAvailable() error should name the passphrase variable, got %v", err)
+	}
+
+	if err := store.Set(&types.Profile{Name: "test"}, "token"); !errors.Is(err, ErrStoreUnavailable) {
+		t.Errorf("Set() without key should return ErrStoreUnavailable, got %v", err)
+	}
+}
+
+func TestEncryptedFileStoreKeyFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	keyFile := filepath.Join(tmpDir, "store.key")
+	if err := os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef\n"), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	store := newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: filepath.Join(tmpDir, "tokens"), KeyFile: keyFile})
+	if err := store.IsAvailable(); err != nil {
+		t.Fatalf("IsAvailable() with key file should not error: %v", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	// Test key file readable by other users is rejected
+	if err := os.Chmod(keyFile, 0644); err != nil {
+		t.Fatalf("failed to chmod key file: %v", err)
+	}
+	store = newTestEncryptedStore(t, EncryptedFileStoreOptions{Dir: filepath.Join(tmpDir, "tokens"), KeyFile: keyFile})
+	if err := store.IsAvailable(); !errors.Is(err, ErrStoreAccessDenied) {
+		t.Errorf("IsAvailable() with world-readable key file should return ErrStoreAccessDenied, got %v", err)
+	}
+}
+
+func TestEncryptedFileStoreEmptyDir(t *testing.T) {
+	if _, err := NewEncryptedFileStore(EncryptedFileStoreOptions{}); err == nil {
+		t.Error("NewEncryptedFileStore() with empty dir should fail")
+	}
+}
+
+func TestNewTokenStoreFromConfig(t *testing.T) {
+	t.Setenv(TestStoreEnvVar, "")
+
+	tests := []struct {
+		name     string
+		cfg      config.TokenStoreConfig
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "default is keyring",
+			cfg:      config.TokenStoreConfig{},
+			wantType: "*tokenstore.KeyringStore",
+		},
+		{
+			name:     "explicit keyring",
+			cfg:      config.TokenStoreConfig{Type: config.TokenStoreKeyring},
+			wantType: "*tokenstore.KeyringStore",
+		},
+		{
+			name:     "encrypted file",
+			cfg:      config.TokenStoreConfig{Type: config.TokenStoreEncryptedFile, Path: t.TempDir()},
+			wantType: "*tokenstore.EncryptedFileStore",
+		},
+		{
+			name:    "unknown type",
+			cfg:     config.TokenStoreConfig{Type: "plaintext"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewTokenStoreFromConfig(&tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewTokenStoreFromConfig() expected error, got %T", store)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTokenStoreFromConfig() unexpected error: %v", err)
+			}
+			if got := fmt.Sprintf("%T", store); got != tt.wantType {
+				t.Errorf("NewTokenStoreFromConfig() = %s, want %s", got, tt.wantType)
+			}
+		})
+	}
+}
@@ -131,5 +131,109 @@ func (f *FileStore) Delete(prof *types.Profile) error {
 		return ErrTokenDelete
 	}
 
+	err = os.Remove(path + metadataSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return ErrTokenDelete
+	}
+
+	return nil
+}
+
+func (f *FileStore) GetMetadata(prof *types.Profile) (*types.TokenMetadata, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.IsAvailable(); err != nil {
+		return nil, err
+	}
+
+	if prof == nil {
+		return nil, ErrProfileNil
+	}
+	key := KeyFromProfile(prof)
+	if key == "" {
+		return nil, ErrProfileNameEmpty
+	}
+
+	path := filepath.Join(f.dir, key+metadataSuffix)
+
+	// #nosec G304 - path is constructed from the store directory and a hashed key
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrMetadataNotFound
+		}
+		return nil, ErrTokenRetrieve
+	}
+
+	return decodeMetadata(string(data))
+}
+
+func (f *FileStore) SetMetadata(prof *types.Profile, meta *types.TokenMetadata) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.IsAvailable(); err != nil {
+		return err
+	}
+
+	if prof == nil {
+		return ErrProfileNil
+	}
+	key := KeyFromProfile(prof)
+	if key == "" {
+		return ErrProfileNameEmpty
+	}
+
+	data, err := encodeMetadata(meta)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(f.dir, key+metadataSuffix)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		return ErrTokenStore
+	}
+
 	return nil
 }
+
+func (f *FileStore) DeleteMetadata(prof *types.Profile) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.IsAvailable(); err != nil {
+		return err
+	}
+
+	if prof == nil {
+		return ErrProfileNil
+	}
+	key := KeyFromProfile(prof)
+	if key == "" {
+		return ErrProfileNameEmpty
+	}
+
+	err := os.Remove(filepath.Join(f.dir, key+metadataSuffix))
+	if err != nil && !os.IsNotExist(err) {
+		return ErrTokenDelete
+	}
+
+	return nil
+}
+
+// ListKeys returns the keys of all entries in the store directory.
+func (f *FileStore) ListKeys() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return listDirKeys(f.dir)
+}
+
+// DeleteKey removes the token and metadata entries stored under key.
+func (f *FileStore) DeleteKey(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return deleteDirKey(f.dir, key)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/xabinapal/patrol/internal/types"
 )
@@ -121,3 +122,95 @@ func TestFileStoreIsAvailableNotDir(t *testing.T) {
 		t.Error("IsAvailable() should fail for non-directory")
 	}
 }
+
+func TestFileStoreMetadata(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+
+	prof := &types.Profile{Name: "test-key"}
+
+	// Test GetMetadata non-existent
+	if _, err := store.GetMetadata(prof); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("GetMetadata(non-existent) should return ErrMetadataNotFound, got %v", err)
+	}
+
+	// Test SetMetadata nil
+	if err := store.SetMetadata(prof, nil); !errors.Is(err, ErrMetadataNil) {
+		t.Errorf("SetMetadata(nil) should return ErrMetadataNil, got %v", err)
+	}
+
+	// Test SetMetadata and GetMetadata
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	meta := &types.TokenMetadata{
+		Accessor:       "accessor-123",
+		Policies:       []string{"default", "admin"},
+		TokenType:      "service",
+		CreationTTL:    3600,
+		ExplicitMaxTTL: 86400,
+		LeaseDuration:  3600,
+		Renewable:      true,
+		ExpiresAt:      expiresAt,
+	}
+	if err := store.Set(prof, "test-token"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if err := store.SetMetadata(prof, meta); err != nil {
+		t.Fatalf("SetMetadata() failed: %v", err)
+	}
+
+	got, err := store.GetMetadata(prof)
+	if err != nil {
+		t.Fatalf("GetMetadata() failed: %v", err)
+	}
+	if got.Version != types.TokenMetadataVersion {
+		t.Errorf("GetMetadata() Version = %d, want %d", got.Version, types.TokenMetadataVersion)
+	}
+	if got.Accessor != meta.Accessor {
+		t.Errorf("GetMetadata() Accessor = %q, want %q", got.Accessor, meta.Accessor)
+	}
+	if len(got.Policies) != 2 {
+		t.Errorf("GetMetadata() Policies = %v, want %v", got.Policies, meta.Policies)
+	}
+	if got.ExplicitMaxTTL != meta.ExplicitMaxTTL {
+		t.Errorf("GetMetadata() ExplicitMaxTTL = %d, want %d", got.ExplicitMaxTTL, meta.ExplicitMaxTTL)
+	}
+	if !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("GetMetadata() ExpiresAt = %v, want %v", got.ExpiresAt, expiresAt)
+	}
+	if got.UpdatedAt.IsZero() {
+		t.Error("GetMetadata() UpdatedAt should be set")
+	}
+
+	// Test Delete removes metadata along with the token
+	if err := store.Delete(prof); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := store.GetMetadata(prof); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("GetMetadata() after Delete() should return ErrMetadataNotFound, got %v", err)
+	}
+
+	// Test DeleteMetadata non-existent (should not error)
+	if err := store.DeleteMetadata(prof); err != nil {
+		t.Errorf("DeleteMetadata(non-existent) should not error: %v", err)
+	}
+}
+
+func TestFileStoreMetadataVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, err := NewFileStore(tmpDir)
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+
+	prof := &types.Profile{Name: "test-key"}
+	path := filepath.Join(tmpDir, KeyFromProfile(prof)+metadataSuffix)
+	if err := os.WriteFile(path, []byte(`{"version":99}`), 0600); err != nil {
+		t.Fatalf("failed to write metadata: %v", err)
+	}
+
+	if _, err := store.GetMetadata(prof); !errors.Is(err, ErrMetadataVersion) {
+		t.Errorf("GetMetadata() with unknown version should return ErrMetadataVersion, got %v", err)
+	}
+}
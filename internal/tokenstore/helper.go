@@ -0,0 +1,353 @@
+package tokenstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// Helper protocol
+//
+// A helper store delegates storage to an external executable, similar to git
+// credential helpers. For every operation Patrol runs the helper with the
+// operation name as its last argument, writes one HelperRequest as JSON to its
+// stdin and reads one HelperResponse as JSON from its stdout. A helper reports
+// failures through HelperResponse.Error; a non-zero exit status without a JSON
+// error is treated as a generic failure and its stderr is included in the error.
+
+const (
+	// HelperProtocolVersion is the protocol version sent in every request.
+	HelperProtocolVersion = 1
+
+	// HelperPrefix is prepended to bare helper names ("pass" runs "patrol-store-pass").
+	HelperPrefix = "patrol-store-"
+
+	// DefaultHelperTimeout bounds a single helper invocation.
+	DefaultHelperTimeout = 30 * time.Second
+)
+
+// Helper operations.
+const (
+	// HelperOpGet returns the secret stored under the key.
+	HelperOpGet = "get"
+	// HelperOpSet stores the secret under the key, replacing any existing value.
+	HelperOpSet = "set"
+	// HelperOpDelete removes the key; deleting a missing key is not an error.
+	HelperOpDelete = "delete"
+	// HelperOpAvailable reports whether the backend can be used.
+	HelperOpAvailable = "available"
+)
+
+// Helper error codes, mapped onto the tokenstore sentinel errors.
+const (
+	// HelperErrNotFound maps to ErrTokenNotFound.
+	HelperErrNotFound = "not_found"
+	// HelperErrAccessDenied maps to ErrStoreAccessDenied.
+	HelperErrAccessDenied = "access_denied"
+	// HelperErrUnavailable maps to ErrStoreUnavailable.
+	HelperErrUnavailable = "unavailable"
+)
+
+// HelperRequest is the JSON request written to a helper's stdin.
+type HelperRequest struct {
+	// Version is the protocol version.
+	Version int `json:"version"`
+	// Operation is one of get, set, delete or available.
+	Operation string `json:"operation"`
+	// Key is the storage key (empty for available).
+	Key string `json:"key,omitempty"`
+	// Profile is the profile name the key belongs to, for labeling entries.
+	Profile string `json:"profile,omitempty"`
+	// Secret is the value to store (set only).
+	Secret string `json:"secret,omitempty"`
+}
+
+// HelperResponse is the JSON response read from a helper's stdout.
+type HelperResponse struct {
+	// Secret is the stored value (get only).
+	Secret string `json:"secret,omitempty"`
+	// Error is set when the operation failed.
+	Error *HelperError `json:"error,omitempty"`
+}
+
+// HelperError describes a failed helper operation.
+type HelperError struct {
+	// Code is one of the HelperErr* codes, or any other value for generic errors.
+	Code string `json:"code"`
+	// Message is a human-readable description.
+	Message string `json:"message,omitempty"`
+}
+
+// HelperStore implements TokenStore by delegating to an external helper executable.
+type HelperStore struct {
+	mu        sync.Mutex
+	command   string
+	args      []string
+	timeout   time.Duration
+	available atomic.Bool
+}
+
+// NewHelperStore creates a new HelperStore. A bare helper name without a path
+// separator is resolved as HelperPrefix+name unless it already has the prefix;
+// a path must be absolute, since a relative one would depend on the directory
+// each command happens to run from. A zero timeout uses DefaultHelperTimeout.
+func NewHelperStore(helper string, args []string, timeout time.Duration) (*HelperStore, error) {
+	if helper == "" {
+		return nil, fmt.Errorf("helper command is required")
+	}
+
+	command := helper
+	switch {
+	case strings.ContainsAny(helper, `/\`):
+		if !filepath.IsAbs(helper) {
+			return nil, fmt.Errorf("helper path must be absolute, got %q", helper)
+		}
+		command = filepath.Clean(helper)
+	case !strings.HasPrefix(helper, HelperPrefix):
+		command = HelperPrefix + helper
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultHelperTimeout
+	}
+
+	return &HelperStore{
+		command: command,
+		args:    args,
+		timeout: timeout,
+	}, nil
+}
+
+// IsAvailable asks the helper whether its backend is usable.
+// A successful answer is cached for the lifetime of the store.
+func (h *HelperStore) IsAvailable() error {
+	if h.available.Load() {
+		return nil
+	}
+
+	if _, err := exec.LookPath(h.command); err != nil {
+		return fmt.Errorf("%w: helper %s not found: %v", ErrStoreUnavailable, h.command, err)
+	}
+
+	if _, err := h.call(HelperOpAvailable, "", "", "", ErrStoreUnavailable); err != nil {
+		return err
+	}
+
+	h.available.Store(true)
+	return nil
+}
+
+func (h *HelperStore) Get(prof *types.Profile) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.IsAvailable(); err != nil {
+		return "", err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return "", err
+	}
+
+	return h.call(HelperOpGet, key, prof.Name, "", ErrTokenRetrieve)
+}
+
+func (h *HelperStore) Set(prof *types.Profile, token string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.IsAvailable(); err != nil {
+		return err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return ErrTokenEmpty
+	}
+
+	_, err = h.call(HelperOpSet, key, prof.Name, token, ErrTokenStore)
+	return err
+}
+
+func (h *HelperStore) Delete(prof *types.Profile) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.IsAvailable(); err != nil {
+		return err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+
+	if err := h.delete(key, prof.Name); err != nil {
+		return err
+	}
+	return h.delete(key+metadataSuffix, prof.Name)
+}
+
+func (h *HelperStore) GetMetadata(prof *types.Profile) (*types.TokenMetadata, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.IsAvailable(); err != nil {
+		return nil, err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := h.call(HelperOpGet, key+metadataSuffix, prof.Name, "", ErrTokenRetrieve)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, ErrMetadataNotFound
+		}
+		return nil, err
+	}
+
+	return decodeMetadata(data)
+}
+
+func (h *HelperStore) SetMetadata(prof *types.Profile, meta *types.TokenMetadata) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.IsAvailable(); err != nil {
+		return err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeMetadata(meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.call(HelperOpSet, key+metadataSuffix, prof.Name, data, ErrTokenStore)
+	return err
+}
+
+func (h *HelperStore) DeleteMetadata(prof *types.Profile) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.IsAvailable(); err != nil {
+		return err
+	}
+
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+
+	return h.delete(key+metadataSuffix, prof.Name)
+}
+
+// delete removes key, treating a missing key as success.
+func (h *HelperStore) delete(key, profileName string) error {
+	_, err := h.call(HelperOpDelete, key, profileName, "", ErrTokenDelete)
+	if err != nil && !errors.Is(err, ErrTokenNotFound) {
+		return err
+	}
+	return nil
+}
+
+// call runs the helper for a single operation and returns the response secret.
+// Failures without a specific helper error code are wrapped in fallback.
+func (h *HelperStore) call(op, key, profileName, secret string, fallback error) (string, error) {
+	req, err := json.Marshal(&HelperRequest{
+		Version:   HelperProtocolVersion,
+		Operation: op,
+		Key:       key,
+		Profile:   profileName,
+		Secret:    secret,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to encode helper request: %v", fallback, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	args := append(append([]string{}, h.args...), op)
+	// #nosec G204 - the helper command comes from the user's configuration
+	cmd := exec.CommandContext(ctx, h.command, args...)
+	cmd.Stdin = bytes.NewReader(req)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%w: helper %s timed out after %s", ErrStoreUnavailable, h.command, h.timeout)
+	}
+
+	var resp HelperResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err == nil && resp.Error != nil {
+		return "", helperErr(resp.Error, fallback)
+	}
+
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return "", fmt.Errorf("%w: failed to run helper %s: %v", ErrStoreUnavailable, h.command, runErr)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = runErr.Error()
+		}
+		return "", fmt.Errorf("%w: helper %s %s failed: %s", fallback, h.command, op, msg)
+	}
+
+	if op == HelperOpGet {
+		if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+			return "", fmt.Errorf("%w: invalid response from helper %s: %v", fallback, h.command, err)
+		}
+		if resp.Secret == "" {
+			return "", fmt.Errorf("%w: helper %s returned an empty secret", fallback, h.command)
+		}
+	}
+
+	return resp.Secret, nil
+}
+
+// helperErr maps a helper error onto the tokenstore sentinel errors.
+func helperErr(e *HelperError, fallback error) error {
+	var sentinel error
+	switch e.Code {
+	case HelperErrNotFound:
+		return ErrTokenNotFound
+	case HelperErrAccessDenied:
+		sentinel = ErrStoreAccessDenied
+	case HelperErrUnavailable:
+		sentinel = ErrStoreUnavailable
+	default:
+		sentinel = fallback
+	}
+
+	if e.Message == "" {
+		return sentinel
+	}
+	return fmt.Errorf("%w: %s", sentinel, e.Message)
+}
@@ -0,0 +1,215 @@
+package tokenstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+const (
+	helperProcessEnv = "PATROL_TEST_HELPER_PROCESS"
+	helperDirEnv     = "PATROL_TEST_HELPER_DIR"
+	helperModeEnv    = "PATROL_TEST_HELPER_MODE"
+)
+
+// TestHelperProcess is not a real test: it acts as a store helper when the
+// test binary is re-executed by newTestHelperStore. Secrets are kept as files
+// in the directory named by PATROL_TEST_HELPER_DIR.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(helperProcessEnv) != "1" {
+		return
+	}
+
+	var req HelperRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "bad request: %v", err)
+		os.Exit(2)
+	}
+
+	respond := func(resp HelperResponse) {
+		_ = json.NewEncoder(os.Stdout).Encode(&resp)
+		os.Exit(0)
+	}
+
+	switch os.Getenv(helperModeEnv) {
+	case "denied":
+		respond(HelperResponse{Error: &HelperError{Code: HelperErrAccessDenied, Message: "vault is locked"}})
+	case "crash":
+		fmt.Fprint(os.Stderr, "helper crashed")
+		os.Exit(1)
+	case "slow":
+		time.Sleep(5 * time.Second)
+	}
+
+	path := filepath.Join(os.Getenv(helperDirEnv), req.Key)
+	switch req.Operation {
+	case HelperOpAvailable:
+		respond(HelperResponse{})
+	case HelperOpGet:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			respond(HelperResponse{Error: &HelperError{Code: HelperErrNotFound}})
+		}
+		respond(HelperResponse{Secret: string(data)})
+	case HelperOpSet:
+		if err := os.WriteFile(path, []byte(req.Secret), 0600); err != nil {
+			respond(HelperResponse{Error: &HelperError{Code: "io", Message: err.Error()}})
+		}
+		respond(HelperResponse{})
+	case HelperOpDelete:
+		if err := os.Remove(path); err != nil {
+			respond(HelperResponse{Error: &HelperError{Code: HelperErrNotFound}})
+		}
+		respond(HelperResponse{})
+	}
+	os.Exit(2)
+}
+
+// newTestHelperStore returns a HelperStore that runs this test binary as its helper.
+func newTestHelperStore(t *testing.T, mode string, timeout time.Duration) *HelperStore {
+	t.Helper()
+
+	t.Setenv(helperProcessEnv, "1")
+	t.Setenv(helperDirEnv, t.TempDir())
+	t.Setenv(helperModeEnv, mode)
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("os.Executable() failed: %v", err)
+	}
+
+	store, err := NewHelperStore(exe, []string{"-test.run=^TestHelperProcess$", "--"}, timeout)
+	if err != nil {
+		t.Fatalf("NewHelperStore() failed: %v", err)
+	}
+	return store
+}
+
+func TestNewHelperStore(t *testing.T) {
+	abs := filepath.Join(t.TempDir(), "bin", "my-helper")
+
+	tests := []struct {
+		helper  string
+		command string
+	}{
+		{helper: "pass", command: "patrol-store-pass"},
+		{helper: "patrol-store-1password", command: "patrol-store-1password"},
+		{helper: abs, command: abs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.helper, func(t *testing.T) {
+			store, err := NewHelperStore(tt.helper, nil, 0)
+			if err != nil {
+				t.Fatalf("NewHelperStore() failed: %v", err)
+			}
+			if store.command != tt.command {
+				t.Errorf("NewHelperStore(%q) command = %q, want %q", tt.helper, store.command, tt.command)
+			}
+			if store.timeout != DefaultHelperTimeout {
+				t.Errorf("NewHelperStore(%q) timeout = %v, want %v", tt.helper, store.timeout, DefaultHelperTimeout)
+			}
+		})
+	}
+
+	if _, err := NewHelperStore("", nil, 0); err == nil {
+		t.Error("NewHelperStore() with empty helper should fail")
+	}
+	if _, err := NewHelperStore("./bin/my-helper", nil, 0); err == nil {
+		t.Error("NewHelperStore() with a relative helper path should fail")
+	}
+}
+
+func TestHelperStore(t *testing.T) {
+	store := newTestHelperStore(t, "", 0)
+
+	if err := store.IsAvailable(); err != nil {
+		t.Fatalf("IsAvailable() should not error: %v", err)
+	}
+
+	prof := &types.Profile{Name: "test"}
+
+	// Test Get non-existent
+	if _, err := store.Get(prof); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Get(non-existent) should return ErrTokenNotFound, got %v", err)
+	}
+	if _, err := store.GetMetadata(prof); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("GetMetadata(non-existent) should return ErrMetadataNotFound, got %v", err)
+	}
+
+	// Test Set and Get
+	if err := store.Set(prof, "hvs.helper-token"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	token, err := store.Get(prof)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if token != "hvs.helper-token" {
+		t.Errorf("Get() = %q, want %q", token, "hvs.helper-token")
+	}
+
+	// Test metadata round trip
+	if err := store.SetMetadata(prof, &types.TokenMetadata{Accessor: "accessor-123"}); err != nil {
+		t.Fatalf("SetMetadata() failed: %v", err)
+	}
+	meta, err := store.GetMetadata(prof)
+	if err != nil {
+		t.Fatalf("GetMetadata() failed: %v", err)
+	}
+	if meta.Accessor != "accessor-123" {
+		t.Errorf("GetMetadata() Accessor = %q, want %q", meta.Accessor, "accessor-123")
+	}
+
+	// Test Delete removes token and metadata, and is idempotent
+	if err := store.Delete(prof); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := store.Get(prof); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Get() after Delete() should return ErrTokenNotFound, got %v", err)
+	}
+	if _, err := store.GetMetadata(prof); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("GetMetadata() after Delete() should return ErrMetadataNotFound, got %v", err)
+	}
+	if err := store.Delete(prof); err != nil {
+		t.Errorf("Delete(non-existent) should not error: %v", err)
+	}
+}
+
+func TestHelperStoreErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		timeout time.Duration
+		wantErr error
+	}{
+		{name: "access denied", mode: "denied", wantErr: ErrStoreAccessDenied},
+		{name: "helper exits non-zero", mode: "crash", wantErr: ErrStoreUnavailable},
+		{name: "helper times out", mode: "slow", timeout: 100 * time.Millisecond, wantErr: ErrStoreUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestHelperStore(t, tt.mode, tt.timeout)
+			if err := store.IsAvailable(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("IsAvailable() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("helper not found", func(t *testing.T) {
+		store, err := NewHelperStore("does-not-exist-anywhere", nil, 0)
+		if err != nil {
+			t.Fatalf("NewHelperStore() failed: %v", err)
+		}
+		if err := store.IsAvailable(); !errors.Is(err, ErrStoreUnavailable) {
+			t.Errorf("IsAvailable() error = %v, want ErrStoreUnavailable", err)
+		}
+	})
+}
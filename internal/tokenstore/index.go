@@ -0,0 +1,360 @@
+package tokenstore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/config"
+	"github.com/xabinapal/patrol/internal/types"
+	"github.com/xabinapal/patrol/internal/utils"
+)
+
+const (
+	// IndexFileName is the name of the key index file in the data directory.
+	IndexFileName = "token-index.json"
+
+	// indexVersion is the current index file format version.
+	indexVersion = 1
+
+	// indexLockTimeout bounds how long an update waits for another process
+	// holding the index lock.
+	indexLockTimeout = 5 * time.Second
+	// indexLockStale is the age after which a lock file is assumed to be left
+	// behind by a process that died while holding it.
+	indexLockStale = 30 * time.Second
+	// indexLockRetry is the interval between attempts to take the lock.
+	indexLockRetry = 10 * time.Millisecond
+)
+
+// IndexEntry records which profile a stored key belongs to.
+type IndexEntry struct {
+	// Store is the token store type holding the entry.
+	Store string `json:"store"`
+	// Key is the storage key derived with KeyFromProfile.
+	Key string `json:"key"`
+	// Profile is the profile name the key was derived from.
+	Profile string `json:"profile"`
+	// UpdatedAt is when a token was last written under the key.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// indexFile is the on-disk format of the key index.
+type indexFile struct {
+	Version int          `json:"version"`
+	Entries []IndexEntry `json:"entries"`
+}
+
+// find returns the position of the entry for key in store, or -1.
+func (f *indexFile) find(store, key string) int {
+	for i, e := range f.Entries {
+		if e.Store == store && e.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// KeyIndex maps hashed storage keys back to profile names, so entries left
+// behind by renamed or removed profiles can be found and cleaned up.
+// The index is advisory: it is only ever used to locate entries, never to
+// decide whether a token exists. Updates hold a lock file next to the index,
+// so the CLI and the daemon do not overwrite each other's changes.
+type KeyIndex struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewKeyIndex creates a key index backed by the file at path.
+func NewKeyIndex(path string) *KeyIndex {
+	return &KeyIndex{path: path}
+}
+
+// DefaultIndexPath returns the path of the key index in the data directory.
+func DefaultIndexPath() string {
+	return filepath.Join(config.GetPaths().DataDir, IndexFileName)
+}
+
+// Entries returns all index entries for the given store type, sorted by profile.
+func (idx *KeyIndex) Entries(store string) ([]IndexEntry, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	f, err := idx.load()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]IndexEntry, 0, len(f.Entries))
+	for _, e := range f.Entries {
+		if e.Store == store {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Profile < entries[j].Profile
+	})
+	return entries, nil
+}
+
+// Add records that prof has an entry in store. When touch is false an
+// existing entry is left unchanged, which avoids rewriting the index on reads.
+func (idx *KeyIndex) Add(store string, prof *types.Profile, touch bool) error {
+	key, err := profileKey(prof)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// Reads only backfill missing entries, so the lock is not taken when
+	// there is nothing to write.
+	if !touch {
+		if f, err := idx.load(); err == nil && f.find(store, key) >= 0 {
+			return nil
+		}
+	}
+
+	unlock, err := idx.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := idx.load()
+	if err != nil {
+		return err
+	}
+
+	entry := IndexEntry{Store: store, Key: key, Profile: prof.Name, UpdatedAt: time.Now().UTC()}
+	switch i := f.find(store, key); {
+	case i < 0:
+		f.Entries = append(f.Entries, entry)
+	case touch:
+		f.Entries[i] = entry
+	default:
+		return nil
+	}
+	return idx.save(f)
+}
+
+// Remove drops the entry for key in store. Removing a missing entry is not an error.
+func (idx *KeyIndex) Remove(store, key string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	unlock, err := idx.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := idx.load()
+	if err != nil {
+		return err
+	}
+
+	kept := f.Entries[:0]
+	for _, e := range f.Entries {
+		if e.Store != store || e.Key != key {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(f.Entries) {
+		return nil
+	}
+	f.Entries = kept
+	return idx.save(f)
+}
+
+// lock takes the lock shared with other processes by creating a lock file
+// next to the index exclusively, and returns a function that releases it.
+// A lock file older than indexLockStale is removed and taken over.
+func (idx *KeyIndex) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key index directory: %w", err)
+	}
+
+	lockPath := idx.path + ".lock"
+	deadline := time.Now().Add(indexLockTimeout)
+	for {
+		// #nosec G304 - lockPath is next to the index file in the data directory
+		file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_ = file.Close()
+			return func() {
+				_ = os.Remove(lockPath) //nolint:errcheck // a leftover lock goes stale
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock key index: %w", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > indexLockStale {
+			_ = os.Remove(lockPath) //nolint:errcheck // retried below
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock key index: %s is held by another process", lockPath)
+		}
+		time.Sleep(indexLockRetry)
+	}
+}
+
+// load reads the index file. A missing file is an empty index.
+func (idx *KeyIndex) load() (*indexFile, error) {
+	data, err := os.ReadFile(idx.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &indexFile{Version: indexVersion}, nil
+		}
+		return nil, fmt.Errorf("failed to read key index: %w", err)
+	}
+
+	var f indexFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse key index %s: %w", idx.path, err)
+	}
+	if f.Version != indexVersion {
+		return nil, fmt.Errorf("unsupported key index version %d", f.Version)
+	}
+	return &f, nil
+}
+
+// save atomically writes the index file.
+func (idx *KeyIndex) save(f *indexFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key index: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0700); err != nil {
+		return fmt.Errorf("failed to create key index directory: %w", err)
+	}
+	return utils.WriteFileAtomic(idx.path, data, 0600)
+}
+
+// KeyLister is implemented by stores that can enumerate their entries on
+// their own, without relying on the key index.
+type KeyLister interface {
+	// ListKeys returns the keys of all token and metadata entries, without
+	// the metadata suffix and without duplicates.
+	ListKeys() ([]string, error)
+	// DeleteKey removes the token and metadata entries stored under key.
+	DeleteKey(key string) error
+}
+
+// IsStoreKey reports whether key has the format produced by KeyFromProfile.
+func IsStoreKey(key string) bool {
+	hash, ok := strings.CutPrefix(key, ServicePrefix+"_")
+	if !ok || len(hash) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// listDirKeys returns the store keys of the entries found in dir.
+func listDirKeys(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+
+	seen := make(map[string]bool)
+	keys := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		key := strings.TrimSuffix(file.Name(), metadataSuffix)
+		if !IsStoreKey(key) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// deleteDirKey removes the token and metadata files for key in dir.
+func deleteDirKey(dir, key string) error {
+	if !IsStoreKey(key) {
+		return fmt.Errorf("%w: invalid key %q", ErrTokenDelete, key)
+	}
+	for _, name := range []string{key, key + metadataSuffix} {
+		err := os.Remove(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%w: %v", ErrTokenDelete, err)
+		}
+	}
+	return nil
+}
+
+// IndexedStore wraps a TokenStore and records every key it writes in a KeyIndex.
+// Index failures never fail the wrapped operation.
+type IndexedStore struct {
+	TokenStore
+	storeType string
+	index     *KeyIndex
+}
+
+// NewIndexedStore wraps store so its keys are recorded in index under storeType.
+func NewIndexedStore(store TokenStore, storeType string, index *KeyIndex) *IndexedStore {
+	return &IndexedStore{TokenStore: store, storeType: storeType, index: index}
+}
+
+// Unwrap returns the wrapped store.
+func (s *IndexedStore) Unwrap() TokenStore {
+	return s.TokenStore
+}
+
+// StoreType returns the store type entries are recorded under.
+func (s *IndexedStore) StoreType() string {
+	return s.storeType
+}
+
+// Index returns the key index.
+func (s *IndexedStore) Index() *KeyIndex {
+	return s.index
+}
+
+func (s *IndexedStore) Get(prof *types.Profile) (string, error) {
+	token, err := s.TokenStore.Get(prof)
+	if err == nil {
+		// Backfill entries written before the index existed.
+		_ = s.index.Add(s.storeType, prof, false) //nolint:errcheck // index is best-effort
+	}
+	return token, err
+}
+
+func (s *IndexedStore) Set(prof *types.Profile, token string) error {
+	if err := s.TokenStore.Set(prof, token); err != nil {
+		return err
+	}
+	_ = s.index.Add(s.storeType, prof, true) //nolint:errcheck // index is best-effort
+	return nil
+}
+
+func (s *IndexedStore) SetMetadata(prof *types.Profile, meta *types.TokenMetadata) error {
+	if err := s.TokenStore.SetMetadata(prof, meta); err != nil {
+		return err
+	}
+	_ = s.index.Add(s.storeType, prof, false) //nolint:errcheck // index is best-effort
+	return nil
+}
+
+func (s *IndexedStore) Delete(prof *types.Profile) error {
+	if err := s.TokenStore.Delete(prof); err != nil {
+		return err
+	}
+	_ = s.index.Remove(s.storeType, KeyFromProfile(prof)) //nolint:errcheck // index is best-effort
+	return nil
+}
@@ -0,0 +1,222 @@
+package tokenstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+func TestKeyIndex(t *testing.T) {
+	idx := NewKeyIndex(filepath.Join(t.TempDir(), "sub", IndexFileName))
+	dev := &types.Profile{Name: "dev"}
+	prod := &types.Profile{Name: "prod"}
+
+	entries, err := idx.Entries("keyring")
+	if err != nil {
+		t.Fatalf("Entries() on missing index failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("Entries() = %v, want empty", entries)
+	}
+
+	for _, prof := range []*types.Profile{prod, dev} {
+		if err := idx.Add("keyring", prof, true); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	if err := idx.Add("encrypted_file", dev, true); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	entries, err = idx.Entries("keyring")
+	if err != nil {
+		t.Fatalf("Entries() failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Profile != "dev" || entries[1].Profile != "prod" {
+		t.Fatalf("Entries() = %+v, want dev and prod", entries)
+	}
+	if entries[0].Key != KeyFromProfile(dev) {
+		t.Errorf("Key = %q, want %q", entries[0].Key, KeyFromProfile(dev))
+	}
+
+	// Adding without touch keeps the existing timestamp.
+	before := entries[0].UpdatedAt
+	if err := idx.Add("keyring", dev, false); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	entries, _ = idx.Entries("keyring")
+	if !entries[0].UpdatedAt.Equal(before) || len(entries) != 2 {
+		t.Errorf("Add() without touch changed the index: %+v", entries)
+	}
+
+	if err := idx.Remove("keyring", KeyFromProfile(dev)); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if err := idx.Remove("keyring", KeyFromProfile(dev)); err != nil {
+		t.Fatalf("Remove() of missing entry failed: %v", err)
+	}
+	entries, _ = idx.Entries("keyring")
+	if len(entries) != 1 || entries[0].Profile != "prod" {
+		t.Errorf("Entries() after Remove() = %+v, want prod", entries)
+	}
+	entries, _ = idx.Entries("encrypted_file")
+	if len(entries) != 1 {
+		t.Errorf("Remove() affected another store: %+v", entries)
+	}
+
+	info, err := os.Stat(idx.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("index permissions = %o, want 600", perm)
+	}
+}
+
+func TestKeyIndexConcurrentProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), IndexFileName)
+
+	// Separate KeyIndex values share no mutex, like the CLI and the daemon.
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx := NewKeyIndex(path)
+			if err := idx.Add("keyring", &types.Profile{Name: fmt.Sprintf("p%d", i)}, true); err != nil {
+				t.Errorf("Add() failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := NewKeyIndex(path).Entries("keyring")
+	if err != nil {
+		t.Fatalf("Entries() failed: %v", err)
+	}
+	if len(entries) != 8 {
+		t.Errorf("Entries() = %d entries, want 8: concurrent updates were lost", len(entries))
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file should be removed after updates, stat error = %v", err)
+	}
+}
+
+func TestKeyIndexStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), IndexFileName)
+	lockPath := path + ".lock"
+	if err := os.WriteFile(lockPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * indexLockStale)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewKeyIndex(path).Add("keyring", &types.Profile{Name: "dev"}, true); err != nil {
+		t.Fatalf("Add() with a stale lock failed: %v", err)
+	}
+}
+
+func TestIndexedStore(t *testing.T) {
+	base, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+	idx := NewKeyIndex(filepath.Join(t.TempDir(), IndexFileName))
+	store := NewIndexedStore(base, "file", idx)
+	prof := &types.Profile{Name: "dev"}
+
+	if err := store.Set(prof, "hvs.token"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	entries, _ := idx.Entries("file")
+	if len(entries) != 1 || entries[0].Profile != "dev" {
+		t.Fatalf("Set() did not index the key: %+v", entries)
+	}
+
+	if err := store.Delete(prof); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	entries, _ = idx.Entries("file")
+	if len(entries) != 0 {
+		t.Fatalf("Delete() did not remove the index entry: %+v", entries)
+	}
+
+	// Tokens written before the index existed are picked up on read.
+	if err := base.Set(prof, "hvs.token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(prof); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	entries, _ = idx.Entries("file")
+	if len(entries) != 1 {
+		t.Errorf("Get() did not backfill the index: %+v", entries)
+	}
+}
+
+func TestFileStoreListKeys(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+	dev := &types.Profile{Name: "dev"}
+	prod := &types.Profile{Name: "prod"}
+
+	if err := store.Set(dev, "hvs.dev"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetMetadata(dev, &types.TokenMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetMetadata(prod, &types.TokenMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "unrelated"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := store.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys() failed: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("ListKeys() = %v, want 2 keys", keys)
+	}
+
+	if err := store.DeleteKey(KeyFromProfile(dev)); err != nil {
+		t.Fatalf("DeleteKey() failed: %v", err)
+	}
+	if _, err := store.Get(dev); err != ErrTokenNotFound {
+		t.Errorf("Get() after DeleteKey() error = %v, want %v", err, ErrTokenNotFound)
+	}
+	if _, err := store.GetMetadata(dev); err != ErrMetadataNotFound {
+		t.Errorf("GetMetadata() after DeleteKey() error = %v, want %v", err, ErrMetadataNotFound)
+	}
+
+	if err := store.DeleteKey("../unrelated"); err == nil {
+		t.Error("DeleteKey() accepted an invalid key")
+	}
+}
+
+func TestIsStoreKey(t *testing.T) {
+	tests := map[string]bool{
+		KeyFromProfile(&types.Profile{Name: "dev"}):                  true,
+		KeyFromProfile(&types.Profile{Name: "dev"}) + metadataSuffix: false,
+		"patrol_xyz":         false,
+		encryptedStoreHeader: false,
+		"":                   false,
+	}
+	for key, want := range tests {
+		if got := IsStoreKey(key); got != want {
+			t.Errorf("IsStoreKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -127,10 +127,91 @@ func (k *KeyringStore) Delete(prof *types.Profile) error {
 	}
 
 	err := gokeyring.Delete(key, "")
+	if err != nil && !errors.Is(err, gokeyring.ErrNotFound) {
+		return wrapKeyringStoreError(err, ErrTokenDelete)
+	}
+
+	err = gokeyring.Delete(key+metadataSuffix, "")
+	if err != nil && !errors.Is(err, gokeyring.ErrNotFound) {
+		return wrapKeyringStoreError(err, ErrTokenDelete)
+	}
+
+	return nil
+}
+
+func (k *KeyringStore) GetMetadata(prof *types.Profile) (*types.TokenMetadata, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.IsAvailable(); err != nil {
+		return nil, err
+	}
+
+	if prof == nil {
+		return nil, ErrProfileNil
+	}
+	key := KeyFromProfile(prof)
+	if key == "" {
+		return nil, ErrProfileNameEmpty
+	}
+
+	data, err := gokeyring.Get(key+metadataSuffix, "")
 	if err != nil {
 		if errors.Is(err, gokeyring.ErrNotFound) {
-			return nil
+			return nil, ErrMetadataNotFound
 		}
+		return nil, wrapKeyringStoreError(err, ErrTokenRetrieve)
+	}
+
+	return decodeMetadata(data)
+}
+
+func (k *KeyringStore) SetMetadata(prof *types.Profile, meta *types.TokenMetadata) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.IsAvailable(); err != nil {
+		return err
+	}
+
+	if prof == nil {
+		return ErrProfileNil
+	}
+	key := KeyFromProfile(prof)
+	if key == "" {
+		return ErrProfileNameEmpty
+	}
+
+	data, err := encodeMetadata(meta)
+	if err != nil {
+		return err
+	}
+
+	if err := gokeyring.Set(key+metadataSuffix, "", data); err != nil {
+		return wrapKeyringStoreError(err, ErrTokenStore)
+	}
+
+	return nil
+}
+
+func (k *KeyringStore) DeleteMetadata(prof *types.Profile) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.IsAvailable(); err != nil {
+		return err
+	}
+
+	if prof == nil {
+		return ErrProfileNil
+	}
+	key := KeyFromProfile(prof)
+	if key == "" {
+		return ErrProfileNameEmpty
+	}
+
+	err := gokeyring.Delete(key+metadataSuffix, "")
+	if err != nil && !errors.Is(err, gokeyring.ErrNotFound) {
 		return wrapKeyringStoreError(err, ErrTokenDelete)
 	}
 
@@ -0,0 +1,54 @@
+package tokenstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+var (
+	// ErrMetadataNotFound is returned when no metadata is stored for a profile.
+	ErrMetadataNotFound = errors.New("token metadata not found in store")
+	// ErrMetadataNil is returned when a nil metadata record is stored.
+	ErrMetadataNil = errors.New("token metadata cannot be nil")
+	// ErrMetadataVersion is returned when a stored record has an unsupported version.
+	ErrMetadataVersion = errors.New("unsupported token metadata version")
+)
+
+// metadataSuffix is appended to a token key to derive its metadata key.
+const metadataSuffix = "_meta"
+
+// encodeMetadata serializes a metadata record, stamping the current version
+// and update time.
+func encodeMetadata(meta *types.TokenMetadata) (string, error) {
+	if meta == nil {
+		return "", ErrMetadataNil
+	}
+
+	record := *meta
+	record.Version = types.TokenMetadataVersion
+	record.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeMetadata parses a metadata record and checks its version.
+func decodeMetadata(data string) (*types.TokenMetadata, error) {
+	var meta types.TokenMetadata
+	if err := json.Unmarshal([]byte(data), &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode token metadata: %w", err)
+	}
+
+	if meta.Version < 1 || meta.Version > types.TokenMetadataVersion {
+		return nil, fmt.Errorf("%w: %d", ErrMetadataVersion, meta.Version)
+	}
+
+	return &meta, nil
+}
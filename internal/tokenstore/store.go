@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/xabinapal/patrol/internal/config"
 	"github.com/xabinapal/patrol/internal/types"
 )
 
@@ -53,8 +54,14 @@ type TokenStore interface {
 	Get(prof *types.Profile) (string, error)
 	// Set stores a token for the given profile.
 	Set(prof *types.Profile, token string) error
-	// Delete removes a token for the given profile.
+	// Delete removes a token and its metadata for the given profile.
 	Delete(prof *types.Profile) error
+	// GetMetadata retrieves the token metadata for the given profile.
+	GetMetadata(prof *types.Profile) (*types.TokenMetadata, error)
+	// SetMetadata stores the token metadata for the given profile.
+	SetMetadata(prof *types.Profile, meta *types.TokenMetadata) error
+	// DeleteMetadata removes the token metadata for the given profile.
+	DeleteMetadata(prof *types.Profile) error
 }
 
 // Store returns the default token store for the current platform.
@@ -70,6 +77,39 @@ func NewTokenStore() TokenStore {
 	return NewKeyringStore()
 }
 
+// NewTokenStoreFromConfig returns the token store selected by the token_store
+// configuration section. PATROL_TEST_KEYRING_DIR still takes precedence.
+func NewTokenStoreFromConfig(cfg *config.TokenStoreConfig) (TokenStore, error) {
+	if os.Getenv(TestStoreEnvVar) != "" {
+		return NewTokenStore(), nil
+	}
+	return NewTokenStoreOfType(cfg.GetType(), cfg)
+}
+
+// NewTokenStoreOfType returns a token store of the given type, taking the
+// backend settings from cfg. PATROL_TEST_KEYRING_DIR is not consulted.
+func NewTokenStoreOfType(storeType string, cfg *config.TokenStoreConfig) (TokenStore, error) {
+	switch storeType {
+	case config.TokenStoreKeyring:
+		return NewKeyringStore(), nil
+	case config.TokenStoreEncryptedFile:
+		opts := EncryptedFileStoreOptions{
+			Dir:     cfg.GetPath(),
+			KeyFile: cfg.KeyFile,
+		}
+		if opts.KeyFile == "" {
+			opts.PassphraseEnv = cfg.GetPassphraseEnv()
+			opts.Passphrase = os.Getenv(opts.PassphraseEnv)
+		}
+		return NewEncryptedFileStore(opts)
+	case config.TokenStoreHelper:
+		return NewHelperStore(cfg.Helper, cfg.HelperArgs, cfg.HelperTimeout)
+	default:
+		return nil, fmt.Errorf("unknown token store type %q (supported: %s, %s, %s)",
+			storeType, config.TokenStoreKeyring, config.TokenStoreEncryptedFile, config.TokenStoreHelper)
+	}
+}
+
 func KeyFromProfile(prof *types.Profile) string {
 	if prof == nil {
 		return ""
@@ -46,3 +46,96 @@ func (t *Token) NeedsRenewal(threshold float64, minTTL time.Duration) bool {
 
 	return false
 }
+
+// TokenMetadataVersion is the current version of the TokenMetadata record format.
+const TokenMetadataVersion = 1
+
+// maxTTLSlack is the margin, in seconds, by which a renewal may fall short of
+// the creation TTL without being taken as capped. Lease durations derived from
+// a lookup are only accurate to the second.
+const maxTTLSlack = 5
+
+// TokenMetadata holds non-secret information about a stored token.
+// It is persisted next to the token so it can be inspected without
+// contacting the server.
+type TokenMetadata struct {
+	// Version is the record format version.
+	Version int `json:"version"`
+	// Accessor is the token accessor.
+	Accessor string `json:"accessor,omitempty"`
+	// Policies are the policies attached to the token.
+	Policies []string `json:"policies,omitempty"`
+	// TokenType is the token type (service, batch).
+	TokenType string `json:"token_type,omitempty"`
+	// IssueTime is when the token was created.
+	IssueTime time.Time `json:"issue_time,omitzero"`
+	// CreationTTL is the TTL in seconds the token was created with.
+	CreationTTL int `json:"creation_ttl,omitempty"`
+	// ExplicitMaxTTL is the hard TTL cap in seconds (0 means none).
+	ExplicitMaxTTL int `json:"explicit_max_ttl,omitempty"`
+	// LeaseDuration is the TTL in seconds granted at the last login or renewal.
+	LeaseDuration int `json:"lease_duration"`
+	// Renewable indicates if the token can be renewed.
+	Renewable bool `json:"renewable"`
+	// ExpiresAt is the calculated expiration time.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// LastRenewal is when the token was last renewed.
+	LastRenewal time.Time `json:"last_renewal,omitzero"`
+	// UpdatedAt is when this record was last written.
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// Token returns a Token built from the metadata for the given token string.
+// LeaseDuration is the full lease granted at the last login or renewal, so
+// NeedsRenewal can compute the elapsed fraction of the current lease.
+func (m *TokenMetadata) Token(clientToken string) *Token {
+	return &Token{
+		ClientToken:   clientToken,
+		LeaseDuration: m.LeaseDuration,
+		Renewable:     m.Renewable,
+		ExpiresAt:     m.ExpiresAt,
+	}
+}
+
+// TTL returns the remaining time until expiration, or zero if unknown or expired.
+func (m *TokenMetadata) TTL() time.Duration {
+	if m.ExpiresAt.IsZero() {
+		return 0
+	}
+	ttl := time.Until(m.ExpiresAt)
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// HardExpiry returns the time after which renewals can no longer keep the
+// token alive: its expiry if it is not renewable, the explicit max TTL if it
+// is known, or the current expiry once a renewal was granted less than the
+// creation TTL, which the server does when a max TTL (explicit, or the
+// mount's or the system's) caps the lease. It returns false if the token does
+// not expire or renewals may still extend it.
+func (m *TokenMetadata) HardExpiry() (time.Time, bool) {
+	switch {
+	case m.ExpiresAt.IsZero():
+		return time.Time{}, false
+	case !m.Renewable:
+		return m.ExpiresAt, true
+	case m.ExplicitMaxTTL > 0 && !m.IssueTime.IsZero():
+		return m.IssueTime.Add(time.Duration(m.ExplicitMaxTTL) * time.Second), true
+	case m.CreationTTL > 0 && !m.LastRenewal.IsZero() && m.LeaseDuration+maxTTLSlack < m.CreationTTL:
+		return m.ExpiresAt, true
+	}
+	return time.Time{}, false
+}
+
+// RecordRenewal updates the metadata after a successful renewal at now.
+func (m *TokenMetadata) RecordRenewal(leaseDuration int, renewable bool, now time.Time) {
+	m.LeaseDuration = leaseDuration
+	m.Renewable = renewable
+	m.LastRenewal = now
+	m.ExpiresAt = time.Time{}
+	if leaseDuration > 0 {
+		m.ExpiresAt = now.Add(time.Duration(leaseDuration) * time.Second)
+	}
+}
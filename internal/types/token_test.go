@@ -79,3 +79,55 @@ func TestTokenNeedsRenewal(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenMetadataHardExpiry(t *testing.T) {
+	issued := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	expires := issued.Add(3 * time.Hour)
+
+	tests := []struct {
+		name       string
+		meta       TokenMetadata
+		wantCapped bool
+		wantExpiry time.Time
+	}{
+		{
+			name: "fresh renewable token",
+			meta: TokenMetadata{Renewable: true, CreationTTL: 3600, LeaseDuration: 3600, ExpiresAt: expires},
+		},
+		{
+			name: "token that never expires",
+			meta: TokenMetadata{Renewable: false},
+		},
+		{
+			name:       "not renewable",
+			meta:       TokenMetadata{Renewable: false, LeaseDuration: 3600, ExpiresAt: expires},
+			wantCapped: true,
+			wantExpiry: expires,
+		},
+		{
+			name:       "explicit max TTL",
+			meta:       TokenMetadata{Renewable: true, IssueTime: issued, ExplicitMaxTTL: 86400, CreationTTL: 3600, LeaseDuration: 3600, ExpiresAt: expires},
+			wantCapped: true,
+			wantExpiry: issued.Add(24 * time.Hour),
+		},
+		{
+			name:       "renewal capped by the server",
+			meta:       TokenMetadata{Renewable: true, CreationTTL: 3600, LeaseDuration: 600, LastRenewal: issued, ExpiresAt: expires},
+			wantCapped: true,
+			wantExpiry: expires,
+		},
+		{
+			name: "renewal to the full TTL, rounded down by a lookup",
+			meta: TokenMetadata{Renewable: true, CreationTTL: 3600, LeaseDuration: 3599, LastRenewal: issued, ExpiresAt: expires},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiry, capped := tt.meta.HardExpiry()
+			if capped != tt.wantCapped || !expiry.Equal(tt.wantExpiry) {
+				t.Errorf("HardExpiry() = %v, %v; want %v, %v", expiry, capped, tt.wantExpiry, tt.wantCapped)
+			}
+		})
+	}
+}
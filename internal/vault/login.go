@@ -0,0 +1,259 @@
+// Package vault provides Vault/OpenBao server interaction utilities.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// Supported native authentication methods.
+const (
+	// AuthMethodToken authenticates with an existing token.
+	AuthMethodToken = "token"
+	// AuthMethodUserpass authenticates with a username and password.
+	AuthMethodUserpass = "userpass"
+	// AuthMethodLDAP authenticates against an LDAP server.
+	AuthMethodLDAP = "ldap"
+	// AuthMethodAppRole authenticates with a role ID and secret ID.
+	AuthMethodAppRole = "approle"
+	// AuthMethodCert authenticates with a TLS client certificate.
+	AuthMethodCert = "cert"
+	// AuthMethodJWT authenticates with a signed JWT.
+	AuthMethodJWT = "jwt"
+	// AuthMethodKubernetes authenticates with a Kubernetes service account token.
+	AuthMethodKubernetes = "kubernetes"
+)
+
+var (
+	// ErrUnsupportedAuthMethod is returned when a method has no native implementation.
+	ErrUnsupportedAuthMethod = errors.New("auth method not supported natively")
+	// ErrMissingAuthParam is returned when a required login parameter is missing.
+	ErrMissingAuthParam = errors.New("missing required auth parameter")
+)
+
+// LoginExecutor provides an interface for authenticating against Vault auth methods.
+type LoginExecutor interface {
+	// Login authenticates using the given method mounted at path.
+	// If path is empty, the method name is used as the mount path.
+	Login(ctx context.Context, prof *types.Profile, method, path string, params map[string]string) (*VaultTokenResponse, error)
+	// LoginOIDC performs the interactive OIDC browser flow against the mount at path.
+	LoginOIDC(ctx context.Context, prof *types.Profile, path string, opts OIDCLoginOptions) (*VaultTokenResponse, error)
+}
+
+type loginExecutor struct{}
+
+// NewLoginExecutor creates a new LoginExecutor.
+func NewLoginExecutor() LoginExecutor {
+	return &loginExecutor{}
+}
+
+// loginRequiredParams lists the parameters each native method needs.
+var loginRequiredParams = map[string][]string{
+	AuthMethodToken:      {"token"},
+	AuthMethodUserpass:   {"username", "password"},
+	AuthMethodLDAP:       {"username", "password"},
+	AuthMethodAppRole:    {"role_id"},
+	AuthMethodCert:       {},
+	AuthMethodJWT:        {"jwt"},
+	AuthMethodKubernetes: {"role", "jwt"},
+}
+
+// IsNativeAuthMethod reports whether the method can be handled without the vault/bao binary.
+func IsNativeAuthMethod(method string) bool {
+	_, ok := loginRequiredParams[normalizeAuthMethod(method)]
+	return ok
+}
+
+// MissingAuthParams returns the required parameters for method that are absent from params.
+func MissingAuthParams(method string, params map[string]string) []string {
+	var missing []string
+	for _, name := range loginRequiredParams[normalizeAuthMethod(method)] {
+		if params[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// normalizeAuthMethod returns the canonical method name, defaulting to token.
+func normalizeAuthMethod(method string) string {
+	method = strings.ToLower(strings.TrimSpace(method))
+	if method == "" {
+		return AuthMethodToken
+	}
+	return method
+}
+
+func (e *loginExecutor) Login(ctx context.Context, prof *types.Profile, method, path string, params map[string]string) (*VaultTokenResponse, error) {
+	method = normalizeAuthMethod(method)
+	if !IsNativeAuthMethod(method) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAuthMethod, method)
+	}
+
+	if missing := MissingAuthParams(method, params); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingAuthParam, strings.Join(missing, ", "))
+	}
+
+	if path == "" {
+		path = method
+	}
+	path = strings.Trim(path, "/")
+
+	if method == AuthMethodToken {
+		return e.loginWithToken(ctx, prof, params["token"])
+	}
+
+	body := make(map[string]any, len(params))
+	for k, v := range params {
+		body[k] = v
+	}
+
+	loginPath := "/v1/auth/" + path + "/login"
+	switch method {
+	case AuthMethodUserpass, AuthMethodLDAP:
+		loginPath += "/" + url.PathEscape(params["username"])
+		delete(body, "username")
+	}
+
+	return e.doLogin(ctx, prof, loginPath, body)
+}
+
+// doLogin posts body to a login endpoint and parses the auth response.
+func (e *loginExecutor) doLogin(ctx context.Context, prof *types.Profile, loginPath string, body map[string]any) (*VaultTokenResponse, error) {
+	respBody, err := e.doRequest(ctx, prof, "POST", loginPath, body)
+	if err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	return ParseLoginResponse(respBody)
+}
+
+// loginWithToken validates an existing token via lookup-self and returns its metadata.
+func (e *loginExecutor) loginWithToken(ctx context.Context, prof *types.Profile, tokenStr string) (*VaultTokenResponse, error) {
+	client, err := buildHTTPClient(prof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", prof.Address+"/v1/auth/token/lookup-self", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("X-Vault-Token", tokenStr)
+	if prof.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", prof.Namespace)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("login failed: status %d: %s", resp.StatusCode, parseErrorResponse(body))
+	}
+
+	data, err := ParseLookupResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tok := &VaultTokenResponse{
+		ClientToken:      tokenStr,
+		Accessor:         data.Accessor,
+		Policies:         data.Policies,
+		IdentityPolicies: data.IdentityPolicies,
+		Metadata:         data.Meta,
+		LeaseDuration:    data.TTL,
+		Renewable:        data.Renewable,
+		EntityID:         data.EntityID,
+		TokenType:        data.Type,
+		Orphan:           data.Orphan,
+		NumUses:          data.NumUses,
+		CreatedAt:        now,
+	}
+	if data.CreationTime > 0 {
+		tok.CreatedAt = time.Unix(data.CreationTime, 0)
+	}
+	if tok.LeaseDuration > 0 {
+		tok.ExpiresAt = now.Add(time.Duration(tok.LeaseDuration) * time.Second)
+	}
+
+	return tok, nil
+}
+
+// doRequest sends an unauthenticated request to Vault and returns the response body.
+func (e *loginExecutor) doRequest(ctx context.Context, prof *types.Profile, method, apiPath string, body map[string]any) ([]byte, error) {
+	client, err := buildHTTPClient(prof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, prof.Address+apiPath, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if prof.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", prof.Namespace)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, parseErrorResponse(respBody))
+	}
+
+	return respBody, nil
+}
+
+// parseErrorResponse extracts the error messages from a Vault error response body.
+// Falls back to the raw body when it is not a standard error response.
+func parseErrorResponse(body []byte) string {
+	var errResp struct {
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil && len(errResp.Errors) > 0 {
+		return strings.Join(errResp.Errors, "; ")
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,221 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+const testLoginResponse = `{
+	"auth": {
+		"client_token": "hvs.test-token",
+		"accessor": "accessor-123",
+		"policies": ["default", "dev"],
+		"lease_duration": 3600,
+		"renewable": true,
+		"token_type": "service"
+	}
+}`
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		params       map[string]string
+		expectedPath string
+		expectedBody map[string]string
+	}{
+		{
+			name:         "userpass",
+			method:       "userpass",
+			params:       map[string]string{"username": "admin", "password": "secret"},
+			expectedPath: "/v1/auth/userpass/login/admin",
+			expectedBody: map[string]string{"password": "secret"},
+		},
+		{
+			name:         "ldap with custom path",
+			method:       "ldap",
+			path:         "ldap-corp",
+			params:       map[string]string{"username": "user", "password": "pass"},
+			expectedPath: "/v1/auth/ldap-corp/login/user",
+			expectedBody: map[string]string{"password": "pass"},
+		},
+		{
+			name:         "approle",
+			method:       "approle",
+			params:       map[string]string{"role_id": "role", "secret_id": "secret"},
+			expectedPath: "/v1/auth/approle/login",
+			expectedBody: map[string]string{"role_id": "role", "secret_id": "secret"},
+		},
+		{
+			name:         "cert with role name",
+			method:       "cert",
+			params:       map[string]string{"name": "web"},
+			expectedPath: "/v1/auth/cert/login",
+			expectedBody: map[string]string{"name": "web"},
+		},
+		{
+			name:         "kubernetes",
+			method:       "kubernetes",
+			params:       map[string]string{"role": "app", "jwt": "sa-token"},
+			expectedPath: "/v1/auth/kubernetes/login",
+			expectedBody: map[string]string{"role": "app", "jwt": "sa-token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("unexpected method: %s", r.Method)
+				}
+				if r.URL.Path != tt.expectedPath {
+					t.Errorf("unexpected path: %s, want %s", r.URL.Path, tt.expectedPath)
+				}
+				var body map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode body: %v", err)
+				}
+				for k, v := range tt.expectedBody {
+					if body[k] != v {
+						t.Errorf("body[%q] = %q, want %q", k, body[k], v)
+					}
+				}
+				if _, ok := body["username"]; ok {
+					t.Error("username should not be sent in the request body")
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(testLoginResponse))
+			}))
+			defer server.Close()
+
+			prof := &types.Profile{Name: "test", Address: server.URL}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := NewLoginExecutor().Login(ctx, prof, tt.method, tt.path, tt.params)
+			if err != nil {
+				t.Fatalf("Login() unexpected error: %v", err)
+			}
+
+			if resp.ClientToken != "hvs.test-token" {
+				t.Errorf("ClientToken = %q, want %q", resp.ClientToken, "hvs.test-token")
+			}
+			if resp.Accessor != "accessor-123" {
+				t.Errorf("Accessor = %q, want %q", resp.Accessor, "accessor-123")
+			}
+			if resp.LeaseDuration != 3600 {
+				t.Errorf("LeaseDuration = %d, want 3600", resp.LeaseDuration)
+			}
+			if len(resp.Policies) != 2 {
+				t.Errorf("Policies = %v, want 2 entries", resp.Policies)
+			}
+		})
+	}
+}
+
+func TestLogin_Token(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/lookup-self" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("X-Vault-Token") != "s.existing" {
+			t.Errorf("unexpected token header: %q", r.Header.Get("X-Vault-Token"))
+		}
+		if r.Header.Get("X-Vault-Namespace") != "team1" {
+			t.Errorf("unexpected namespace header: %q", r.Header.Get("X-Vault-Namespace"))
+		}
+		_, _ = w.Write([]byte(`{"data": {"accessor": "acc", "ttl": 600, "renewable": true, "type": "service", "policies": ["default"]}}`))
+	}))
+	defer server.Close()
+
+	prof := &types.Profile{Name: "test", Address: server.URL, Namespace: "team1"}
+
+	resp, err := NewLoginExecutor().Login(context.Background(), prof, "", "", map[string]string{"token": "s.existing"})
+	if err != nil {
+		t.Fatalf("Login() unexpected error: %v", err)
+	}
+
+	if resp.ClientToken != "s.existing" {
+		t.Errorf("ClientToken = %q, want %q", resp.ClientToken, "s.existing")
+	}
+	if resp.Accessor != "acc" {
+		t.Errorf("Accessor = %q, want %q", resp.Accessor, "acc")
+	}
+	if resp.LeaseDuration != 600 {
+		t.Errorf("LeaseDuration = %d, want 600", resp.LeaseDuration)
+	}
+	if resp.ExpiresAt.IsZero() {
+		t.Error("ExpiresAt should be set for tokens with a TTL")
+	}
+}
+
+func TestLogin_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errors": ["invalid username or password"]}`))
+	}))
+	defer server.Close()
+
+	prof := &types.Profile{Name: "test", Address: server.URL}
+	params := map[string]string{"username": "admin", "password": "wrong"}
+
+	_, err := NewLoginExecutor().Login(context.Background(), prof, "userpass", "", params)
+	if err == nil {
+		t.Fatal("Login() expected error, got nil")
+	}
+
+	expected := "login failed: status 400: invalid username or password"
+	if err.Error() != expected {
+		t.Errorf("Login() error = %q, want %q", err.Error(), expected)
+	}
+}
+
+func TestLogin_Validation(t *testing.T) {
+	prof := &types.Profile{Name: "test", Address: "http://127.0.0.1:1"}
+	executor := NewLoginExecutor()
+
+	_, err := executor.Login(context.Background(), prof, "github", "", map[string]string{"token": "x"})
+	if !errors.Is(err, ErrUnsupportedAuthMethod) {
+		t.Errorf("Login(github) error = %v, want ErrUnsupportedAuthMethod", err)
+	}
+
+	_, err = executor.Login(context.Background(), prof, "userpass", "", map[string]string{"username": "admin"})
+	if !errors.Is(err, ErrMissingAuthParam) {
+		t.Errorf("Login(userpass without password) error = %v, want ErrMissingAuthParam", err)
+	}
+}
+
+func TestMissingAuthParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		params   map[string]string
+		expected int
+	}{
+		{name: "token without token", method: "", params: map[string]string{}, expected: 1},
+		{name: "token with token", method: "token", params: map[string]string{"token": "x"}, expected: 0},
+		{name: "userpass without anything", method: "userpass", params: map[string]string{}, expected: 2},
+		{name: "approle with role_id", method: "approle", params: map[string]string{"role_id": "r"}, expected: 0},
+		{name: "cert without params", method: "cert", params: map[string]string{}, expected: 0},
+		{name: "kubernetes without role", method: "kubernetes", params: map[string]string{"jwt": "x"}, expected: 1},
+		{name: "unsupported method", method: "oidc", params: map[string]string{}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing := MissingAuthParams(tt.method, tt.params)
+			if len(missing) != tt.expected {
+				t.Errorf("MissingAuthParams(%q) = %v, want %d entries", tt.method, missing, tt.expected)
+			}
+		})
+	}
+}
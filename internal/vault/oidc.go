@@ -0,0 +1,324 @@
+// Package vault provides Vault/OpenBao server interaction utilities.
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+const (
+	// AuthMethodOIDC authenticates through an OIDC provider in the browser.
+	AuthMethodOIDC = "oidc"
+
+	// DefaultOIDCListenAddress is the default address for the local callback listener.
+	DefaultOIDCListenAddress = "localhost"
+	// DefaultOIDCPort is the default port for the local callback listener.
+	DefaultOIDCPort = 8250
+
+	oidcCallbackPath = "/oidc/callback"
+)
+
+// OIDCCallbackMethod values accepted by Vault.
+const (
+	// OIDCCallbackMethodGet receives the authorization code as query parameters.
+	OIDCCallbackMethodGet = "get"
+	// OIDCCallbackMethodFormPost receives the authorization code as a form POST.
+	OIDCCallbackMethodFormPost = "form_post"
+)
+
+// OIDCLoginOptions configures the OIDC browser login flow.
+type OIDCLoginOptions struct {
+	// Role is the OIDC role to authenticate against (empty uses the mount default).
+	Role string
+	// ListenAddress is the address the local callback server binds to.
+	ListenAddress string
+	// Port is the port the local callback server binds to.
+	Port int
+	// CallbackHost overrides the host in the redirect URI (defaults to ListenAddress).
+	CallbackHost string
+	// CallbackPort overrides the port in the redirect URI (defaults to Port).
+	CallbackPort int
+	// CallbackMethod is either "get" or "form_post".
+	CallbackMethod string
+	// SkipBrowser disables launching a browser; the URL is only printed.
+	SkipBrowser bool
+	// Output receives user-facing instructions (defaults to os.Stderr).
+	Output io.Writer
+	// OpenURL opens the authorization URL (defaults to the platform browser opener).
+	OpenURL func(string) error
+}
+
+// OIDCOptionsFromParams builds OIDCLoginOptions from K=V login parameters,
+// using the same parameter names as the vault CLI.
+func OIDCOptionsFromParams(params map[string]string) (OIDCLoginOptions, error) {
+	opts := OIDCLoginOptions{
+		Role:           params["role"],
+		ListenAddress:  params["listenaddress"],
+		CallbackHost:   params["callbackhost"],
+		CallbackMethod: params["callbackmethod"],
+	}
+
+	for name, target := range map[string]*int{"port": &opts.Port, "callbackport": &opts.CallbackPort} {
+		if v := params[name]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > 65535 {
+				return opts, fmt.Errorf("invalid %s %q: must be a port number", name, v)
+			}
+			*target = n
+		}
+	}
+
+	if v := params["skip_browser"]; v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid skip_browser %q: must be a boolean", v)
+		}
+		opts.SkipBrowser = skip
+	}
+
+	return opts, nil
+}
+
+// withDefaults returns a copy of the options with unset fields defaulted.
+func (o OIDCLoginOptions) withDefaults() OIDCLoginOptions {
+	if o.ListenAddress == "" {
+		o.ListenAddress = DefaultOIDCListenAddress
+	}
+	if o.Port == 0 {
+		o.Port = DefaultOIDCPort
+	}
+	if o.CallbackHost == "" {
+		o.CallbackHost = o.ListenAddress
+	}
+	if o.CallbackPort == 0 {
+		o.CallbackPort = o.Port
+	}
+	if o.CallbackMethod == "" {
+		o.CallbackMethod = OIDCCallbackMethodGet
+	}
+	if o.Output == nil {
+		o.Output = os.Stderr
+	}
+	if o.OpenURL == nil {
+		o.OpenURL = openBrowser
+	}
+	return o
+}
+
+// oidcResult carries the outcome of the browser callback.
+type oidcResult struct {
+	resp *VaultTokenResponse
+	err  error
+}
+
+// LoginOIDC performs the OIDC authorization code flow:
+// it starts a local callback server, requests an authorization URL from Vault,
+// opens it in the browser and exchanges the returned code for a token.
+func (e *loginExecutor) LoginOIDC(ctx context.Context, prof *types.Profile, path string, opts OIDCLoginOptions) (*VaultTokenResponse, error) {
+	opts = opts.withDefaults()
+	if opts.CallbackMethod != OIDCCallbackMethodGet && opts.CallbackMethod != OIDCCallbackMethodFormPost {
+		return nil, fmt.Errorf("invalid callback method %q: must be %q or %q",
+			opts.CallbackMethod, OIDCCallbackMethodGet, OIDCCallbackMethodFormPost)
+	}
+
+	if path == "" {
+		path = AuthMethodOIDC
+	}
+	path = strings.Trim(path, "/")
+
+	listenAddr := net.JoinHostPort(opts.ListenAddress, strconv.Itoa(opts.Port))
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", listenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start OIDC callback listener on %s: %w", listenAddr, err)
+	}
+
+	redirectURI := (&url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(opts.CallbackHost, strconv.Itoa(opts.CallbackPort)),
+		Path:   oidcCallbackPath,
+	}).String()
+
+	clientNonce, err := randomHex(20)
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to generate client nonce: %w", err)
+	}
+
+	authURL, err := e.fetchOIDCAuthURL(ctx, prof, path, opts.Role, redirectURI, clientNonce)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	results := make(chan oidcResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidcCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		resp, err := e.handleOIDCCallback(r, prof, path, clientNonce)
+		w.Header().Set("Content-Type", "text/html")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, oidcPage("Login failed", "Patrol could not complete the login. Check your terminal for details."))
+		} else {
+			fmt.Fprint(w, oidcPage("Login successful", "You can close this window and return to your terminal."))
+		}
+		select {
+		case results <- oidcResult{resp: resp, err: err}:
+		default:
+		}
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(opts.Output, "Complete the login via your OIDC provider.\n")
+	if opts.SkipBrowser {
+		fmt.Fprintf(opts.Output, "Open the following URL in your browser:\n\n    %s\n\n", authURL)
+	} else {
+		fmt.Fprintf(opts.Output, "Launching browser to:\n\n    %s\n\n", authURL)
+		if err := opts.OpenURL(authURL); err != nil {
+			fmt.Fprintf(opts.Output, "Could not open a browser (%v); open the URL above manually.\n\n", err)
+		}
+	}
+	fmt.Fprintf(opts.Output, "Waiting for OIDC authentication to complete...\n")
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("OIDC login canceled: %w", ctx.Err())
+	case result := <-results:
+		return result.resp, result.err
+	}
+}
+
+// fetchOIDCAuthURL asks Vault for the provider authorization URL.
+func (e *loginExecutor) fetchOIDCAuthURL(ctx context.Context, prof *types.Profile, path, role, redirectURI, clientNonce string) (string, error) {
+	body := map[string]any{
+		"redirect_uri": redirectURI,
+		"client_nonce": clientNonce,
+	}
+	if role != "" {
+		body["role"] = role
+	}
+
+	respBody, err := e.doRequest(ctx, prof, "POST", "/v1/auth/"+path+"/oidc/auth_url", body)
+	if err != nil {
+		return "", fmt.Errorf("failed to get OIDC auth URL: %w", err)
+	}
+
+	var resp struct {
+		Data struct {
+			AuthURL string `json:"auth_url"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse OIDC auth URL response: %w", err)
+	}
+	if resp.Data.AuthURL == "" {
+		return "", errors.New("OIDC auth URL response is empty; check the role's allowed_redirect_uris")
+	}
+
+	return resp.Data.AuthURL, nil
+}
+
+// handleOIDCCallback exchanges the callback parameters for a Vault token.
+func (e *loginExecutor) handleOIDCCallback(r *http.Request, prof *types.Profile, path, clientNonce string) (*VaultTokenResponse, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC callback: %w", err)
+	}
+
+	if errParam := r.Form.Get("error"); errParam != "" {
+		return nil, fmt.Errorf("OIDC provider returned an error: %s: %s", errParam, r.Form.Get("error_description"))
+	}
+
+	state := r.Form.Get("state")
+	code := r.Form.Get("code")
+	if state == "" || code == "" {
+		return nil, errors.New("OIDC callback is missing state or code")
+	}
+
+	var (
+		respBody []byte
+		err      error
+	)
+	callbackPath := "/v1/auth/" + path + "/oidc/callback"
+	if r.Method == http.MethodPost {
+		respBody, err = e.doRequest(r.Context(), prof, "POST", callbackPath, map[string]any{
+			"state":        state,
+			"code":         code,
+			"id_token":     r.Form.Get("id_token"),
+			"client_nonce": clientNonce,
+		})
+	} else {
+		query := url.Values{
+			"state":        {state},
+			"code":         {code},
+			"client_nonce": {clientNonce},
+		}
+		respBody, err = e.doRequest(r.Context(), prof, "GET", callbackPath+"?"+query.Encode(), nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete OIDC login: %w", err)
+	}
+
+	return ParseLoginResponse(respBody)
+}
+
+// openBrowser opens url in the platform's default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// randomHex returns n random bytes encoded as hex.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// oidcPage renders the page shown in the browser after the callback.
+func oidcPage(title, message string) string {
+	return `<!DOCTYPE html>
+<html>
+<head><title>Patrol: ` + title + `</title></head>
+<body>
+<h1>` + title + `</h1>
+<p>` + message + `</p>
+</body>
+</html>`
+}
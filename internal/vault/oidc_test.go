@@ -0,0 +1,147 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xabinapal/patrol/internal/types"
+)
+
+// freePort returns a TCP port that is currently free on localhost.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestLoginOIDC(t *testing.T) {
+	var clientNonce string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/oidc/oidc/auth_url":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode auth_url body: %v", err)
+			}
+			if body["role"] != "dev" {
+				t.Errorf("role = %q, want %q", body["role"], "dev")
+			}
+			clientNonce = body["client_nonce"]
+			authURL := body["redirect_uri"] + "?state=test-state&code=test-code"
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"auth_url": authURL}})
+		case "/v1/auth/oidc/oidc/callback":
+			q := r.URL.Query()
+			if q.Get("state") != "test-state" || q.Get("code") != "test-code" {
+				t.Errorf("unexpected callback params: %v", q)
+			}
+			if q.Get("client_nonce") == "" || q.Get("client_nonce") != clientNonce {
+				t.Errorf("client_nonce = %q, want %q", q.Get("client_nonce"), clientNonce)
+			}
+			_, _ = w.Write([]byte(testLoginResponse))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	prof := &types.Profile{Name: "test", Address: server.URL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var browser sync.WaitGroup
+	defer browser.Wait()
+
+	opts := OIDCLoginOptions{
+		Role:          "dev",
+		ListenAddress: "127.0.0.1",
+		Port:          freePort(t),
+		Output:        io.Discard,
+		OpenURL: func(url string) error {
+			// Simulate the browser following the provider redirect.
+			browser.Add(1)
+			go func() {
+				defer browser.Done()
+				req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+				if err != nil {
+					t.Errorf("failed to create browser request: %v", err)
+					return
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Errorf("browser request failed: %v", err)
+					return
+				}
+				resp.Body.Close()
+			}()
+			return nil
+		},
+	}
+
+	resp, err := NewLoginExecutor().LoginOIDC(ctx, prof, "", opts)
+	if err != nil {
+		t.Fatalf("LoginOIDC() unexpected error: %v", err)
+	}
+
+	if resp.ClientToken != "hvs.test-token" {
+		t.Errorf("ClientToken = %q, want %q", resp.ClientToken, "hvs.test-token")
+	}
+}
+
+func TestLoginOIDC_Canceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"auth_url": "https://idp.example.com/auth"}})
+	}))
+	defer server.Close()
+
+	prof := &types.Profile{Name: "test", Address: server.URL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	opts := OIDCLoginOptions{
+		ListenAddress: "127.0.0.1",
+		Port:          freePort(t),
+		SkipBrowser:   true,
+		Output:        io.Discard,
+	}
+
+	if _, err := NewLoginExecutor().LoginOIDC(ctx, prof, "", opts); err == nil {
+		t.Fatal("LoginOIDC() expected error when context is canceled, got nil")
+	}
+}
+
+func TestOIDCOptionsFromParams(t *testing.T) {
+	opts, err := OIDCOptionsFromParams(map[string]string{
+		"role":           "dev",
+		"port":           "9000",
+		"callbackmethod": "form_post",
+		"skip_browser":   "true",
+	})
+	if err != nil {
+		t.Fatalf("OIDCOptionsFromParams() unexpected error: %v", err)
+	}
+	if opts.Role != "dev" || opts.Port != 9000 || opts.CallbackMethod != "form_post" || !opts.SkipBrowser {
+		t.Errorf("OIDCOptionsFromParams() = %+v", opts)
+	}
+
+	if _, err := OIDCOptionsFromParams(map[string]string{"port": "abc"}); err == nil {
+		t.Error("OIDCOptionsFromParams() expected error for invalid port")
+	}
+	if _, err := OIDCOptionsFromParams(map[string]string{"skip_browser": "maybe"}); err == nil {
+		t.Error("OIDCOptionsFromParams() expected error for invalid skip_browser")
+	}
+}
@@ -33,9 +33,17 @@ func NewTokenExecutor() TokenExecutor {
 }
 
 // TokenStatus represents the status of a token from Vault.
+// Fields other than TTL and Renewable are only populated by LookupToken.
 type TokenStatus struct {
-	TTL       int  `json:"ttl"`
-	Renewable bool `json:"renewable"`
+	TTL            int       `json:"ttl"`
+	Renewable      bool      `json:"renewable"`
+	Accessor       string    `json:"accessor,omitempty"`
+	Policies       []string  `json:"policies,omitempty"`
+	TokenType      string    `json:"token_type,omitempty"`
+	CreationTime   time.Time `json:"creation_time,omitzero"`
+	CreationTTL    int       `json:"creation_ttl,omitempty"`
+	ExplicitMaxTTL int       `json:"explicit_max_ttl,omitempty"`
+	LastRenewal    time.Time `json:"last_renewal,omitzero"`
 }
 
 // VaultLoginResponse represents the JSON response from vault login.
@@ -123,6 +131,25 @@ func ParseLoginResponse(data []byte) (*VaultTokenResponse, error) {
 	return tok, nil
 }
 
+// TokenMetadata returns the token metadata record for this response.
+func (r *VaultTokenResponse) TokenMetadata() *types.TokenMetadata {
+	policies := r.Policies
+	if len(policies) == 0 {
+		policies = r.TokenPolicies
+	}
+	return &types.TokenMetadata{
+		Version:       types.TokenMetadataVersion,
+		Accessor:      r.Accessor,
+		Policies:      policies,
+		TokenType:     r.TokenType,
+		IssueTime:     r.CreatedAt,
+		CreationTTL:   r.LeaseDuration,
+		LeaseDuration: r.LeaseDuration,
+		Renewable:     r.Renewable,
+		ExpiresAt:     r.ExpiresAt,
+	}
+}
+
 func (e *tokenExecutor) RenewToken(ctx context.Context, prof *types.Profile, tokenStr string, increment string, opts ...proxy.Option) (*TokenStatus, error) {
 	client, err := buildHTTPClient(prof)
 	if err != nil {
@@ -208,6 +235,7 @@ type VaultTokenLookupData struct {
 	ID               string            `json:"id"`
 	IdentityPolicies []string          `json:"identity_policies"`
 	IssueTime        string            `json:"issue_time"`
+	LastRenewalTime  int64             `json:"last_renewal_time"`
 	Meta             map[string]string `json:"meta"`
 	NumUses          int               `json:"num_uses"`
 	Orphan           bool              `json:"orphan"`
@@ -270,10 +298,23 @@ func (e *tokenExecutor) LookupToken(ctx context.Context, prof *types.Profile, to
 		return nil, fmt.Errorf("failed to parse lookup response: %w", err)
 	}
 
-	return &TokenStatus{
-		TTL:       lookupData.TTL,
-		Renewable: lookupData.Renewable,
-	}, nil
+	status := &TokenStatus{
+		TTL:            lookupData.TTL,
+		Renewable:      lookupData.Renewable,
+		Accessor:       lookupData.Accessor,
+		Policies:       lookupData.Policies,
+		TokenType:      lookupData.Type,
+		CreationTTL:    lookupData.CreationTTL,
+		ExplicitMaxTTL: lookupData.ExplicitMaxTTL,
+	}
+	if lookupData.CreationTime > 0 {
+		status.CreationTime = time.Unix(lookupData.CreationTime, 0)
+	}
+	if lookupData.LastRenewalTime > 0 {
+		status.LastRenewal = time.Unix(lookupData.LastRenewalTime, 0)
+	}
+
+	return status, nil
 }
 
 func (e *tokenExecutor) RevokeToken(ctx context.Context, prof *types.Profile, tokenStr string, opts ...proxy.Option) error {